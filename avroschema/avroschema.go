@@ -0,0 +1,292 @@
+// Package avroschema derives AVRO schemas from Go types by reflection.
+//
+// Structs become records whose fields are named by their avro tags, the way
+// hamba/avro matches them, so a value can be encoded with the schema derived
+// from its own type:
+//
+//   - bool, int8, int16 and int32 become boolean and int
+//   - int64 becomes long, float32 float and float64 double
+//   - int is rejected: hamba/avro encodes it only as an AVRO int, so neither
+//     int nor long describes it, and int fields must be declared int32 or int64
+//   - string and named string types become string, []byte becomes bytes
+//   - [N]byte arrays become fixed types named after the Go type
+//   - time.Time becomes a timestamp-millis long and time.Duration a
+//     time-micros long
+//   - pointers become unions with null that default to null
+//   - slices become arrays and maps with string keys become maps
+//
+// Fields tagged avro:"-" and unexported fields are skipped, embedded structs
+// are flattened into the record as hamba/avro does.
+package avroschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/hamba/avro"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+// Options configures the derived schema.
+type Options struct {
+	// Namespace is the namespace of the named types.
+	Namespace string
+	// TimestampMicros derives time.Time as timestamp-micros instead of
+	// timestamp-millis.
+	TimestampMicros bool
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	bytesType    = reflect.TypeOf([]byte(nil))
+)
+
+// Schema derives the AVRO schema of the type of v.
+func Schema(v interface{}, opts Options) (string, error) {
+	if v == nil {
+		return "", fmt.Errorf("cannot derive a schema from nil")
+	}
+
+	d := &deriver{opts: opts, named: make(map[string]reflect.Type)}
+	node, err := d.schema(reflect.TypeOf(v), "")
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	if _, err := avro.ParseWithCache(string(b), "", &avro.SchemaCache{}); err != nil {
+		return "", fmt.Errorf("error parsing derived schema err:%w", err)
+	}
+
+	return string(b), nil
+}
+
+// Register derives the schema of v and registers it under the subject,
+// returning the schema id.
+func Register(c *schemaregistry.SchemaClient, subject string, v interface{}, opts Options) (int, error) {
+	schema, err := Schema(v, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.CreateSchema(subject, schema, schemaregistry.AVRO)
+}
+
+// Lookup derives the schema of v and looks it up under the subject.
+func Lookup(c *schemaregistry.SchemaClient, subject string, v interface{}, opts Options) (schemaregistry.SchemaResponse, error) {
+	schema, err := Schema(v, opts)
+	if err != nil {
+		return schemaregistry.SchemaResponse{}, err
+	}
+
+	return c.LookupSchema(subject, schema, schemaregistry.AVRO)
+}
+
+type record struct {
+	Type      string  `json:"type"`
+	Name      string  `json:"name"`
+	Namespace string  `json:"namespace,omitempty"`
+	Fields    []field `json:"fields"`
+}
+
+type field struct {
+	Name    string          `json:"name"`
+	Type    interface{}     `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+type fixed struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Size      int    `json:"size"`
+}
+
+type logical struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+}
+
+type array struct {
+	Type  string      `json:"type"`
+	Items interface{} `json:"items"`
+}
+
+type values struct {
+	Type   string      `json:"type"`
+	Values interface{} `json:"values"`
+}
+
+// deriver derives schemas, declaring every named type once.
+type deriver struct {
+	opts  Options
+	named map[string]reflect.Type
+}
+
+// schema returns the schema of the type. The path names the field the type
+// belongs to in error messages.
+func (d *deriver) schema(t reflect.Type, path string) (interface{}, error) {
+	switch t {
+	case timeType:
+		if d.opts.TimestampMicros {
+			return logical{Type: "long", LogicalType: "timestamp-micros"}, nil
+		}
+		return logical{Type: "long", LogicalType: "timestamp-millis"}, nil
+	case durationType:
+		return logical{Type: "long", LogicalType: "time-micros"}, nil
+	case bytesType:
+		return "bytes", nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return "int", nil
+	case reflect.Int64:
+		return "long", nil
+	case reflect.Int:
+		return nil, fmt.Errorf("field %s: int has no AVRO type it encodes as, use int32 or int64", path)
+	case reflect.Float32:
+		return "float", nil
+	case reflect.Float64:
+		return "double", nil
+	case reflect.String:
+		return "string", nil
+	case reflect.Ptr:
+		elem, err := d.schema(t.Elem(), path)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := elem.([]interface{}); ok {
+			return nil, fmt.Errorf("field %s: pointers to unions are not supported", path)
+		}
+		return []interface{}{"null", elem}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes", nil
+		}
+		items, err := d.schema(t.Elem(), path)
+		if err != nil {
+			return nil, err
+		}
+		return array{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("field %s: map keys must be strings, got %s", path, t.Key())
+		}
+		vals, err := d.schema(t.Elem(), path)
+		if err != nil {
+			return nil, err
+		}
+		return values{Type: "map", Values: vals}, nil
+	case reflect.Array:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return nil, fmt.Errorf("field %s: only byte arrays are supported, got %s", path, t)
+		}
+		return d.fixed(t, path)
+	case reflect.Struct:
+		return d.record(t, path)
+	}
+
+	return nil, fmt.Errorf("field %s: type %s is not supported", path, t)
+}
+
+// declare reports whether the named type has to be declared, or if it was
+// declared already and may be referenced by name.
+func (d *deriver) declare(name string, t reflect.Type, path string) (bool, error) {
+	if name == "" {
+		return false, fmt.Errorf("field %s: anonymous %s types are not supported", path, t.Kind())
+	}
+	if declared, ok := d.named[name]; ok {
+		if declared != t {
+			return false, fmt.Errorf("field %s: types %s and %s have the same name %s", path, declared, t, name)
+		}
+		return false, nil
+	}
+	d.named[name] = t
+
+	return true, nil
+}
+
+func (d *deriver) fullName(name string) string {
+	if d.opts.Namespace == "" {
+		return name
+	}
+
+	return d.opts.Namespace + "." + name
+}
+
+func (d *deriver) fixed(t reflect.Type, path string) (interface{}, error) {
+	ok, err := d.declare(t.Name(), t, path)
+	if err != nil || !ok {
+		return d.fullName(t.Name()), err
+	}
+
+	return fixed{Type: "fixed", Name: t.Name(), Namespace: d.opts.Namespace, Size: t.Len()}, nil
+}
+
+func (d *deriver) record(t reflect.Type, path string) (interface{}, error) {
+	ok, err := d.declare(t.Name(), t, path)
+	if err != nil || !ok {
+		return d.fullName(t.Name()), err
+	}
+
+	r := record{Type: "record", Name: t.Name(), Namespace: d.opts.Namespace, Fields: []field{}}
+	if err := d.fields(t, &r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// fields appends the fields of the struct to the record, flattening embedded
+// structs.
+func (d *deriver) fields(t reflect.Type, r *record) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("avro")
+		if tag == "-" {
+			continue
+		}
+		if sf.Anonymous {
+			embedded := sf.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := d.fields(embedded, r); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		name := sf.Name
+		if tag != "" {
+			name = tag
+		}
+		typ, err := d.schema(sf.Type, t.Name()+"."+sf.Name)
+		if err != nil {
+			return err
+		}
+
+		f := field{Name: name, Type: typ}
+		if sf.Type.Kind() == reflect.Ptr {
+			f.Default = json.RawMessage("null")
+		}
+		r.Fields = append(r.Fields, f)
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package avroschema
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/registrytest"
+)
+
+type Audit struct {
+	CreatedBy string `avro:"created_by"`
+}
+
+type Hash [4]byte
+
+type Address struct {
+	City string `avro:"city"`
+}
+
+type schemaAddress = Address
+
+type User struct {
+	Audit
+	ID       int64             `avro:"id"`
+	Name     string            `avro:"name"`
+	Age      int64             `avro:"age"`
+	Score    float64           `avro:"score"`
+	Active   bool              `avro:"active"`
+	Email    *string           `avro:"email"`
+	Created  time.Time         `avro:"created"`
+	Timeout  time.Duration     `avro:"timeout"`
+	Tags     []string          `avro:"tags"`
+	Labels   map[string]int64  `avro:"labels"`
+	Avatar   []byte            `avro:"avatar"`
+	Hash     Hash              `avro:"hash"`
+	Home     Address           `avro:"home"`
+	Work     *Address          `avro:"work"`
+	Previous []Address         `avro:"previous"`
+	Extra    map[string]string `avro:"-"`
+	internal string
+}
+
+const userSchema = `{"type":"record","name":"User","namespace":"com.test","fields":[` +
+	`{"name":"created_by","type":"string"},` +
+	`{"name":"id","type":"long"},` +
+	`{"name":"name","type":"string"},` +
+	`{"name":"age","type":"long"},` +
+	`{"name":"score","type":"double"},` +
+	`{"name":"active","type":"boolean"},` +
+	`{"name":"email","type":["null","string"],"default":null},` +
+	`{"name":"created","type":{"type":"long","logicalType":"timestamp-millis"}},` +
+	`{"name":"timeout","type":{"type":"long","logicalType":"time-micros"}},` +
+	`{"name":"tags","type":{"type":"array","items":"string"}},` +
+	`{"name":"labels","type":{"type":"map","values":"long"}},` +
+	`{"name":"avatar","type":"bytes"},` +
+	`{"name":"hash","type":{"type":"fixed","name":"Hash","namespace":"com.test","size":4}},` +
+	`{"name":"home","type":{"type":"record","name":"Address","namespace":"com.test","fields":[{"name":"city","type":"string"}]}},` +
+	`{"name":"work","type":["null","com.test.Address"],"default":null},` +
+	`{"name":"previous","type":{"type":"array","items":"com.test.Address"}}]}`
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema(User{}, Options{Namespace: "com.test"})
+	if err != nil {
+		t.Fatal("TestSchema: ", err)
+	}
+	if schema != userSchema {
+		t.Errorf("TestSchema: got\n%s\nwant\n%s", schema, userSchema)
+	}
+
+	ptr, err := Schema(&User{}, Options{Namespace: "com.test"})
+	if err != nil {
+		t.Fatal("TestSchema: ", err)
+	}
+	if ptr != `["null",`+userSchema+`]` {
+		t.Errorf("TestSchema: pointer got %s", ptr)
+	}
+}
+
+func TestSchemaRoundTrip(t *testing.T) {
+	derived, err := Schema(User{}, Options{})
+	if err != nil {
+		t.Fatal("TestSchemaRoundTrip: ", err)
+	}
+	schema := avro.MustParse(derived)
+
+	email := "jane@example.com"
+	in := User{
+		Audit:    Audit{CreatedBy: "admin"},
+		ID:       42,
+		Name:     "jane",
+		Age:      math.MaxInt32 + 1,
+		Email:    &email,
+		Created:  time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC),
+		Timeout:  3 * time.Second,
+		Tags:     []string{"a"},
+		Labels:   map[string]int64{"x": 1},
+		Hash:     Hash{1, 2, 3, 4},
+		Home:     Address{City: "Colombo"},
+		Previous: []Address{{City: "Kandy"}},
+	}
+	b, err := avro.Marshal(schema, in)
+	if err != nil {
+		t.Fatal("TestSchemaRoundTrip: ", err)
+	}
+	var out User
+	if err := avro.Unmarshal(schema, b, &out); err != nil {
+		t.Fatal("TestSchemaRoundTrip: ", err)
+	}
+	if out.CreatedBy != "admin" || out.ID != 42 || out.Age != in.Age || *out.Email != email || !out.Created.Equal(in.Created) ||
+		out.Timeout != in.Timeout || out.Labels["x"] != 1 || out.Hash != in.Hash || out.Home.City != "Colombo" ||
+		out.Work != nil || out.Previous[0].City != "Kandy" {
+		t.Errorf("TestSchemaRoundTrip: got %+v want %+v", out, in)
+	}
+}
+
+func TestSchemaInt(t *testing.T) {
+	type Counter struct {
+		Count int `avro:"count"`
+	}
+	type Counter64 struct {
+		Count int64 `avro:"count"`
+	}
+	if _, err := Schema(Counter{}, Options{}); err == nil || !strings.Contains(err.Error(), "Counter.Count") {
+		t.Error("TestSchemaInt: int field not rejected", err)
+	}
+	if _, err := Register(nil, "counters", Counter{}, Options{}); err == nil {
+		t.Error("TestSchemaInt: int field registered")
+	}
+
+	derived, err := Schema(Counter64{}, Options{})
+	if err != nil || derived != `{"type":"record","name":"Counter64","fields":[{"name":"count","type":"long"}]}` {
+		t.Fatal("TestSchemaInt: unexpected schema", derived, err)
+	}
+	schema := avro.MustParse(derived)
+	b, err := avro.Marshal(schema, Counter64{Count: math.MaxInt32 + 1})
+	if err != nil {
+		t.Fatal("TestSchemaInt: ", err)
+	}
+	var out Counter64
+	if err := avro.Unmarshal(schema, b, &out); err != nil || out.Count != math.MaxInt32+1 {
+		t.Error("TestSchemaInt: value truncated", out.Count, err)
+	}
+}
+
+func TestSchemaTimestampMicros(t *testing.T) {
+	type Event struct {
+		At time.Time
+	}
+	schema, err := Schema(Event{}, Options{TimestampMicros: true})
+	want := `{"type":"record","name":"Event","fields":[{"name":"At","type":{"type":"long","logicalType":"timestamp-micros"}}]}`
+	if err != nil || schema != want {
+		t.Errorf("TestSchemaTimestampMicros: got %s %v want %s", schema, err, want)
+	}
+}
+
+func TestSchemaUnsupported(t *testing.T) {
+	type Bad struct {
+		Value interface{}
+	}
+	type BadKey struct {
+		Value map[int]string
+	}
+	type Address struct{ Street string }
+	type Duplicate struct {
+		Home  Address
+		Other *schemaAddress
+	}
+	for _, v := range []interface{}{nil, Bad{}, BadKey{}, uint(1), [2]int{}, struct{ A int }{}, Duplicate{}} {
+		if schema, err := Schema(v, Options{}); err == nil {
+			t.Errorf("TestSchemaUnsupported: %T derived %s", v, schema)
+		}
+	}
+}
+
+func TestSchemaRecursive(t *testing.T) {
+	type Node struct {
+		Value int64 `avro:"value"`
+		Next  *Node `avro:"next"`
+	}
+	schema, err := Schema(Node{}, Options{})
+	want := `{"type":"record","name":"Node","fields":[{"name":"value","type":"long"},{"name":"next","type":["null","Node"],"default":null}]}`
+	if err != nil || schema != want {
+		t.Errorf("TestSchemaRecursive: got %s %v want %s", schema, err, want)
+	}
+}
+
+func TestRegisterAndLookup(t *testing.T) {
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := schemaregistry.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal("TestRegisterAndLookup: ", err)
+	}
+
+	if _, err := Lookup(c, "users-value", User{}, Options{}); !errors.Is(err, schemaregistry.ErrSubjectNotFound) {
+		t.Error("TestRegisterAndLookup: lookup before register returned", err)
+	}
+	id, err := Register(c, "users-value", User{}, Options{})
+	if err != nil {
+		t.Fatal("TestRegisterAndLookup: ", err)
+	}
+	resp, err := Lookup(c, "users-value", &User{}, Options{})
+	if err == nil {
+		t.Error("TestRegisterAndLookup: pointer schema matched the struct schema", resp)
+	}
+	resp, err = Lookup(c, "users-value", User{}, Options{})
+	if err != nil || resp.ID != id || resp.Version != 1 {
+		t.Error("TestRegisterAndLookup: unexpected lookup", resp, err)
+	}
+}
@@ -0,0 +1,324 @@
+// Package backup exports the contents of a schema registry and restores them
+// into another registry, keeping the original schema ids and versions.
+//
+// A Backup is read from a registry with Export and written with Restore.
+// In between it can be stored as a directory with WriteDir and ReadDir, or as
+// a single gzipped tar archive with WriteArchive and ReadArchive. Both layouts
+// are deterministic, so backups of the same registry state are identical.
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/internal/refs"
+)
+
+// Backup is a snapshot of a schema registry.
+type Backup struct {
+	Compatibility schemaregistry.CompatibilityLevel `json:"compatibilityLevel,omitempty"`
+	Contract
+	Mode     schemaregistry.Mode `json:"mode,omitempty"`
+	Subjects []Subject           `json:"-"`
+}
+
+// Subject is a subject with its subject level config and mode, which are empty
+// when the subject uses the global settings.
+type Subject struct {
+	Name          string                            `json:"subject"`
+	Compatibility schemaregistry.CompatibilityLevel `json:"compatibilityLevel,omitempty"`
+	Contract
+	Mode     schemaregistry.Mode `json:"mode,omitempty"`
+	Versions []Version           `json:"-"`
+}
+
+// Contract holds the default and override metadata and rule sets of a config,
+// applied by the registry to the schemas registered without them and to every
+// registered schema respectively.
+type Contract struct {
+	DefaultMetadata  *schemaregistry.Metadata `json:"defaultMetadata,omitempty"`
+	OverrideMetadata *schemaregistry.Metadata `json:"overrideMetadata,omitempty"`
+	DefaultRuleSet   *schemaregistry.RuleSet  `json:"defaultRuleSet,omitempty"`
+	OverrideRuleSet  *schemaregistry.RuleSet  `json:"overrideRuleSet,omitempty"`
+}
+
+func contractOf(config schemaregistry.Config) Contract {
+	return Contract{
+		DefaultMetadata:  config.DefaultMetadata,
+		OverrideMetadata: config.OverrideMetadata,
+		DefaultRuleSet:   config.DefaultRuleSet,
+		OverrideRuleSet:  config.OverrideRuleSet,
+	}
+}
+
+func (c Contract) empty() bool {
+	return c.DefaultMetadata == nil && c.OverrideMetadata == nil && c.DefaultRuleSet == nil && c.OverrideRuleSet == nil
+}
+
+// config returns the config with the compatibility level and the contract.
+func (c Contract) config(level schemaregistry.CompatibilityLevel) schemaregistry.Config {
+	return schemaregistry.Config{
+		CompatibilityLevel: level,
+		DefaultMetadata:    c.DefaultMetadata,
+		OverrideMetadata:   c.OverrideMetadata,
+		DefaultRuleSet:     c.DefaultRuleSet,
+		OverrideRuleSet:    c.OverrideRuleSet,
+	}
+}
+
+// Version is a registered schema version.
+type Version struct {
+	Subject    string                     `json:"subject"`
+	Version    int                        `json:"version"`
+	ID         int                        `json:"id"`
+	SchemaType schemaregistry.SchemaType  `json:"schemaType"`
+	Schema     string                     `json:"schema"`
+	References []schemaregistry.Reference `json:"references,omitempty"`
+	Metadata   *schemaregistry.Metadata   `json:"metadata,omitempty"`
+	RuleSet    *schemaregistry.RuleSet    `json:"ruleSet,omitempty"`
+	Deleted    bool                       `json:"deleted,omitempty"`
+}
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// IncludeDeleted exports soft deleted subjects and versions.
+	IncludeDeleted bool
+}
+
+// Export reads the subjects, versions, configs and modes of the registry.
+func Export(c *schemaregistry.SchemaClient, opts ExportOptions) (*Backup, error) {
+	b := &Backup{}
+
+	config, err := c.GetFullConfig("")
+	if err != nil {
+		return nil, fmt.Errorf("error obtaining global config err:%w", err)
+	}
+	b.Compatibility, b.Contract = config.CompatibilityLevel, contractOf(config)
+	if b.Mode, err = c.GetMode(""); err != nil {
+		return nil, fmt.Errorf("error obtaining global mode err:%w", err)
+	}
+
+	var names []string
+	if opts.IncludeDeleted {
+		names, err = c.GetSubjectsWithDeleted()
+	} else {
+		names, err = c.GetSubjects()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error obtaining subjects err:%w", err)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		subject, err := exportSubject(c, name, opts)
+		if err != nil {
+			return nil, err
+		}
+		b.Subjects = append(b.Subjects, subject)
+	}
+
+	return b, nil
+}
+
+func exportSubject(c *schemaregistry.SchemaClient, name string, opts ExportOptions) (Subject, error) {
+	subject := Subject{Name: name}
+
+	config, err := c.GetFullConfig(name)
+	switch {
+	case err == nil:
+		subject.Compatibility, subject.Contract = config.CompatibilityLevel, contractOf(config)
+	case !errors.Is(err, schemaregistry.ErrSubjectCompatibilityNotFound):
+		return Subject{}, fmt.Errorf("error obtaining config for subject:%s err:%w", name, err)
+	}
+	mode, err := c.GetMode(name)
+	switch {
+	case err == nil:
+		subject.Mode = mode
+	case !errors.Is(err, schemaregistry.ErrSubjectModeNotFound):
+		return Subject{}, fmt.Errorf("error obtaining mode for subject:%s err:%w", name, err)
+	}
+
+	live, err := c.GetVersions(name)
+	if err != nil && !errors.Is(err, schemaregistry.ErrSubjectNotFound) {
+		return Subject{}, fmt.Errorf("error obtaining versions for subject:%s err:%w", name, err)
+	}
+	versions := live
+	if opts.IncludeDeleted {
+		if versions, err = c.GetVersionsWithDeleted(name); err != nil {
+			return Subject{}, fmt.Errorf("error obtaining versions for subject:%s err:%w", name, err)
+		}
+	}
+
+	isLive := make(map[int]bool, len(live))
+	for _, v := range live {
+		isLive[v] = true
+	}
+	sort.Ints(versions)
+	for _, v := range versions {
+		var resp schemaregistry.SchemaResponse
+		if isLive[v] {
+			resp, err = c.GetSchemaByVersion(name, v)
+		} else {
+			resp, err = c.GetSchemaByVersionWithDeleted(name, v)
+		}
+		if err != nil {
+			return Subject{}, fmt.Errorf("error obtaining subject:%s version:%d err:%w", name, v, err)
+		}
+
+		schemaType := schemaregistry.AVRO
+		if resp.SchemaType != nil && *resp.SchemaType != "" {
+			schemaType = *resp.SchemaType
+		}
+		subject.Versions = append(subject.Versions, Version{
+			Subject:    name,
+			Version:    resp.Version,
+			ID:         resp.ID,
+			SchemaType: schemaType,
+			Schema:     resp.Schema,
+			References: resp.References,
+			Metadata:   resp.Metadata,
+			RuleSet:    resp.RuleSet,
+			Deleted:    !isLive[v],
+		})
+	}
+
+	return subject, nil
+}
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	// SkipConfig leaves the global and subject configs and modes unchanged.
+	SkipConfig bool
+}
+
+// Restore registers the backed up schemas in the registry with their original
+// ids and versions. Each subject is switched to IMPORT mode while its schemas
+// are registered, and schemas are registered after the schemas they reference.
+// Soft deleted versions are registered and then soft deleted again.
+func Restore(c *schemaregistry.SchemaClient, b *Backup, opts RestoreOptions) error {
+	versions, err := restoreOrder(b)
+	if err != nil {
+		return err
+	}
+
+	for _, subject := range b.Subjects {
+		if len(subject.Versions) == 0 {
+			continue
+		}
+		if _, err := c.SetMode(subject.Name, schemaregistry.ModeImport); err != nil {
+			return fmt.Errorf("error setting import mode for subject:%s err:%w", subject.Name, err)
+		}
+	}
+
+	for _, v := range versions {
+		if _, err := c.ImportSchemaWithMetadata(v.Subject, v.ID, v.Version, v.Schema, v.SchemaType, v.Metadata, v.RuleSet, v.References...); err != nil {
+			return fmt.Errorf("error importing subject:%s version:%d err:%w", v.Subject, v.Version, err)
+		}
+	}
+
+	// Delete in reverse order so versions are deleted before the versions they reference.
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if !v.Deleted {
+			continue
+		}
+		if _, err := c.DeleteSchemaVersion(v.Subject, v.Version, false); err != nil {
+			return fmt.Errorf("error deleting subject:%s version:%d err:%w", v.Subject, v.Version, err)
+		}
+	}
+
+	for _, subject := range b.Subjects {
+		if err := restoreSubjectSettings(c, subject, opts); err != nil {
+			return err
+		}
+	}
+	if opts.SkipConfig {
+		return nil
+	}
+	if b.Compatibility != "" || !b.Contract.empty() {
+		if _, err := c.SetFullConfig("", b.Contract.config(b.Compatibility)); err != nil {
+			return fmt.Errorf("error setting global config err:%w", err)
+		}
+	}
+	if b.Mode != "" {
+		if _, err := c.SetMode("", b.Mode); err != nil {
+			return fmt.Errorf("error setting global mode err:%w", err)
+		}
+	}
+
+	return nil
+}
+
+func restoreSubjectSettings(c *schemaregistry.SchemaClient, subject Subject, opts RestoreOptions) error {
+	if len(subject.Versions) > 0 {
+		var err error
+		if subject.Mode != "" && !opts.SkipConfig {
+			_, err = c.SetMode(subject.Name, subject.Mode)
+		} else {
+			_, err = c.DeleteMode(subject.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("error restoring mode for subject:%s err:%w", subject.Name, err)
+		}
+	} else if subject.Mode != "" && !opts.SkipConfig {
+		if _, err := c.SetMode(subject.Name, subject.Mode); err != nil {
+			return fmt.Errorf("error restoring mode for subject:%s err:%w", subject.Name, err)
+		}
+	}
+
+	if (subject.Compatibility != "" || !subject.Contract.empty()) && !opts.SkipConfig {
+		if _, err := c.SetFullConfig(subject.Name, subject.Contract.config(subject.Compatibility)); err != nil {
+			return fmt.Errorf("error restoring config for subject:%s err:%w", subject.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreOrder returns all backed up versions ordered so that every version
+// comes after the versions it references. Otherwise versions are ordered by
+// schema id, then subject and version.
+func restoreOrder(b *Backup) ([]Version, error) {
+	var all []Version
+	index := map[string]int{}
+	for _, subject := range b.Subjects {
+		all = append(all, subject.Versions...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].ID != all[j].ID {
+			return all[i].ID < all[j].ID
+		}
+		if all[i].Subject != all[j].Subject {
+			return all[i].Subject < all[j].Subject
+		}
+		return all[i].Version < all[j].Version
+	})
+	for i, v := range all {
+		index[refs.VersionKey(v.Subject, v.Version)] = i
+	}
+
+	order, err := refs.Sort(len(all), func(i int) []int {
+		var deps []int
+		for _, ref := range all[i].References {
+			// References missing from the backup are expected to exist in
+			// the target registry.
+			if j, ok := index[refs.VersionKey(ref.Subject, ref.Version)]; ok {
+				deps = append(deps, j)
+			}
+		}
+		return deps
+	}, func(i int) error {
+		return fmt.Errorf("reference cycle at subject:%s version:%d", all[i].Subject, all[i].Version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Version, len(order))
+	for i, j := range order {
+		out[i] = all[j]
+	}
+
+	return out, nil
+}
@@ -0,0 +1,189 @@
+package backup
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/registrytest"
+)
+
+const address = `{"type":"record","name":"Address","namespace":"com.test","fields":[{"name":"city","type":"string"}]}`
+
+const user = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"address","type":"com.test.Address"}]}`
+
+const userV2 = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"address","type":"com.test.Address"},{"name":"age","type":"int","default":0}]}`
+
+func newClient(t *testing.T) *schemaregistry.SchemaClient {
+	t.Helper()
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+
+	c, err := schemaregistry.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal("newClient: ", err)
+	}
+
+	return c
+}
+
+// seed fills the registry with a referencing schema whose id is lower than the
+// id of the schema it references, a schema with metadata and a rule set, a soft
+// deleted version and subject settings.
+func seed(t *testing.T, c *schemaregistry.SchemaClient) {
+	t.Helper()
+	ref := schemaregistry.Reference{Name: "com.test.Address", Subject: "address", Version: 1}
+	metadata := &schemaregistry.Metadata{Tags: map[string][]string{"Address.city": {"PII"}}, Properties: map[string]string{"owner": "team-a"}}
+	ruleSet := &schemaregistry.RuleSet{DomainRules: []schemaregistry.Rule{{Name: "encrypt", Kind: schemaregistry.RuleKindTransform, Mode: schemaregistry.RuleModeWriteRead, Type: "ENCRYPT", Tags: []string{"PII"}}}}
+
+	steps := []func() error{
+		func() error { _, err := c.SetMode("", schemaregistry.ModeImport); return err },
+		func() error {
+			_, err := c.ImportSchemaWithMetadata("address", 10, 1, address, schemaregistry.AVRO, metadata, ruleSet)
+			return err
+		},
+		func() error { _, err := c.ImportSchema("users", 5, 1, user, schemaregistry.AVRO, ref); return err },
+		func() error { _, err := c.SetMode("", schemaregistry.ModeReadWrite); return err },
+		func() error { _, err := c.CreateSchema("users", userV2, schemaregistry.AVRO, ref); return err },
+		func() error { _, err := c.DeleteSchemaVersion("users", 1, false); return err },
+		func() error { _, err := c.SetConfig("users", schemaregistry.CompatibilityFull); return err },
+		func() error { _, err := c.SetMode("address", schemaregistry.ModeReadOnly); return err },
+		func() error { _, err := c.SetConfig("", schemaregistry.CompatibilityForward); return err },
+		func() error {
+			_, err := c.SetFullConfig("", schemaregistry.Config{DefaultMetadata: &schemaregistry.Metadata{Properties: map[string]string{"owner": "platform"}}})
+			return err
+		},
+		func() error {
+			_, err := c.SetFullConfig("users", schemaregistry.Config{OverrideRuleSet: ruleSet, OverrideMetadata: metadata})
+			return err
+		},
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("seed: step %d: %v", i, err)
+		}
+	}
+}
+
+func TestExportRestoreDir(t *testing.T) {
+	src := newClient(t)
+	seed(t, src)
+
+	b, err := Export(src, ExportOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal("TestExportRestoreDir: ", err)
+	}
+	if len(b.Subjects) != 2 || len(b.Subjects[1].Versions) != 2 || !b.Subjects[1].Versions[0].Deleted {
+		t.Fatalf("TestExportRestoreDir: unexpected backup %+v", b)
+	}
+	if v := b.Subjects[0].Versions[0]; v.Metadata == nil || v.Metadata.Properties["owner"] != "team-a" || v.RuleSet == nil {
+		t.Fatalf("TestExportRestoreDir: metadata and rule set not exported %+v", v)
+	}
+	if b.Compatibility != schemaregistry.CompatibilityForward || b.DefaultMetadata == nil || b.DefaultMetadata.Properties["owner"] != "platform" {
+		t.Fatalf("TestExportRestoreDir: global config not exported %+v", b)
+	}
+	if users := b.Subjects[1]; users.Compatibility != schemaregistry.CompatibilityFull || users.OverrideRuleSet == nil || users.OverrideMetadata == nil {
+		t.Fatalf("TestExportRestoreDir: subject config not exported %+v", users)
+	}
+
+	dir := t.TempDir()
+	if err := b.WriteDir(dir); err != nil {
+		t.Fatal("TestExportRestoreDir: ", err)
+	}
+	read, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal("TestExportRestoreDir: ", err)
+	}
+	if !reflect.DeepEqual(read, b) {
+		t.Fatalf("TestExportRestoreDir: read %+v, want %+v", read, b)
+	}
+
+	dst := newClient(t)
+	if err := Restore(dst, read, RestoreOptions{}); err != nil {
+		t.Fatal("TestExportRestoreDir: ", err)
+	}
+	restored, err := Export(dst, ExportOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal("TestExportRestoreDir: ", err)
+	}
+	if !reflect.DeepEqual(restored, b) {
+		t.Errorf("TestExportRestoreDir: restored %+v, want %+v", restored, b)
+	}
+	if config, err := dst.GetFullConfig("users"); err != nil || config.OverrideRuleSet == nil || config.OverrideMetadata.Properties["owner"] != "team-a" {
+		t.Error("TestExportRestoreDir: subject config not restored", config, err)
+	}
+	if config, err := dst.GetFullConfig(""); err != nil || config.DefaultMetadata == nil || config.DefaultMetadata.Properties["owner"] != "platform" {
+		t.Error("TestExportRestoreDir: global config not restored", config, err)
+	}
+
+	latest, err := dst.GetLatestSchema("users")
+	if err != nil || latest.ID != b.Subjects[1].Versions[1].ID || latest.Version != 2 {
+		t.Error("TestExportRestoreDir: latest users schema", latest, err)
+	}
+}
+
+func TestExportSkipsDeleted(t *testing.T) {
+	src := newClient(t)
+	seed(t, src)
+
+	b, err := Export(src, ExportOptions{})
+	if err != nil {
+		t.Fatal("TestExportSkipsDeleted: ", err)
+	}
+	for _, subject := range b.Subjects {
+		for _, v := range subject.Versions {
+			if v.Deleted {
+				t.Error("TestExportSkipsDeleted: exported deleted version", v)
+			}
+		}
+	}
+}
+
+func TestArchiveIsDeterministic(t *testing.T) {
+	src := newClient(t)
+	seed(t, src)
+
+	b, err := Export(src, ExportOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal("TestArchiveIsDeterministic: ", err)
+	}
+
+	var first, second bytes.Buffer
+	if err := b.WriteArchive(&first); err != nil {
+		t.Fatal("TestArchiveIsDeterministic: ", err)
+	}
+	if err := b.WriteArchive(&second); err != nil {
+		t.Fatal("TestArchiveIsDeterministic: ", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("TestArchiveIsDeterministic: archives differ")
+	}
+
+	read, err := ReadArchive(&first)
+	if err != nil {
+		t.Fatal("TestArchiveIsDeterministic: ", err)
+	}
+	if !reflect.DeepEqual(read, b) {
+		t.Errorf("TestArchiveIsDeterministic: read %+v, want %+v", read, b)
+	}
+}
+
+func TestRestoreOrder(t *testing.T) {
+	b := &Backup{Subjects: []Subject{
+		{Name: "a", Versions: []Version{{Subject: "a", Version: 1, ID: 1, References: []schemaregistry.Reference{{Name: "b", Subject: "b", Version: 1}}}}},
+		{Name: "b", Versions: []Version{{Subject: "b", Version: 1, ID: 2, References: []schemaregistry.Reference{{Name: "a", Subject: "a", Version: 1}}}}},
+	}}
+	if _, err := restoreOrder(b); err == nil {
+		t.Error("TestRestoreOrder: reference cycle not detected")
+	}
+
+	b.Subjects[1].Versions[0].References = nil
+	versions, err := restoreOrder(b)
+	if err != nil {
+		t.Fatal("TestRestoreOrder: ", err)
+	}
+	if versions[0].Subject != "b" || versions[1].Subject != "a" {
+		t.Error("TestRestoreOrder: referenced version not restored first", versions)
+	}
+}
@@ -0,0 +1,234 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// The backup layout is
+//
+//	registry.json                          global config and mode
+//	subjects/<subject>/subject.json        subject config and mode
+//	subjects/<subject>/versions/<n>.json   schema version
+//
+// where subject names are path escaped.
+const (
+	registryFile = "registry.json"
+	subjectsDir  = "subjects"
+	subjectFile  = "subject.json"
+	versionsDir  = "versions"
+)
+
+var layoutJSON = jsoniter.Config{SortMapKeys: true, EscapeHTML: false}.Froze()
+
+// files returns the backup as file contents keyed by slash separated path.
+func (b *Backup) files() (map[string][]byte, error) {
+	files := map[string][]byte{}
+	add := func(name string, v interface{}) error {
+		data, err := layoutJSON.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		files[name] = append(data, '\n')
+		return nil
+	}
+
+	if err := add(registryFile, b); err != nil {
+		return nil, err
+	}
+	for _, subject := range b.Subjects {
+		dir := path.Join(subjectsDir, url.PathEscape(subject.Name))
+		if err := add(path.Join(dir, subjectFile), subject); err != nil {
+			return nil, err
+		}
+		for _, v := range subject.Versions {
+			if err := add(path.Join(dir, versionsDir, strconv.Itoa(v.Version)+".json"), v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// fromFiles builds a backup from file contents keyed by slash separated path.
+func fromFiles(files map[string][]byte) (*Backup, error) {
+	data, ok := files[registryFile]
+	if !ok {
+		return nil, errors.New("backup is missing " + registryFile)
+	}
+	b := &Backup{}
+	if err := layoutJSON.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("invalid %s err:%w", registryFile, err)
+	}
+
+	subjects := map[string]*Subject{}
+	for name, data := range files {
+		parts := strings.Split(name, "/")
+		if len(parts) != 3 || parts[0] != subjectsDir || parts[2] != subjectFile {
+			continue
+		}
+		subject := &Subject{}
+		if err := layoutJSON.Unmarshal(data, subject); err != nil {
+			return nil, fmt.Errorf("invalid %s err:%w", name, err)
+		}
+		subjects[parts[1]] = subject
+	}
+	for name, data := range files {
+		parts := strings.Split(name, "/")
+		if len(parts) != 4 || parts[0] != subjectsDir || parts[2] != versionsDir || !strings.HasSuffix(parts[3], ".json") {
+			continue
+		}
+		subject, ok := subjects[parts[1]]
+		if !ok {
+			return nil, fmt.Errorf("backup is missing %s for %s", subjectFile, name)
+		}
+		var v Version
+		if err := layoutJSON.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("invalid %s err:%w", name, err)
+		}
+		v.Subject = subject.Name
+		subject.Versions = append(subject.Versions, v)
+	}
+
+	for _, subject := range subjects {
+		versions := subject.Versions
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+		b.Subjects = append(b.Subjects, *subject)
+	}
+	sort.Slice(b.Subjects, func(i, j int) bool { return b.Subjects[i].Name < b.Subjects[j].Name })
+
+	return b, nil
+}
+
+func sortedNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// WriteDir writes the backup to the directory, creating it if needed. Files of
+// an earlier backup in the directory are not removed.
+func (b *Backup) WriteDir(dir string) error {
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedNames(files) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, files[name], 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadDir reads a backup written by WriteDir.
+func ReadDir(dir string) (*Backup, error) {
+	files := map[string][]byte{}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fromFiles(files)
+}
+
+// WriteArchive writes the backup as a gzipped tar archive with the directory
+// layout. Entries are sorted and carry no timestamps, so the archive only
+// depends on the backup contents.
+func (b *Backup) WriteArchive(w io.Writer) error {
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range sortedNames(files) {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(files[name])),
+			ModTime: time.Unix(0, 0),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// ReadArchive reads a backup written by WriteArchive.
+func ReadArchive(r io.Reader) (*Backup, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+
+	return fromFiles(files)
+}
@@ -0,0 +1,193 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails every request without sending it.
+	CircuitOpen
+	// CircuitHalfOpen lets a few trial requests through to find out whether
+	// the registry recovered.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrCircuitOpen is returned for the requests not sent because the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("schema registry circuit breaker open")
+
+// CircuitBreakerSettings configures a circuit breaker. Requests failing to
+// reach the registry and requests answered with a server error are failures,
+// other registry errors are not.
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures opening the
+	// circuit, 5 if not set.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before trial requests
+	// are let through, 30 seconds if not set.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of trial requests let through at once
+	// when the circuit is half-open, and the number of them that must succeed
+	// to close it, 1 if not set. A failed trial request opens the circuit again.
+	HalfOpenRequests int
+	// OnStateChange is called by the request changing the state of the circuit.
+	OnStateChange func(from, to CircuitState)
+}
+
+// circuitBreaker fails requests fast while the registry is unhealthy.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	settings  CircuitBreakerSettings
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	trials    int
+	successes int
+	now       func() time.Time
+}
+
+func newCircuitBreaker(settings CircuitBreakerSettings, now func() time.Time) *circuitBreaker {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = defaultFailureThreshold
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = defaultOpenTimeout
+	}
+	if settings.HalfOpenRequests <= 0 {
+		settings.HalfOpenRequests = defaultHalfOpenRequests
+	}
+
+	return &circuitBreaker{settings: settings, now: now}
+}
+
+// current returns the current state of the circuit.
+func (b *circuitBreaker) current() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.settings.OpenTimeout {
+		return CircuitHalfOpen
+	}
+
+	return b.state
+}
+
+// allow reports whether a request may be sent and whether it is a trial
+// request. Every allowed request must be followed by a call to done.
+func (b *circuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	from := b.state
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.settings.OpenTimeout {
+		b.state = CircuitHalfOpen
+		b.trials = 0
+		b.successes = 0
+	}
+
+	var trial bool
+	var err error
+	switch b.state {
+	case CircuitOpen:
+		err = ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.trials >= b.settings.HalfOpenRequests {
+			err = ErrCircuitOpen
+		} else {
+			b.trials++
+			trial = true
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+
+	return trial, err
+}
+
+// done records the outcome of an allowed request. Requests that neither
+// succeeded nor failed, such as canceled requests, are ignored.
+func (b *circuitBreaker) done(trial, failed, ignored bool) {
+	b.mu.Lock()
+	from := b.state
+	if trial {
+		b.trials--
+	}
+	switch {
+	case ignored:
+	case b.state == CircuitClosed && !trial:
+		if failed {
+			b.failures++
+			if b.failures >= b.settings.FailureThreshold {
+				b.open()
+			}
+		} else {
+			b.failures = 0
+		}
+	case b.state == CircuitHalfOpen && trial:
+		if failed {
+			b.open()
+			break
+		}
+		b.successes++
+		if b.successes >= b.settings.HalfOpenRequests {
+			b.state = CircuitClosed
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+func (b *circuitBreaker) open() {
+	b.state = CircuitOpen
+	b.openedAt = b.now()
+	b.failures = 0
+}
+
+func (b *circuitBreaker) notify(from, to CircuitState) {
+	if from != to && b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(from, to)
+	}
+}
+
+// requestFailed reports whether the outcome of a request counts as a failure of
+// the registry, and whether it should be ignored because the request context
+// ended.
+func requestFailed(ctx context.Context, err error) (bool, bool) {
+	if err == nil {
+		return false, false
+	}
+	if ctx.Err() != nil {
+		return false, true
+	}
+
+	return unavailable(err), false
+}
@@ -0,0 +1,230 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	var changes []string
+	b := newCircuitBreaker(CircuitBreakerSettings{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		HalfOpenRequests: 2,
+		OnStateChange: func(from, to CircuitState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	}, func() time.Time { return now })
+
+	request := func(failed bool) error {
+		trial, err := b.allow()
+		if err != nil {
+			return err
+		}
+		b.done(trial, failed, false)
+		return nil
+	}
+
+	request(true)
+	request(false)
+	request(true)
+	if b.current() != CircuitClosed {
+		t.Error("TestCircuitBreaker: circuit opened by non consecutive failures")
+	}
+	request(true)
+	if b.current() != CircuitOpen {
+		t.Fatal("TestCircuitBreaker: circuit not opened", b.current())
+	}
+	if err := request(false); !errors.Is(err, ErrCircuitOpen) {
+		t.Error("TestCircuitBreaker: request allowed while open", err)
+	}
+
+	now = now.Add(time.Minute)
+	if b.current() != CircuitHalfOpen {
+		t.Error("TestCircuitBreaker: circuit not half-open after the timeout", b.current())
+	}
+	first, _ := b.allow()
+	second, _ := b.allow()
+	if _, err := b.allow(); !first || !second || !errors.Is(err, ErrCircuitOpen) {
+		t.Error("TestCircuitBreaker: unexpected trial requests", first, second, err)
+	}
+	b.done(true, false, false)
+	b.done(true, true, false)
+	if b.current() != CircuitOpen {
+		t.Error("TestCircuitBreaker: failed trial did not open the circuit", b.current())
+	}
+
+	now = now.Add(time.Minute)
+	request(false)
+	trial, _ := b.allow()
+	b.done(trial, false, true)
+	if b.current() != CircuitHalfOpen {
+		t.Error("TestCircuitBreaker: ignored trial counted", b.current())
+	}
+	request(false)
+	if b.current() != CircuitClosed {
+		t.Error("TestCircuitBreaker: circuit not closed by successful trials", b.current())
+	}
+
+	want := "closed->open,open->half-open,half-open->open,open->half-open,half-open->closed"
+	if got := strings.Join(changes, ","); got != want {
+		t.Errorf("TestCircuitBreaker: got state changes %s want %s", got, want)
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var mu sync.Mutex
+	status := 503
+	requests := 0
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if status == 404 {
+			return &http.Response{Body: ioutil.NopCloser(strings.NewReader(`{"error_code":40401,"message":"Subject not found."}`)), StatusCode: 404}, nil
+		}
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(`{"error_code":50001,"message":"Store error."}`)), StatusCode: status}, nil
+	}
+	opened := make(chan struct{}, 1)
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(client), WithRequestCircuitBreaker(CircuitBreakerSettings{
+		FailureThreshold: 3,
+		OpenTimeout:      20 * time.Millisecond,
+		OnStateChange: func(from, to CircuitState) {
+			if to == CircuitOpen {
+				opened <- struct{}{}
+			}
+		},
+	}))
+	if err != nil {
+		t.Fatal("TestClientCircuitBreaker: ", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		var out []string
+		c.RequestContext(ctx, http.MethodGet, "/subjects", nil, &out)
+	}
+	if c.CircuitState() != CircuitClosed {
+		t.Error("TestClientCircuitBreaker: canceled requests counted as failures")
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetSubjects(); !errors.Is(err, ErrBackendStore) {
+			t.Error("TestClientCircuitBreaker: unexpected error", err)
+		}
+	}
+	select {
+	case <-opened:
+	default:
+		t.Fatal("TestClientCircuitBreaker: circuit not opened")
+	}
+	sent := requests
+	if _, err := c.GetSubjects(); !errors.Is(err, ErrCircuitOpen) || requests != sent {
+		t.Error("TestClientCircuitBreaker: request sent while open", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	mu.Lock()
+	status = 404
+	mu.Unlock()
+	if _, err := c.GetSubjects(); !errors.Is(err, ErrSubjectNotFound) || c.CircuitState() != CircuitClosed {
+		t.Error("TestClientCircuitBreaker: circuit not closed by a client error", err, c.CircuitState())
+	}
+}
+
+func TestRegistryCircuitBreakerServesCache(t *testing.T) {
+	var mu sync.Mutex
+	down := false
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			return nil, errors.New("connection refused")
+		}
+		body := `{"subject":"users","version":2,"id":7,"schema":"\"string\""}`
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(body)), StatusCode: 200}, nil
+	}
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithCircuitBreaker(CircuitBreakerSettings{FailureThreshold: 1}))
+	if err != nil {
+		t.Fatal("TestRegistryCircuitBreakerServesCache: ", err)
+	}
+	latest, err := reg.GetLatestSchema("users")
+	if err != nil {
+		t.Fatal("TestRegistryCircuitBreakerServesCache: ", err)
+	}
+
+	mu.Lock()
+	down = true
+	mu.Unlock()
+	if _, err := reg.GetLatestSchema("users"); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Error("TestRegistryCircuitBreakerServesCache: unexpected error before the circuit opened", err)
+	}
+	if reg.registry.CircuitState() != CircuitOpen {
+		t.Fatal("TestRegistryCircuitBreakerServesCache: circuit not opened")
+	}
+
+	if schema, err := reg.GetLatestSchema("users"); err != nil || schema != latest {
+		t.Error("TestRegistryCircuitBreakerServesCache: cached latest schema not served", schema, err)
+	}
+	if schema, err := reg.GetSchemaByID(7); err != nil || schema != latest {
+		t.Error("TestRegistryCircuitBreakerServesCache: cached schema not served", schema, err)
+	}
+	if _, err := reg.GetLatestSchema("orders"); !errors.Is(err, ErrCircuitOpen) {
+		t.Error("TestRegistryCircuitBreakerServesCache: unexpected error", err)
+	}
+}
+
+func TestClientCircuitBreakerBeforeLimits(t *testing.T) {
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(`{"error_code":50001,"message":"Store error."}`)), StatusCode: 503}, nil
+	}
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(client),
+		WithRequestLimits(RequestLimits{RequestsPerSecond: 0.001, Burst: 1, NoWait: true}),
+		WithRequestCircuitBreaker(CircuitBreakerSettings{FailureThreshold: 1, OpenTimeout: time.Hour}))
+	if err != nil {
+		t.Fatal("TestClientCircuitBreakerBeforeLimits: ", err)
+	}
+
+	if _, err := c.GetSubjects(); !errors.Is(err, ErrBackendStore) {
+		t.Fatal("TestClientCircuitBreakerBeforeLimits: unexpected error", err)
+	}
+	if _, err := c.GetSubjects(); !errors.Is(err, ErrCircuitOpen) {
+		t.Error("TestClientCircuitBreakerBeforeLimits: open circuit checked after the limits", err)
+	}
+}
+
+func TestClientCircuitBreakerLimitedTrial(t *testing.T) {
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(`{"error_code":50001,"message":"Store error."}`)), StatusCode: 503}, nil
+	}
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(client),
+		WithRequestLimits(RequestLimits{RequestsPerSecond: 0.001, Burst: 1, NoWait: true}),
+		WithRequestCircuitBreaker(CircuitBreakerSettings{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond}))
+	if err != nil {
+		t.Fatal("TestClientCircuitBreakerLimitedTrial: ", err)
+	}
+
+	if _, err := c.GetSubjects(); err == nil {
+		t.Fatal("TestClientCircuitBreakerLimitedTrial: expected a failure")
+	}
+	time.Sleep(15 * time.Millisecond)
+	var limited *RateLimitError
+	if _, err := c.GetSubjects(); !errors.As(err, &limited) {
+		t.Fatal("TestClientCircuitBreakerLimitedTrial: unexpected error", err)
+	}
+	if c.CircuitState() != CircuitHalfOpen {
+		t.Error("TestClientCircuitBreakerLimitedTrial: unsent trial request changed the circuit", c.CircuitState())
+	}
+}
@@ -0,0 +1,90 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const defaultFetchConcurrency = 8
+
+// WithFetchConcurrency sets the number of schemas GetSchemasByIDs fetches in
+// parallel, 8 by default.
+func WithFetchConcurrency(n int) regOps {
+	return func(opts *registryOptions) {
+		opts.fetchConcurrency = n
+	}
+}
+
+// GetSchemasByIDs gets the schemas with the given ids. Duplicate ids are looked
+// up once, cached schemas are returned without a request and the other schemas
+// are fetched in parallel. The schemas that could not be fetched are left out of
+// the schemas and their errors returned by id. Ids not fetched before the
+// context is done fail with the context error.
+func (sr *SchemaRegistry) GetSchemasByIDs(ctx context.Context, ids []int) (map[int]*Schema, map[int]error) {
+	schemas := make(map[int]*Schema, len(ids))
+	errs := make(map[int]error)
+
+	var misses []int
+	seen := make(map[int]bool, len(ids))
+	sr.idMu.RLock()
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if id == 0 {
+			errs[id] = errors.New("schema id cannot be zero")
+			continue
+		}
+		schema, ok := sr.idSchema[schemaID{sr.context, id}]
+		sr.metrics.ObserveCache(CacheID, ok)
+		if ok {
+			schemas[id] = schema
+			continue
+		}
+		misses = append(misses, id)
+	}
+	sr.idMu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, sr.fetchConcurrency)
+	for i, id := range misses {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			mu.Lock()
+			for _, id := range misses[i:] {
+				errs[id] = err
+			}
+			mu.Unlock()
+			break
+		}
+
+		id := id
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			schema, err := sr.fetchSchemaByID(ctx, sr.context, id)
+			if err == nil && schema == nil {
+				err = ErrSchemaNotFound
+			}
+			mu.Lock()
+			if err != nil {
+				errs[id] = err
+			} else {
+				schemas[id] = schema
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return schemas, errs
+}
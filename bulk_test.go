@@ -0,0 +1,87 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetSchemasByIDs(t *testing.T) {
+	client := &preloadClient{subjects: []string{"users-value", "orders-value"}}
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithFetchConcurrency(2))
+	if err != nil {
+		t.Fatal("TestGetSchemasByIDs: ", err)
+	}
+	if _, err := reg.GetSchemaByID(1); err != nil {
+		t.Fatal("TestGetSchemasByIDs: ", err)
+	}
+	requests := len(client.requests)
+
+	schemas, errs := reg.GetSchemasByIDs(context.Background(), []int{1, 2, 11, 12, 2, 99, 11})
+	if len(schemas) != 4 {
+		t.Error("TestGetSchemasByIDs: unexpected schemas", schemas)
+	}
+	for _, id := range []int{1, 2, 11, 12} {
+		if schemas[id] == nil || schemas[id].ID != id {
+			t.Error("TestGetSchemasByIDs: schema missing", id)
+		}
+	}
+	if len(errs) != 1 || !errors.Is(errs[99], ErrSchemaNotFound) {
+		t.Error("TestGetSchemasByIDs: unexpected errors", errs)
+	}
+	if got := len(client.requests) - requests; got != 4 {
+		t.Error("TestGetSchemasByIDs: unexpected number of requests", got, client.requests[requests:])
+	}
+	if client.max > 2 {
+		t.Error("TestGetSchemasByIDs: concurrency limit exceeded", client.max)
+	}
+
+	requests = len(client.requests)
+	schemas, errs = reg.GetSchemasByIDs(context.Background(), []int{12, 1})
+	if len(schemas) != 2 || len(errs) != 0 || len(client.requests) != requests {
+		t.Error("TestGetSchemasByIDs: cached schemas fetched again", errs)
+	}
+}
+
+func TestGetSchemasByIDsCanceled(t *testing.T) {
+	client := &preloadClient{subjects: []string{"users-value"}}
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client))
+	if err != nil {
+		t.Fatal("TestGetSchemasByIDsCanceled: ", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	schemas, errs := reg.GetSchemasByIDs(ctx, []int{1, 2})
+	if len(schemas) != 0 || !errors.Is(errs[1], context.Canceled) || !errors.Is(errs[2], context.Canceled) {
+		t.Error("TestGetSchemasByIDsCanceled: unexpected result", schemas, errs)
+	}
+	if len(client.requests) != 0 {
+		t.Error("TestGetSchemasByIDsCanceled: requests sent", client.requests)
+	}
+}
+
+func TestGetSchemasByIDsTimeout(t *testing.T) {
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		time.Sleep(30 * time.Millisecond)
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(`{"schema":"\"string\""}`)), StatusCode: 200}, nil
+	}
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithFetchConcurrency(1))
+	if err != nil {
+		t.Fatal("TestGetSchemasByIDsTimeout: ", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, errs := reg.GetSchemasByIDs(ctx, []int{1, 2, 3, 4})
+	for _, id := range []int{2, 3, 4} {
+		if !errors.Is(errs[id], context.DeadlineExceeded) {
+			t.Error("TestGetSchemasByIDsTimeout: unexpected error", id, errs[id])
+		}
+	}
+}
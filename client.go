@@ -2,6 +2,7 @@ package schemaregistry
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net"
 	"net/http"
@@ -21,12 +22,59 @@ type schemaPayload struct {
 	Schema string `json:"schema"`
 }
 
+type schemaRequest struct {
+	Schema     string      `json:"schema"`
+	SchemaType SchemaType  `json:"schemaType,omitempty"`
+	References []Reference `json:"references,omitempty"`
+	ID         int         `json:"id,omitempty"`
+	Version    int         `json:"version,omitempty"`
+	Metadata   *Metadata   `json:"metadata,omitempty"`
+	RuleSet    *RuleSet    `json:"ruleSet,omitempty"`
+}
+
+type idPayload struct {
+	ID int `json:"id"`
+}
+
+type configPayload struct {
+	Compatibility      CompatibilityLevel `json:"compatibility,omitempty"`
+	CompatibilityLevel CompatibilityLevel `json:"compatibilityLevel,omitempty"`
+	DefaultMetadata    *Metadata          `json:"defaultMetadata,omitempty"`
+	OverrideMetadata   *Metadata          `json:"overrideMetadata,omitempty"`
+	DefaultRuleSet     *RuleSet           `json:"defaultRuleSet,omitempty"`
+	OverrideRuleSet    *RuleSet           `json:"overrideRuleSet,omitempty"`
+}
+
+type modePayload struct {
+	Mode Mode `json:"mode"`
+}
+
 type SchemaResponse struct {
 	ID         int         `json:"id"`
 	Subject    string      `json:"subject"`
 	Version    int         `json:"version"`
 	SchemaType *SchemaType `json:"schemaType"`
 	Schema     string      `json:"schema"`
+	References []Reference `json:"references,omitempty"`
+	Metadata   *Metadata   `json:"metadata,omitempty"`
+	RuleSet    *RuleSet    `json:"ruleSet,omitempty"`
+}
+
+// Config is the configuration of a subject or of the registry. The default
+// metadata and rule set apply to schemas registered without their own, and the
+// override metadata and rule set are merged into every registered schema.
+type Config struct {
+	CompatibilityLevel CompatibilityLevel `json:"compatibilityLevel,omitempty"`
+	DefaultMetadata    *Metadata          `json:"defaultMetadata,omitempty"`
+	OverrideMetadata   *Metadata          `json:"overrideMetadata,omitempty"`
+	DefaultRuleSet     *RuleSet           `json:"defaultRuleSet,omitempty"`
+	OverrideRuleSet    *RuleSet           `json:"overrideRuleSet,omitempty"`
+}
+
+// CompatibilityResponse is the result of a compatibility check.
+type CompatibilityResponse struct {
+	IsCompatible bool     `json:"is_compatible"`
+	Messages     []string `json:"messages,omitempty"`
 }
 
 type SubjectVersion struct {
@@ -36,12 +84,27 @@ type SubjectVersion struct {
 
 // Client is an HTTP registry client.
 type SchemaClient struct {
-	client HTTPClient
-	base   string
+	client    HTTPClient
+	base      string
+	normalize bool
+	metrics   Metrics
+	tracer    Tracer
+	logger    Logger
+	limits    *requestLimits
+	breaker   *circuitBreaker
+	context   string
 }
 
 type clientOptions struct {
-	client HTTPClient
+	client         HTTPClient
+	normalize      bool
+	metrics        Metrics
+	tracer         Tracer
+	logger         Logger
+	limits         *RequestLimits
+	endpointLimits map[EndpointClass]RequestLimits
+	breaker        *CircuitBreakerSettings
+	context        string
 }
 
 type clientOps func(*clientOptions)
@@ -52,8 +115,84 @@ func WithCustomHTTPClient(client HTTPClient) clientOps {
 	}
 }
 
+// WithNormalizedSchemas asks the registry to normalize schemas when they are
+// registered and looked up, so that equivalent schemas resolve to the same id.
+func WithNormalizedSchemas() clientOps {
+	return func(opts *clientOptions) {
+		opts.normalize = true
+	}
+}
+
+// WithRequestMetrics reports every registry request to the metrics.
+func WithRequestMetrics(metrics Metrics) clientOps {
+	return func(opts *clientOptions) {
+		if metrics != nil {
+			opts.metrics = metrics
+		}
+	}
+}
+
+// WithRequestTracer traces every registry request and propagates the trace
+// context in the request headers.
+func WithRequestTracer(tracer Tracer) clientOps {
+	return func(opts *clientOptions) {
+		if tracer != nil {
+			opts.tracer = tracer
+		}
+	}
+}
+
+// WithRequestLogger logs a summary of every registry request at debug level
+// and failed requests at warn level.
+func WithRequestLogger(logger Logger) clientOps {
+	return func(opts *clientOptions) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	}
+}
+
+// WithRequestLimits limits the rate and the number of in flight requests.
+// Requests over the limits wait for them, or fail if the limits do not wait.
+func WithRequestLimits(limits RequestLimits) clientOps {
+	return func(opts *clientOptions) {
+		opts.limits = &limits
+	}
+}
+
+// WithRequestEndpointLimits limits the requests to one endpoint class, on top
+// of the limits set by WithRequestLimits.
+func WithRequestEndpointLimits(class EndpointClass, limits RequestLimits) clientOps {
+	return func(opts *clientOptions) {
+		if opts.endpointLimits == nil {
+			opts.endpointLimits = make(map[EndpointClass]RequestLimits)
+		}
+		opts.endpointLimits[class] = limits
+	}
+}
+
+// WithRequestCircuitBreaker fails requests with ErrCircuitOpen without sending
+// them while the registry is unhealthy.
+func WithRequestCircuitBreaker(settings CircuitBreakerSettings) clientOps {
+	return func(opts *clientOptions) {
+		opts.breaker = &settings
+	}
+}
+
+// WithContext scopes the subjects, schema ids, configs and modes of the client
+// to the schema context. Subjects qualified with a context already are left
+// unchanged.
+func WithContext(name string) clientOps {
+	return func(opts *clientOptions) {
+		opts.context = contextName(name)
+	}
+}
+
 func applyDefaultClientOptions() *clientOptions {
 	ops := new(clientOptions)
+	ops.metrics = NopMetrics{}
+	ops.tracer = NopTracer{}
+	ops.logger = NopLogger{}
 
 	ops.client = &http.Client{
 		Transport: &http.Transport{
@@ -81,27 +220,99 @@ func NewClient(baseURL string, ops ...clientOps) (*SchemaClient, error) {
 	baseURL = strings.TrimSuffix(baseURL, "/")
 
 	c := &SchemaClient{
-		client: opts.client,
-		base:   baseURL,
+		client:    opts.client,
+		base:      baseURL,
+		normalize: opts.normalize,
+		metrics:   opts.metrics,
+		tracer:    opts.tracer,
+		logger:    opts.logger,
+		limits:    &requestLimits{endpoints: make(map[EndpointClass]*limiter)},
+		context:   opts.context,
+	}
+	if opts.limits != nil {
+		c.limits.all = newLimiter(*opts.limits)
+	}
+	for class, limits := range opts.endpointLimits {
+		c.limits.endpoints[class] = newLimiter(limits)
+	}
+	if opts.breaker != nil {
+		settings := *opts.breaker
+		onStateChange := settings.OnStateChange
+		settings.OnStateChange = func(from, to CircuitState) {
+			c.logger.Warn("schema registry circuit breaker state changed", "from", from.String(), "to", to.String())
+			if onStateChange != nil {
+				onStateChange(from, to)
+			}
+		}
+		c.breaker = newCircuitBreaker(settings, time.Now)
 	}
 
 	return c, nil
 }
 
+// CircuitState returns the state of the circuit breaker, which is always
+// closed without one.
+func (c *SchemaClient) CircuitState() CircuitState {
+	if c.breaker == nil {
+		return CircuitClosed
+	}
+
+	return c.breaker.current()
+}
+
+// InContext returns a client scoped to the schema context, sharing the
+// connections and limits of the client.
+func (c *SchemaClient) InContext(name string) *SchemaClient {
+	scoped := *c
+	scoped.context = contextName(name)
+
+	return &scoped
+}
+
+// Context returns the schema context of the client, empty for the default
+// context.
+func (c *SchemaClient) Context() string {
+	return c.context
+}
+
+// ListContexts gets the schema contexts of the registry.
+func (c *SchemaClient) ListContexts() ([]string, error) {
+	var contexts []string
+	err := c.Request(http.MethodGet, "/contexts", nil, &contexts)
+	if err != nil {
+		return nil, err
+	}
+
+	return contexts, nil
+}
+
 // GetSchema returns the schema with the given id.
 func (c *SchemaClient) GetSchemaByID(id int) (string, error) {
 	var payload schemaPayload
-	if err := c.Request(http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &payload); err != nil {
+	if err := c.Request(http.MethodGet, "/schemas/ids/"+strconv.Itoa(id)+c.idQuery(), nil, &payload); err != nil {
 		return "", err
 	}
 
 	return payload.Schema, nil
 }
 
+// GetSchemaResponseByID gets the schema with the given id together with its
+// type and references. The registry does not return the subject and version of
+// the schema, which are left empty.
+func (c *SchemaClient) GetSchemaResponseByID(ctx context.Context, id int) (SchemaResponse, error) {
+	var payload SchemaResponse
+	if err := c.RequestContext(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id)+c.idQuery(), nil, &payload); err != nil {
+		return SchemaResponse{}, err
+	}
+	payload.ID = id
+
+	return payload, nil
+}
+
 // GetSubjects gets the registry subjects.
 func (c *SchemaClient) GetSubjects() ([]string, error) {
 	var subjects []string
-	err := c.Request(http.MethodGet, "/subjects", nil, &subjects)
+	err := c.Request(http.MethodGet, "/subjects"+c.subjectsQuery(""), nil, &subjects)
 	if err != nil {
 		return nil, err
 	}
@@ -109,10 +320,21 @@ func (c *SchemaClient) GetSubjects() ([]string, error) {
 	return subjects, err
 }
 
+// GetSubjectsWithDeleted gets the registry subjects, including soft deleted subjects.
+func (c *SchemaClient) GetSubjectsWithDeleted() ([]string, error) {
+	var subjects []string
+	err := c.Request(http.MethodGet, "/subjects"+c.subjectsQuery("deleted=true"), nil, &subjects)
+	if err != nil {
+		return nil, err
+	}
+
+	return subjects, nil
+}
+
 // GetVersions gets the schema versions for a subject.
 func (c *SchemaClient) GetVersions(subject string) ([]int, error) {
 	var versions []int
-	err := c.Request(http.MethodGet, "/subjects/"+subject+"/versions", nil, &versions)
+	err := c.Request(http.MethodGet, "/subjects/"+c.subject(subject)+"/versions", nil, &versions)
 	if err != nil {
 		return nil, err
 	}
@@ -120,10 +342,38 @@ func (c *SchemaClient) GetVersions(subject string) ([]int, error) {
 	return versions, err
 }
 
+// GetVersionsWithDeleted gets the schema versions for a subject, including
+// soft deleted versions.
+func (c *SchemaClient) GetVersionsWithDeleted(subject string) ([]int, error) {
+	var versions []int
+	err := c.Request(http.MethodGet, "/subjects/"+c.subject(subject)+"/versions?deleted=true", nil, &versions)
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
 // GetSchemaByVersion gets the schema by version.
 func (c *SchemaClient) GetSchemaByVersion(subject string, version int) (SchemaResponse, error) {
+	return c.GetSchemaByVersionContext(context.Background(), subject, version)
+}
+
+// GetSchemaByVersionContext is GetSchemaByVersion with a context.
+func (c *SchemaClient) GetSchemaByVersionContext(ctx context.Context, subject string, version int) (SchemaResponse, error) {
 	var payload SchemaResponse
-	err := c.Request(http.MethodGet, "/subjects/"+subject+"/versions/"+strconv.Itoa(version), nil, &payload)
+	err := c.RequestContext(ctx, http.MethodGet, "/subjects/"+c.subject(subject)+"/versions/"+strconv.Itoa(version), nil, &payload)
+	if err != nil {
+		return SchemaResponse{}, err
+	}
+
+	return payload, nil
+}
+
+// GetSchemaByVersionWithDeleted gets the schema by version, which may be soft deleted.
+func (c *SchemaClient) GetSchemaByVersionWithDeleted(subject string, version int) (SchemaResponse, error) {
+	var payload SchemaResponse
+	err := c.Request(http.MethodGet, "/subjects/"+c.subject(subject)+"/versions/"+strconv.Itoa(version)+"?deleted=true", nil, &payload)
 	if err != nil {
 		return SchemaResponse{}, err
 	}
@@ -134,7 +384,7 @@ func (c *SchemaClient) GetSchemaByVersion(subject string, version int) (SchemaRe
 // GetLatestSchema gets the latest schema for a subject.
 func (c *SchemaClient) GetLatestSchema(subject string) (SchemaResponse, error) {
 	var payload SchemaResponse
-	err := c.Request(http.MethodGet, "/subjects/"+subject+"/versions/latest", nil, &payload)
+	err := c.Request(http.MethodGet, "/subjects/"+c.subject(subject)+"/versions/latest", nil, &payload)
 	if err != nil {
 		return SchemaResponse{}, err
 	}
@@ -143,54 +393,451 @@ func (c *SchemaClient) GetLatestSchema(subject string) (SchemaResponse, error) {
 }
 
 func (c *SchemaClient) GetSubjectVersionByID(schemaId int) ([]SubjectVersion, error) {
+	return c.GetSubjectVersionByIDContext(context.Background(), schemaId)
+}
+
+// GetSubjectVersionByIDContext is GetSubjectVersionByID with a context.
+func (c *SchemaClient) GetSubjectVersionByIDContext(ctx context.Context, schemaId int) ([]SubjectVersion, error) {
 	var payload []SubjectVersion
-	err := c.Request(http.MethodGet, "/schemas/ids/"+strconv.Itoa(schemaId)+"/versions", nil, &payload)
+	err := c.RequestContext(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(schemaId)+"/versions"+c.idQuery(), nil, &payload)
 	if err != nil {
 		return []SubjectVersion{}, err
 	}
 	return payload, nil
 }
 
+// GetSchemaTypes gets the schema types supported by the registry.
+func (c *SchemaClient) GetSchemaTypes() ([]SchemaType, error) {
+	var types []SchemaType
+	err := c.Request(http.MethodGet, "/schemas/types", nil, &types)
+	if err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
+
+// GetSchemaReferencedBy gets the ids of the schemas referencing the given subject version.
+func (c *SchemaClient) GetSchemaReferencedBy(subject string, version int) ([]int, error) {
+	var ids []int
+	err := c.Request(http.MethodGet, "/subjects/"+c.subject(subject)+"/versions/"+versionPath(version)+"/referencedby", nil, &ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// CreateSchema registers a schema under the subject and returns its id. If the
+// schema is already registered under the subject its existing id is returned.
+func (c *SchemaClient) CreateSchema(subject, schema string, schemaType SchemaType, references ...Reference) (int, error) {
+	return c.CreateSchemaWithMetadata(subject, schema, schemaType, nil, nil, references...)
+}
+
+// CreateSchemaWithMetadata registers a schema with its metadata and rule set
+// under the subject and returns its id. Either of them may be nil.
+func (c *SchemaClient) CreateSchemaWithMetadata(subject, schema string, schemaType SchemaType, metadata *Metadata, ruleSet *RuleSet, references ...Reference) (int, error) {
+	var payload idPayload
+	in := newSchemaRequest(schema, schemaType, references)
+	in.Metadata = metadata
+	in.RuleSet = ruleSet
+	err := c.Request(http.MethodPost, "/subjects/"+c.subject(subject)+"/versions"+c.normalizeQuery(), in, &payload)
+	if err != nil {
+		return 0, err
+	}
+
+	return payload.ID, nil
+}
+
+// ImportSchema registers a schema under the subject with the given id and
+// version. The subject must be in IMPORT mode.
+func (c *SchemaClient) ImportSchema(subject string, id, version int, schema string, schemaType SchemaType, references ...Reference) (int, error) {
+	return c.ImportSchemaWithMetadata(subject, id, version, schema, schemaType, nil, nil, references...)
+}
+
+// ImportSchemaWithMetadata imports the schema like ImportSchema, together with
+// its metadata and rule set. Either of them may be nil.
+func (c *SchemaClient) ImportSchemaWithMetadata(subject string, id, version int, schema string, schemaType SchemaType, metadata *Metadata, ruleSet *RuleSet, references ...Reference) (int, error) {
+	var payload idPayload
+	in := newSchemaRequest(schema, schemaType, references)
+	in.Metadata = metadata
+	in.RuleSet = ruleSet
+	in.ID = id
+	in.Version = version
+	err := c.Request(http.MethodPost, "/subjects/"+c.subject(subject)+"/versions", in, &payload)
+	if err != nil {
+		return 0, err
+	}
+
+	return payload.ID, nil
+}
+
+// LookupSchema checks whether the schema is registered under the subject and
+// returns the matching subject version.
+func (c *SchemaClient) LookupSchema(subject, schema string, schemaType SchemaType, references ...Reference) (SchemaResponse, error) {
+	return c.LookupSchemaWithMetadata(subject, schema, schemaType, nil, nil, references...)
+}
+
+// LookupSchemaWithMetadata looks the schema up like LookupSchema, matching
+// its metadata and rule set too when they are not nil.
+func (c *SchemaClient) LookupSchemaWithMetadata(subject, schema string, schemaType SchemaType, metadata *Metadata, ruleSet *RuleSet, references ...Reference) (SchemaResponse, error) {
+	var payload SchemaResponse
+	in := newSchemaRequest(schema, schemaType, references)
+	in.Metadata = metadata
+	in.RuleSet = ruleSet
+	err := c.Request(http.MethodPost, "/subjects/"+c.subject(subject)+c.normalizeQuery(), in, &payload)
+	if err != nil {
+		return SchemaResponse{}, err
+	}
+
+	return payload, nil
+}
+
+// CheckCompatibility tests the schema against the given subject version. Use
+// LatestVersion to test against the latest version and AllVersions to test
+// against every version the subject compatibility level applies to.
+func (c *SchemaClient) CheckCompatibility(subject, schema string, schemaType SchemaType, version int, references ...Reference) (CompatibilityResponse, error) {
+	uri := "/compatibility/subjects/" + c.subject(subject) + "/versions"
+	if Version(version) != AllVersions {
+		uri += "/" + versionPath(version)
+	}
+
+	var payload CompatibilityResponse
+	in := newSchemaRequest(schema, schemaType, references)
+	err := c.Request(http.MethodPost, uri+"?verbose=true", in, &payload)
+	if err != nil {
+		return CompatibilityResponse{}, err
+	}
+
+	return payload, nil
+}
+
+// DeleteSubject deletes the subject and returns the deleted versions. A subject
+// has to be soft deleted before it can be permanently deleted.
+func (c *SchemaClient) DeleteSubject(subject string, permanent bool) ([]int, error) {
+	var versions []int
+	err := c.Request(http.MethodDelete, "/subjects/"+c.subject(subject)+permanentQuery(permanent), nil, &versions)
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// DeleteSchemaVersion deletes a version of the subject and returns the deleted version.
+func (c *SchemaClient) DeleteSchemaVersion(subject string, version int, permanent bool) (int, error) {
+	var deleted int
+	err := c.Request(http.MethodDelete, "/subjects/"+c.subject(subject)+"/versions/"+versionPath(version)+permanentQuery(permanent), nil, &deleted)
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// GetConfig gets the compatibility level of the subject, or the global level
+// if subject is empty.
+func (c *SchemaClient) GetConfig(subject string) (CompatibilityLevel, error) {
+	var payload configPayload
+	err := c.Request(http.MethodGet, c.configPath(subject), nil, &payload)
+	if err != nil {
+		return "", err
+	}
+
+	return payload.CompatibilityLevel, nil
+}
+
+// SetConfig sets the compatibility level of the subject, or the global level
+// if subject is empty.
+func (c *SchemaClient) SetConfig(subject string, level CompatibilityLevel) (CompatibilityLevel, error) {
+	var payload configPayload
+	err := c.Request(http.MethodPut, c.configPath(subject), configPayload{Compatibility: level}, &payload)
+	if err != nil {
+		return "", err
+	}
+
+	return payload.Compatibility, nil
+}
+
+// DeleteConfig deletes the subject compatibility level, reverting it to the global level.
+func (c *SchemaClient) DeleteConfig(subject string) (CompatibilityLevel, error) {
+	var payload configPayload
+	err := c.Request(http.MethodDelete, c.configPath(subject), nil, &payload)
+	if err != nil {
+		return "", err
+	}
+
+	return payload.CompatibilityLevel, nil
+}
+
+// GetFullConfig gets the configuration of the subject, or the global
+// configuration if subject is empty.
+func (c *SchemaClient) GetFullConfig(subject string) (Config, error) {
+	var payload configPayload
+	err := c.Request(http.MethodGet, c.configPath(subject), nil, &payload)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return payload.config(), nil
+}
+
+// SetFullConfig sets the configuration of the subject, or the global
+// configuration if subject is empty.
+func (c *SchemaClient) SetFullConfig(subject string, config Config) (Config, error) {
+	in := configPayload{
+		Compatibility:    config.CompatibilityLevel,
+		DefaultMetadata:  config.DefaultMetadata,
+		OverrideMetadata: config.OverrideMetadata,
+		DefaultRuleSet:   config.DefaultRuleSet,
+		OverrideRuleSet:  config.OverrideRuleSet,
+	}
+	var payload configPayload
+	err := c.Request(http.MethodPut, c.configPath(subject), in, &payload)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return payload.config(), nil
+}
+
+// config returns the configuration of the payload, whose compatibility level
+// is named differently in requests and responses.
+func (p configPayload) config() Config {
+	level := p.CompatibilityLevel
+	if level == "" {
+		level = p.Compatibility
+	}
+
+	return Config{
+		CompatibilityLevel: level,
+		DefaultMetadata:    p.DefaultMetadata,
+		OverrideMetadata:   p.OverrideMetadata,
+		DefaultRuleSet:     p.DefaultRuleSet,
+		OverrideRuleSet:    p.OverrideRuleSet,
+	}
+}
+
+// GetMode gets the mode of the subject, or the global mode if subject is empty.
+func (c *SchemaClient) GetMode(subject string) (Mode, error) {
+	var payload modePayload
+	err := c.Request(http.MethodGet, c.modePath(subject), nil, &payload)
+	if err != nil {
+		return "", err
+	}
+
+	return payload.Mode, nil
+}
+
+// SetMode sets the mode of the subject, or the global mode if subject is empty.
+func (c *SchemaClient) SetMode(subject string, mode Mode) (Mode, error) {
+	var payload modePayload
+	err := c.Request(http.MethodPut, c.modePath(subject), modePayload{Mode: mode}, &payload)
+	if err != nil {
+		return "", err
+	}
+
+	return payload.Mode, nil
+}
+
+// SetModeForce sets the mode like SetMode, allowing IMPORT mode to be set on
+// a subject or registry that already has schemas.
+func (c *SchemaClient) SetModeForce(subject string, mode Mode) (Mode, error) {
+	var payload modePayload
+	err := c.Request(http.MethodPut, c.modePath(subject)+"?force=true", modePayload{Mode: mode}, &payload)
+	if err != nil {
+		return "", err
+	}
+
+	return payload.Mode, nil
+}
+
+// DeleteMode deletes the subject mode, reverting it to the global mode.
+func (c *SchemaClient) DeleteMode(subject string) (Mode, error) {
+	var payload modePayload
+	err := c.Request(http.MethodDelete, c.modePath(subject), nil, &payload)
+	if err != nil {
+		return "", err
+	}
+
+	return payload.Mode, nil
+}
+
+func newSchemaRequest(schema string, schemaType SchemaType, references []Reference) schemaRequest {
+	// The registry defaults to AVRO and older versions reject the schemaType field.
+	if schemaType == AVRO {
+		schemaType = ""
+	}
+
+	return schemaRequest{
+		Schema:     schema,
+		SchemaType: schemaType,
+		References: references,
+	}
+}
+
+func versionPath(version int) string {
+	if Version(version) == LatestVersion {
+		return "latest"
+	}
+
+	return strconv.Itoa(version)
+}
+
+// subjectsQuery returns the query listing the subjects of the client context.
+func (c *SchemaClient) subjectsQuery(query string) string {
+	if c.context != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += "subjectPrefix=" + url.QueryEscape(c.subject(""))
+	}
+	if query == "" {
+		return ""
+	}
+
+	return "?" + query
+}
+
+func permanentQuery(permanent bool) string {
+	if permanent {
+		return "?permanent=true"
+	}
+
+	return ""
+}
+
+func (c *SchemaClient) normalizeQuery() string {
+	if c.normalize {
+		return "?normalize=true"
+	}
+
+	return ""
+}
+
+// configPath returns the config path of the subject, or of the client context
+// if subject is empty.
+func (c *SchemaClient) configPath(subject string) string {
+	if subject = c.subject(subject); subject == "" {
+		return "/config"
+	}
+
+	return "/config/" + subject
+}
+
+// modePath returns the mode path of the subject, or of the client context if
+// subject is empty.
+func (c *SchemaClient) modePath(subject string) string {
+	if subject = c.subject(subject); subject == "" {
+		return "/mode"
+	}
+
+	return "/mode/" + subject
+}
+
+// subject qualifies the subject with the client context.
+func (c *SchemaClient) subject(subject string) string {
+	return QualifiedSubject(c.context, subject)
+}
+
+// idQuery returns the query looking schema ids up in the client context.
+func (c *SchemaClient) idQuery() string {
+	if c.context == "" {
+		return ""
+	}
+
+	return "?subject=" + url.QueryEscape(c.subject(""))
+}
+
+// Request sends a request to the registry, encoding in as the JSON body if it
+// is not nil and decoding the JSON response into out.
 func (c *SchemaClient) Request(method, uri string, in, out interface{}) error {
+	return c.RequestContext(context.Background(), method, uri, in, out)
+}
+
+// RequestContext is Request with a context, which carries the parent span of
+// the request span and is propagated to the registry.
+func (c *SchemaClient) RequestContext(ctx context.Context, method, uri string, in, out interface{}) (err error) {
 	var body io.Reader
 	if in != nil {
-		b, _ := jsoniter.Marshal(in)
+		b, err := jsoniter.Marshal(in)
+		if err != nil {
+			return err
+		}
 		body = bytes.NewReader(b)
 	}
 
+	path, params := endpoint(uri)
+	ctx, span := c.tracer.Start(ctx, method+" "+path)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+	span.SetAttribute(AttrMethod, method)
+	span.SetAttribute(AttrEndpoint, path)
+	if subject, ok := params["subject"]; ok {
+		span.SetAttribute(AttrSubject, subject)
+	}
+	if version, ok := params["version"]; ok {
+		span.SetAttribute(AttrVersion, version)
+	}
+	if id, err := strconv.Atoi(params["id"]); err == nil {
+		span.SetAttribute(AttrSchemaID, id)
+	}
+
+	// The circuit is checked first so that requests it fails do not take up
+	// the limits. Requests the limits then refuse were never sent and leave
+	// the circuit as it is.
+	var trial, sent bool
+	if c.breaker != nil {
+		if trial, err = c.breaker.allow(); err != nil {
+			return err
+		}
+		defer func() {
+			failed, ignored := requestFailed(ctx, err)
+			c.breaker.done(trial, failed, ignored || !sent)
+		}()
+	}
+
+	release, err := c.limits.acquire(ctx, path)
+	if err != nil {
+		c.logger.Warn("schema registry request not sent", "method", method, "endpoint", path, "error", err)
+		return err
+	}
+	defer release()
+
 	req, _ := http.NewRequest(method, c.base+uri, body) // This error is not possible as we already parsed the url
+	req = req.WithContext(ctx)
 	req.Header.Set("Content-Type", contentType)
+	c.tracer.Inject(ctx, req.Header)
 
+	sent = true
+	start := time.Now()
 	resp, err := c.client.Do(req)
+	latency := time.Since(start)
 	if err != nil {
+		c.metrics.ObserveRequest(method, path, 0, latency)
+		c.logger.Warn("schema registry request failed", "method", method, "url", req.URL.Redacted(), "duration", latency, "error", err)
 		return err
 	}
 	defer func() {
 		resp.Body.Close()
 	}()
+	c.metrics.ObserveRequest(method, path, resp.StatusCode, latency)
+	span.SetAttribute(AttrStatusCode, resp.StatusCode)
+	c.logger.Debug("schema registry request", "method", method, "url", req.URL.Redacted(), "status", resp.StatusCode,
+		"duration", latency, "headers", redactHeaders(req.Header))
 
 	if resp.StatusCode >= 400 {
 		err := Error{StatusCode: resp.StatusCode}
 		_ = jsoniter.NewDecoder(resp.Body).Decode(&err)
+		if resp.StatusCode >= 500 {
+			c.logger.Warn("schema registry server error", "method", method, "url", req.URL.Redacted(), "status", resp.StatusCode, "error", err)
+		}
 		return err
 	}
 
 	return jsoniter.NewDecoder(resp.Body).Decode(out)
 }
-
-// Error is returned by the registry when there is an error.
-type Error struct {
-	StatusCode int `json:"-"`
-
-	Code    int    `json:"error_code"`
-	Message string `json:"message"`
-}
-
-// Error returns the error message.
-func (e Error) Error() string {
-	if e.Message != "" {
-		return e.Message
-	}
-
-	return "registry error: " + strconv.Itoa(e.StatusCode)
-}
@@ -3,6 +3,7 @@ package schemaregistry
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -433,3 +434,213 @@ func TestGetSubjectVersionByID(t *testing.T) {
 		t.Error("TestGetSubjectVersionByID: making get schema request", err.Error())
 	}
 }
+
+func TestErrorIs(t *testing.T) {
+	client := &HTTPClientMock{}
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(client))
+	if err != nil {
+		t.Error("TestErrorIs: failed creating client")
+	}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error_code":40401,"message":"Subject 'com.test' not found."}`)),
+			StatusCode: 404,
+		}, nil
+	}
+
+	_, err = c.GetVersions("com.test")
+	if !errors.Is(err, ErrSubjectNotFound) {
+		t.Error("TestErrorIs: expected ErrSubjectNotFound, got", err)
+	}
+	if errors.Is(err, ErrVersionNotFound) {
+		t.Error("TestErrorIs: unexpected match with ErrVersionNotFound")
+	}
+
+	var regErr Error
+	if !errors.As(err, &regErr) {
+		t.Fatal("TestErrorIs: expected registry Error")
+	}
+	if regErr.StatusCode != 404 || regErr.Message != "Subject 'com.test' not found." {
+		t.Error("TestErrorIs: unexpected error fields", regErr)
+	}
+}
+
+func TestRequestMarshalError(t *testing.T) {
+	client := &HTTPClientMock{}
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(client))
+	if err != nil {
+		t.Error("TestRequestMarshalError: failed creating client")
+	}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		t.Error("TestRequestMarshalError: request should not be sent")
+		return nil, errors.New("unexpected request")
+	}
+
+	if err := c.Request(http.MethodPost, "/subjects", make(chan int), nil); err == nil {
+		t.Error("TestRequestMarshalError: marshal error not returned")
+	}
+}
+
+func TestCreateSchemaWithMetadata(t *testing.T) {
+	client := &HTTPClientMock{}
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(client))
+	if err != nil {
+		t.Error("TestCreateSchemaWithMetadata: failed creating client")
+	}
+	var sent schemaRequest
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+			t.Error("TestCreateSchemaWithMetadata: failed decoding request", err)
+		}
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(`{"id":3}`)), StatusCode: 200}, nil
+	}
+
+	metadata := &Metadata{Properties: map[string]string{"owner": "team-a"}, Sensitive: []string{"email"}}
+	ruleSet := &RuleSet{DomainRules: []Rule{{Name: "encrypt", Kind: RuleKindTransform, Mode: RuleModeWriteRead, Type: "ENCRYPT", Tags: []string{"PII"}}}}
+	id, err := c.CreateSchemaWithMetadata("com.test", `"string"`, AVRO, metadata, ruleSet)
+	if err != nil || id != 3 {
+		t.Fatal("TestCreateSchemaWithMetadata: ", id, err)
+	}
+	if sent.Metadata == nil || sent.Metadata.Properties["owner"] != "team-a" || sent.RuleSet == nil || sent.RuleSet.DomainRules[0].Mode != RuleModeWriteRead {
+		t.Error("TestCreateSchemaWithMetadata: metadata and rule set not sent", sent.Metadata, sent.RuleSet)
+	}
+
+	sent = schemaRequest{}
+	if _, err := c.CreateSchema("com.test", `"string"`, AVRO); err != nil {
+		t.Fatal("TestCreateSchemaWithMetadata: ", err)
+	}
+	if sent.Metadata != nil || sent.RuleSet != nil {
+		t.Error("TestCreateSchemaWithMetadata: unexpected metadata and rule set", sent.Metadata, sent.RuleSet)
+	}
+}
+
+func TestGetFullConfig(t *testing.T) {
+	client := &HTTPClientMock{}
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(client))
+	if err != nil {
+		t.Error("TestGetFullConfig: failed creating client")
+	}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		body := `{"compatibilityLevel":"FULL","defaultMetadata":{"properties":{"owner":"team-a"}},"overrideRuleSet":{"domainRules":[{"name":"checkName","kind":"CONDITION","mode":"WRITE"}]}}`
+		if r.Method == http.MethodPut {
+			body = `{"compatibility":"FULL"}`
+		}
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(body)), StatusCode: 200}, nil
+	}
+
+	config, err := c.GetFullConfig("com.test")
+	if err != nil {
+		t.Fatal("TestGetFullConfig: ", err)
+	}
+	if config.CompatibilityLevel != CompatibilityFull || config.DefaultMetadata.Properties["owner"] != "team-a" ||
+		config.OverrideRuleSet.DomainRules[0].Kind != RuleKindCondition || config.OverrideMetadata != nil {
+		t.Error("TestGetFullConfig: unexpected config", config)
+	}
+
+	config, err = c.SetFullConfig("com.test", Config{CompatibilityLevel: CompatibilityFull})
+	if err != nil || config.CompatibilityLevel != CompatibilityFull {
+		t.Error("TestGetFullConfig: unexpected updated config", config, err)
+	}
+}
+
+// requestRecorder answers every request with the body and records the method
+// and url of the last one.
+func requestRecorder(t *testing.T, body string) (*SchemaClient, *string) {
+	t.Helper()
+	var last string
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		last = r.Method + " " + r.URL.String()
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(body)), StatusCode: 200}, nil
+	}
+	c, err := NewClient("http://localhost:8080", WithCustomHTTPClient(client))
+	if err != nil {
+		t.Fatal("requestRecorder: ", err)
+	}
+
+	return c, &last
+}
+
+func TestSchemaRegistrationRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		call func(c *SchemaClient) (interface{}, error)
+		want string
+		out  string
+	}{
+		{"GetSchemaTypes", `["AVRO","JSON"]`, func(c *SchemaClient) (interface{}, error) { return c.GetSchemaTypes() },
+			"GET http://localhost:8080/schemas/types", "[AVRO JSON]"},
+		{"GetSchemaReferencedBy", `[4,5]`, func(c *SchemaClient) (interface{}, error) { return c.GetSchemaReferencedBy("users", -1) },
+			"GET http://localhost:8080/subjects/users/versions/latest/referencedby", "[4 5]"},
+		{"CreateSchema", `{"id":3}`, func(c *SchemaClient) (interface{}, error) { return c.CreateSchema("users", `"int"`, AVRO) },
+			"POST http://localhost:8080/subjects/users/versions", "3"},
+		{"LookupSchema", `{"id":3,"version":2}`, func(c *SchemaClient) (interface{}, error) {
+			resp, err := c.LookupSchema("users", `"int"`, AVRO)
+			return resp.Version, err
+		}, "POST http://localhost:8080/subjects/users", "2"},
+		{"CheckCompatibility", `{"is_compatible":true}`, func(c *SchemaClient) (interface{}, error) {
+			resp, err := c.CheckCompatibility("users", `"int"`, AVRO, 2)
+			return resp.IsCompatible, err
+		}, "POST http://localhost:8080/compatibility/subjects/users/versions/2?verbose=true", "true"},
+		{"CheckCompatibilityAll", `{"is_compatible":false}`, func(c *SchemaClient) (interface{}, error) {
+			resp, err := c.CheckCompatibility("users", `"int"`, AVRO, int(AllVersions))
+			return resp.IsCompatible, err
+		}, "POST http://localhost:8080/compatibility/subjects/users/versions?verbose=true", "false"},
+		{"DeleteSubject", `[1,2]`, func(c *SchemaClient) (interface{}, error) { return c.DeleteSubject("users", true) },
+			"DELETE http://localhost:8080/subjects/users?permanent=true", "[1 2]"},
+		{"DeleteSchemaVersion", `2`, func(c *SchemaClient) (interface{}, error) { return c.DeleteSchemaVersion("users", 2, false) },
+			"DELETE http://localhost:8080/subjects/users/versions/2", "2"},
+	}
+	for _, tt := range tests {
+		c, last := requestRecorder(t, tt.body)
+		out, err := tt.call(c)
+		if err != nil {
+			t.Error("TestSchemaRegistrationRequests: "+tt.name, err)
+			continue
+		}
+		if *last != tt.want {
+			t.Errorf("TestSchemaRegistrationRequests: %s sent %q want %q", tt.name, *last, tt.want)
+		}
+		if got := fmt.Sprint(out); got != tt.out {
+			t.Errorf("TestSchemaRegistrationRequests: %s returned %s want %s", tt.name, got, tt.out)
+		}
+	}
+}
+
+func TestConfigAndModeRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		call func(c *SchemaClient) (interface{}, error)
+		want string
+		out  string
+	}{
+		{"GetConfig", `{"compatibilityLevel":"FULL"}`, func(c *SchemaClient) (interface{}, error) { return c.GetConfig("") },
+			"GET http://localhost:8080/config", "FULL"},
+		{"SetConfig", `{"compatibility":"NONE"}`, func(c *SchemaClient) (interface{}, error) { return c.SetConfig("users", CompatibilityNone) },
+			"PUT http://localhost:8080/config/users", "NONE"},
+		{"DeleteConfig", `{"compatibilityLevel":"NONE"}`, func(c *SchemaClient) (interface{}, error) { return c.DeleteConfig("users") },
+			"DELETE http://localhost:8080/config/users", "NONE"},
+		{"GetMode", `{"mode":"READWRITE"}`, func(c *SchemaClient) (interface{}, error) { return c.GetMode("") },
+			"GET http://localhost:8080/mode", "READWRITE"},
+		{"SetMode", `{"mode":"IMPORT"}`, func(c *SchemaClient) (interface{}, error) { return c.SetMode("users", ModeImport) },
+			"PUT http://localhost:8080/mode/users", "IMPORT"},
+		{"DeleteMode", `{"mode":"IMPORT"}`, func(c *SchemaClient) (interface{}, error) { return c.DeleteMode("users") },
+			"DELETE http://localhost:8080/mode/users", "IMPORT"},
+	}
+	for _, tt := range tests {
+		c, last := requestRecorder(t, tt.body)
+		out, err := tt.call(c)
+		if err != nil {
+			t.Error("TestConfigAndModeRequests: "+tt.name, err)
+			continue
+		}
+		if *last != tt.want {
+			t.Errorf("TestConfigAndModeRequests: %s sent %q want %q", tt.name, *last, tt.want)
+		}
+		if got := fmt.Sprint(out); got != tt.out {
+			t.Errorf("TestConfigAndModeRequests: %s returned %s want %s", tt.name, got, tt.out)
+		}
+	}
+}
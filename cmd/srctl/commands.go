@@ -0,0 +1,450 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/codegen"
+	"github.com/anjulapaulus/schema_registry/diff"
+)
+
+// errIncompatible is returned by check after printing the result, so that the
+// exit status reports an incompatible schema.
+var errIncompatible = errors.New("schema is incompatible")
+
+type cli struct {
+	client *schemaregistry.SchemaClient
+	stdin  io.Reader
+	stderr io.Writer
+}
+
+type command struct {
+	name  string
+	usage string
+	run   func(c *cli, args []string) (result, error)
+}
+
+var commands = []command{
+	{"contexts", "contexts", (*cli).contexts},
+	{"subjects", "subjects", (*cli).subjects},
+	{"versions", "versions <subject>", (*cli).versions},
+	{"get", "get -id <id> | get <subject> [version|latest]", (*cli).get},
+	{"register", "register [-type AVRO] [-ref name=subject:version]... -file <path> <subject>", (*cli).register},
+	{"check", "check [-type AVRO] [-version latest|all|<n>] [-ref name=subject:version]... -file <path> <subject>", (*cli).check},
+	{"config", "config get [subject] | config set [subject] <level> | config delete <subject>", (*cli).config},
+	{"mode", "mode get [subject] | mode set [subject] <mode> | mode delete <subject>", (*cli).mode},
+	{"delete", "delete [-permanent] <subject> [version]", (*cli).delete},
+	{"diff", "diff <subject> <from version> <to version|latest>", (*cli).diff},
+	{"gen", "gen [-package name] [-type name] -id <id> | gen [-package name] [-type name] <subject> [version|latest]", (*cli).gen},
+}
+
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	return fs
+}
+
+func (c *cli) contexts(args []string) (result, error) {
+	if len(args) != 0 {
+		return result{}, errUsage
+	}
+	contexts, err := c.client.ListContexts()
+	if err != nil {
+		return result{}, err
+	}
+
+	return listResult(contexts, "CONTEXT", contexts), nil
+}
+
+func (c *cli) subjects(args []string) (result, error) {
+	if len(args) != 0 {
+		return result{}, errUsage
+	}
+	subjects, err := c.client.GetSubjects()
+	if err != nil {
+		return result{}, err
+	}
+
+	return listResult(subjects, "SUBJECT", subjects), nil
+}
+
+func (c *cli) versions(args []string) (result, error) {
+	if len(args) != 1 {
+		return result{}, errUsage
+	}
+	versions, err := c.client.GetVersions(args[0])
+	if err != nil {
+		return result{}, err
+	}
+
+	items := make([]string, 0, len(versions))
+	for _, v := range versions {
+		items = append(items, strconv.Itoa(v))
+	}
+
+	return listResult(versions, "VERSION", items), nil
+}
+
+func (c *cli) get(args []string) (result, error) {
+	fs := c.flagSet("get")
+	id := fs.Int("id", 0, "schema id")
+	if err := fs.Parse(args); err != nil {
+		return result{}, errUsage
+	}
+	args = fs.Args()
+
+	if *id != 0 {
+		if len(args) != 0 {
+			return result{}, errUsage
+		}
+		schema, err := c.client.GetSchemaByID(*id)
+		if err != nil {
+			return result{}, err
+		}
+		return result{
+			value:  map[string]interface{}{"id": *id, "schema": schema},
+			header: []string{"ID", "SCHEMA"},
+			rows:   [][]string{{strconv.Itoa(*id), schema}},
+		}, nil
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return result{}, errUsage
+	}
+	version := int(schemaregistry.LatestVersion)
+	if len(args) == 2 {
+		var err error
+		if version, err = parseVersion(args[1]); err != nil {
+			return result{}, err
+		}
+	}
+
+	var resp schemaregistry.SchemaResponse
+	var err error
+	if schemaregistry.Version(version) == schemaregistry.LatestVersion {
+		resp, err = c.client.GetLatestSchema(args[0])
+	} else {
+		resp, err = c.client.GetSchemaByVersion(args[0], version)
+	}
+	if err != nil {
+		return result{}, err
+	}
+
+	return schemaResult(resp), nil
+}
+
+func schemaResult(resp schemaregistry.SchemaResponse) result {
+	schemaType := schemaregistry.AVRO
+	if resp.SchemaType != nil && *resp.SchemaType != "" {
+		schemaType = *resp.SchemaType
+	}
+
+	return result{
+		value:  resp,
+		header: []string{"SUBJECT", "VERSION", "ID", "TYPE", "SCHEMA"},
+		rows:   [][]string{{resp.Subject, strconv.Itoa(resp.Version), strconv.Itoa(resp.ID), string(schemaType), resp.Schema}},
+	}
+}
+
+// schemaFlags are the flags describing a schema read from a file.
+type schemaFlags struct {
+	file       *string
+	schemaType *string
+	refs       *referencesFlag
+}
+
+func (c *cli) schemaFlags(fs *flag.FlagSet) schemaFlags {
+	refs := &referencesFlag{}
+	fs.Var(refs, "ref", "schema reference as name=subject:version, may be repeated")
+
+	return schemaFlags{
+		file:       fs.String("file", "", "schema file, - reads stdin"),
+		schemaType: fs.String("type", string(schemaregistry.AVRO), "schema type: AVRO, PROTOBUF or JSONSCHEMA"),
+		refs:       refs,
+	}
+}
+
+func (c *cli) readSchema(f schemaFlags) (string, error) {
+	if *f.file == "" {
+		return "", errUsage
+	}
+
+	var b []byte
+	var err error
+	if *f.file == "-" {
+		b, err = ioutil.ReadAll(c.stdin)
+	} else {
+		b, err = ioutil.ReadFile(*f.file)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func (c *cli) register(args []string) (result, error) {
+	fs := c.flagSet("register")
+	sf := c.schemaFlags(fs)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return result{}, errUsage
+	}
+	schema, err := c.readSchema(sf)
+	if err != nil {
+		return result{}, err
+	}
+
+	subject := fs.Arg(0)
+	schemaType := schemaregistry.SchemaType(strings.ToUpper(*sf.schemaType))
+	if _, err := c.client.CreateSchema(subject, schema, schemaType, *sf.refs...); err != nil {
+		return result{}, err
+	}
+	resp, err := c.client.LookupSchema(subject, schema, schemaType, *sf.refs...)
+	if err != nil {
+		return result{}, err
+	}
+
+	return schemaResult(resp), nil
+}
+
+func (c *cli) check(args []string) (result, error) {
+	fs := c.flagSet("check")
+	sf := c.schemaFlags(fs)
+	rawVersion := fs.String("version", "latest", "version to check against: latest, all or a version number")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return result{}, errUsage
+	}
+	schema, err := c.readSchema(sf)
+	if err != nil {
+		return result{}, err
+	}
+
+	version := int(schemaregistry.AllVersions)
+	if *rawVersion != "all" {
+		if version, err = parseVersion(*rawVersion); err != nil {
+			return result{}, err
+		}
+	}
+
+	resp, err := c.client.CheckCompatibility(fs.Arg(0), schema, schemaregistry.SchemaType(strings.ToUpper(*sf.schemaType)), version, *sf.refs...)
+	if err != nil {
+		return result{}, err
+	}
+
+	rows := [][]string{{strconv.FormatBool(resp.IsCompatible), ""}}
+	for _, msg := range resp.Messages {
+		rows = append(rows, []string{"", msg})
+	}
+	res := result{value: resp, header: []string{"COMPATIBLE", "MESSAGE"}, rows: rows}
+	if !resp.IsCompatible {
+		return res, errIncompatible
+	}
+
+	return res, nil
+}
+
+func (c *cli) config(args []string) (result, error) {
+	level, err := subjectSetting(args, func(op, subject, value string) (string, error) {
+		var level schemaregistry.CompatibilityLevel
+		var err error
+		switch op {
+		case "get":
+			level, err = c.client.GetConfig(subject)
+		case "set":
+			level, err = c.client.SetConfig(subject, schemaregistry.CompatibilityLevel(strings.ToUpper(value)))
+		case "delete":
+			level, err = c.client.DeleteConfig(subject)
+		}
+		return string(level), err
+	})
+	if err != nil {
+		return result{}, err
+	}
+
+	return result{
+		value:  map[string]string{"compatibilityLevel": level},
+		header: []string{"COMPATIBILITY"},
+		rows:   [][]string{{level}},
+	}, nil
+}
+
+func (c *cli) mode(args []string) (result, error) {
+	mode, err := subjectSetting(args, func(op, subject, value string) (string, error) {
+		var mode schemaregistry.Mode
+		var err error
+		switch op {
+		case "get":
+			mode, err = c.client.GetMode(subject)
+		case "set":
+			mode, err = c.client.SetMode(subject, schemaregistry.Mode(strings.ToUpper(value)))
+		case "delete":
+			mode, err = c.client.DeleteMode(subject)
+		}
+		return string(mode), err
+	})
+	if err != nil {
+		return result{}, err
+	}
+
+	return result{
+		value:  map[string]string{"mode": mode},
+		header: []string{"MODE"},
+		rows:   [][]string{{mode}},
+	}, nil
+}
+
+// subjectSetting parses the arguments of the config and mode commands, where
+// an omitted subject refers to the global setting, and calls do with them.
+func subjectSetting(args []string, do func(op, subject, value string) (string, error)) (string, error) {
+	if len(args) == 0 {
+		return "", errUsage
+	}
+
+	op, args := args[0], args[1:]
+	switch {
+	case op == "get" && len(args) <= 1:
+		return do(op, strings.Join(args, ""), "")
+	case op == "set" && len(args) == 1:
+		return do(op, "", args[0])
+	case op == "set" && len(args) == 2:
+		return do(op, args[0], args[1])
+	case op == "delete" && len(args) == 1:
+		return do(op, args[0], "")
+	}
+
+	return "", errUsage
+}
+
+func (c *cli) delete(args []string) (result, error) {
+	fs := c.flagSet("delete")
+	permanent := fs.Bool("permanent", false, "permanently delete a soft deleted subject or version")
+	if err := fs.Parse(args); err != nil || fs.NArg() < 1 || fs.NArg() > 2 {
+		return result{}, errUsage
+	}
+
+	var versions []int
+	if fs.NArg() == 2 {
+		version, err := parseVersion(fs.Arg(1))
+		if err != nil {
+			return result{}, err
+		}
+		deleted, err := c.client.DeleteSchemaVersion(fs.Arg(0), version, *permanent)
+		if err != nil {
+			return result{}, err
+		}
+		versions = []int{deleted}
+	} else {
+		var err error
+		if versions, err = c.client.DeleteSubject(fs.Arg(0), *permanent); err != nil {
+			return result{}, err
+		}
+	}
+
+	items := make([]string, 0, len(versions))
+	for _, v := range versions {
+		items = append(items, strconv.Itoa(v))
+	}
+
+	return listResult(versions, "DELETED VERSION", items), nil
+}
+
+func (c *cli) diff(args []string) (result, error) {
+	if len(args) != 3 {
+		return result{}, errUsage
+	}
+	from, err := parseVersion(args[1])
+	if err != nil {
+		return result{}, err
+	}
+	to, err := parseVersion(args[2])
+	if err != nil {
+		return result{}, err
+	}
+
+	d, err := diff.Versions(c.client, args[0], from, to)
+	if err != nil {
+		return result{}, err
+	}
+
+	return result{value: d, text: d.String()}, nil
+}
+
+func (c *cli) gen(args []string) (result, error) {
+	fs := c.flagSet("gen")
+	id := fs.Int("id", 0, "schema id")
+	var opts codegen.Options
+	fs.StringVar(&opts.Package, "package", "", "package name of the generated code (default schemas)")
+	fs.StringVar(&opts.TypeName, "type", "", "name of the root type if the schema does not name it")
+	if err := fs.Parse(args); err != nil {
+		return result{}, errUsage
+	}
+	args = fs.Args()
+
+	var src []byte
+	var err error
+	switch {
+	case *id != 0 && len(args) == 0:
+		src, err = codegen.GenerateByID(c.client, *id, opts)
+	case *id == 0 && (len(args) == 1 || len(args) == 2):
+		version := int(schemaregistry.LatestVersion)
+		if len(args) == 2 {
+			if version, err = parseVersion(args[1]); err != nil {
+				return result{}, err
+			}
+		}
+		src, err = codegen.Generate(c.client, args[0], version, opts)
+	default:
+		return result{}, errUsage
+	}
+	if err != nil {
+		return result{}, err
+	}
+
+	return result{value: string(src), text: string(src)}, nil
+}
+
+func parseVersion(s string) (int, error) {
+	if s == "latest" {
+		return int(schemaregistry.LatestVersion), nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid version %q", s)
+	}
+
+	return v, nil
+}
+
+// referencesFlag collects repeated -ref name=subject:version flags.
+type referencesFlag []schemaregistry.Reference
+
+func (f *referencesFlag) String() string {
+	refs := make([]string, 0, len(*f))
+	for _, ref := range *f {
+		refs = append(refs, ref.Name+"="+ref.Subject+":"+strconv.Itoa(ref.Version))
+	}
+
+	return strings.Join(refs, ",")
+}
+
+func (f *referencesFlag) Set(value string) error {
+	eq := strings.Index(value, "=")
+	colon := strings.LastIndex(value, ":")
+	if eq <= 0 || colon < eq+2 {
+		return fmt.Errorf("invalid reference %q, expected name=subject:version", value)
+	}
+	version, err := strconv.Atoi(value[colon+1:])
+	if err != nil {
+		return fmt.Errorf("invalid reference version in %q", value)
+	}
+	*f = append(*f, schemaregistry.Reference{Name: value[:eq], Subject: value[eq+1 : colon], Version: version})
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+// config holds the settings srctl needs to reach the registry. Values set by
+// flags take precedence over environment variables, which take precedence
+// over the config file.
+type config struct {
+	URL       string `json:"url"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+	Output    string `json:"output,omitempty"`
+	Normalize bool   `json:"normalize,omitempty"`
+	Context   string `json:"context,omitempty"`
+}
+
+const (
+	envURL      = "SRCTL_URL"
+	envUsername = "SRCTL_USERNAME"
+	envPassword = "SRCTL_PASSWORD"
+	envOutput   = "SRCTL_OUTPUT"
+	envConfig   = "SRCTL_CONFIG"
+	envContext  = "SRCTL_CONTEXT"
+)
+
+// globalFlags registers the flags accepted before the subcommand.
+func globalFlags(fs *flag.FlagSet) (flags *config, configFile *string) {
+	flags = &config{}
+	fs.StringVar(&flags.URL, "url", "", "registry url (env "+envURL+")")
+	fs.StringVar(&flags.Output, "output", "", "output format, table or json (env "+envOutput+")")
+	fs.BoolVar(&flags.Normalize, "normalize", false, "normalize schemas on register and lookup")
+	fs.StringVar(&flags.Context, "context", "", "schema context of the subjects and ids (env "+envContext+")")
+	configFile = fs.String("config", "", "path to a JSON config file (env "+envConfig+")")
+
+	return flags, configFile
+}
+
+// setFlags returns the names of the flags set on the command line.
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	return set
+}
+
+// loadConfig merges the config file, the environment and the flags. Boolean
+// flags only override the config file if they are set, see setFlags.
+func loadConfig(flags *config, set map[string]bool, configFile string, getenv func(string) string) (*config, error) {
+	cfg := &config{}
+
+	if configFile == "" {
+		configFile = getenv(envConfig)
+	}
+	if configFile != "" {
+		b, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := jsoniter.Unmarshal(b, cfg); err != nil {
+			return nil, errors.New("invalid config file " + configFile + ": " + err.Error())
+		}
+	}
+
+	override(&cfg.URL, getenv(envURL), flags.URL)
+	override(&cfg.Username, getenv(envUsername), "")
+	override(&cfg.Password, getenv(envPassword), "")
+	override(&cfg.Output, getenv(envOutput), flags.Output)
+	override(&cfg.Context, getenv(envContext), flags.Context)
+	if set["normalize"] {
+		cfg.Normalize = flags.Normalize
+	}
+
+	if cfg.URL == "" {
+		return nil, errors.New("registry url not set, use -url or " + envURL)
+	}
+	switch cfg.Output {
+	case "":
+		cfg.Output = outputTable
+	case outputTable, outputJSON:
+	default:
+		return nil, errors.New("unknown output format " + cfg.Output)
+	}
+
+	return cfg, nil
+}
+
+func override(dst *string, values ...string) {
+	for _, v := range values {
+		if v != "" {
+			*dst = v
+		}
+	}
+}
+
+// basicAuthClient sets basic auth credentials on every request.
+type basicAuthClient struct {
+	client             schemaregistry.HTTPClient
+	username, password string
+}
+
+func (c *basicAuthClient) Do(req *http.Request) (*http.Response, error) {
+	req.SetBasicAuth(c.username, c.password)
+	return c.client.Do(req)
+}
+
+// requestTimeout bounds every registry request, so that srctl does not hang
+// on an unresponsive registry.
+var requestTimeout = 30 * time.Second
+
+func newClient(cfg *config, httpClient schemaregistry.HTTPClient) (*schemaregistry.SchemaClient, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: requestTimeout}
+	}
+	if cfg.Username != "" {
+		httpClient = &basicAuthClient{client: httpClient, username: cfg.Username, password: cfg.Password}
+	}
+
+	var client *schemaregistry.SchemaClient
+	var err error
+	if cfg.Normalize {
+		client, err = schemaregistry.NewClient(cfg.URL, schemaregistry.WithCustomHTTPClient(httpClient), schemaregistry.WithNormalizedSchemas())
+	} else {
+		client, err = schemaregistry.NewClient(cfg.URL, schemaregistry.WithCustomHTTPClient(httpClient))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return client.InContext(cfg.Context), nil
+}
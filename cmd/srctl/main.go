@@ -0,0 +1,103 @@
+// Command srctl manages a schema registry from the command line.
+//
+// Usage:
+//
+//	srctl [-url url] [-output table|json] [-config file] [-normalize] [-context name] <command> [arguments]
+//
+// The registry url, credentials, output format and schema context can also be
+// set with the SRCTL_URL, SRCTL_USERNAME, SRCTL_PASSWORD, SRCTL_OUTPUT and
+// SRCTL_CONTEXT environment variables or in a JSON config file named by
+// -config or SRCTL_CONFIG.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+// errUsage is returned by commands called with invalid arguments.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv, nil, os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs srctl with the arguments and returns the exit status. A nil
+// httpClient uses the default HTTP client.
+func run(args []string, getenv func(string) string, httpClient schemaregistry.HTTPClient, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("srctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(fs, stderr) }
+	flags, configFile := globalFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cmd, ok := findCommand(fs.Arg(0))
+	if !ok {
+		fmt.Fprintf(stderr, "srctl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := loadConfig(flags, setFlags(fs), *configFile, getenv)
+	if err != nil {
+		fmt.Fprintln(stderr, "srctl:", err)
+		return 2
+	}
+	client, err := newClient(cfg, httpClient)
+	if err != nil {
+		fmt.Fprintln(stderr, "srctl:", err)
+		return 1
+	}
+
+	c := &cli{client: client, stdin: stdin, stderr: stderr}
+	res, err := cmd.run(c, fs.Args()[1:])
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(stderr, "usage: srctl", cmd.usage)
+		return 2
+	case err != nil && !errors.Is(err, errIncompatible):
+		fmt.Fprintln(stderr, "srctl:", err)
+		return 1
+	}
+
+	if werr := res.write(stdout, cfg.Output); werr != nil {
+		fmt.Fprintln(stderr, "srctl:", werr)
+		return 1
+	}
+	if err != nil {
+		return 1
+	}
+
+	return 0
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+
+	return command{}, false
+}
+
+func usage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintln(w, "usage: srctl [flags] <command> [arguments]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintln(w, "  "+cmd.usage)
+	}
+	fmt.Fprintln(w, "\nflags:")
+	fs.PrintDefaults()
+}
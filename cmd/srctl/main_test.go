@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anjulapaulus/schema_registry/registrytest"
+)
+
+const userV1 = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"}]}`
+
+const userIncompatible = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"email","type":"string"}]}`
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal("writeFile: ", err)
+	}
+
+	return path
+}
+
+func runCLI(t *testing.T, env map[string]string, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	getenv := func(key string) string { return env[key] }
+	code := run(args, getenv, nil, strings.NewReader(stdin), &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func newServer(t *testing.T) (*registrytest.Server, map[string]string) {
+	t.Helper()
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+
+	return srv, map[string]string{envURL: srv.URL}
+}
+
+func TestRegisterAndGet(t *testing.T) {
+	_, env := newServer(t)
+	file := writeFile(t, "user.avsc", userV1)
+
+	code, out, errOut := runCLI(t, env, "", "register", "-file", file, "users")
+	if code != 0 {
+		t.Fatal("TestRegisterAndGet: register failed", code, errOut)
+	}
+	if !strings.Contains(out, "users") || !strings.Contains(out, "AVRO") {
+		t.Error("TestRegisterAndGet: unexpected register output", out)
+	}
+
+	code, out, _ = runCLI(t, env, "", "subjects")
+	if code != 0 || out != "SUBJECT\nusers\n" {
+		t.Errorf("TestRegisterAndGet: subjects returned %d %q", code, out)
+	}
+
+	code, out, _ = runCLI(t, env, "", "-output", "json", "versions", "users")
+	if code != 0 || strings.Join(strings.Fields(out), "") != "[1]" {
+		t.Errorf("TestRegisterAndGet: versions returned %d %q", code, out)
+	}
+
+	code, out, _ = runCLI(t, env, "", "-output", "json", "get", "-id", "1")
+	if code != 0 || !strings.Contains(out, `"id": 1`) {
+		t.Errorf("TestRegisterAndGet: get -id returned %d %q", code, out)
+	}
+
+	code, out, _ = runCLI(t, env, "", "get", "users", "1")
+	if code != 0 || !strings.Contains(out, userV1) {
+		t.Errorf("TestRegisterAndGet: get version returned %d %q", code, out)
+	}
+}
+
+func TestRegisterFromStdin(t *testing.T) {
+	_, env := newServer(t)
+
+	code, _, errOut := runCLI(t, env, userV1, "register", "-file", "-", "users")
+	if code != 0 {
+		t.Fatal("TestRegisterFromStdin: register failed", code, errOut)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	_, env := newServer(t)
+	if code, _, errOut := runCLI(t, env, userV1, "register", "-file", "-", "users"); code != 0 {
+		t.Fatal("TestCheck: register failed", errOut)
+	}
+
+	code, out, _ := runCLI(t, env, userV1, "check", "-file", "-", "users")
+	if code != 0 || !strings.Contains(out, "true") {
+		t.Errorf("TestCheck: compatible schema returned %d %q", code, out)
+	}
+
+	code, out, _ = runCLI(t, env, userIncompatible, "check", "-version", "all", "-file", "-", "users")
+	if code != 1 || !strings.Contains(out, "false") {
+		t.Errorf("TestCheck: incompatible schema returned %d %q", code, out)
+	}
+}
+
+func TestConfigAndMode(t *testing.T) {
+	_, env := newServer(t)
+
+	code, out, _ := runCLI(t, env, "", "config", "set", "users", "full")
+	if code != 0 || !strings.Contains(out, "FULL") {
+		t.Errorf("TestConfigAndMode: config set returned %d %q", code, out)
+	}
+	code, out, _ = runCLI(t, env, "", "-output", "json", "config", "get", "users")
+	if code != 0 || !strings.Contains(out, `"compatibilityLevel": "FULL"`) {
+		t.Errorf("TestConfigAndMode: config get returned %d %q", code, out)
+	}
+	code, out, _ = runCLI(t, env, "", "config", "get")
+	if code != 0 || !strings.Contains(out, "BACKWARD") {
+		t.Errorf("TestConfigAndMode: global config get returned %d %q", code, out)
+	}
+
+	code, out, _ = runCLI(t, env, "", "mode", "set", "readonly")
+	if code != 0 || !strings.Contains(out, "READONLY") {
+		t.Errorf("TestConfigAndMode: mode set returned %d %q", code, out)
+	}
+	code, _, errOut := runCLI(t, env, userV1, "register", "-file", "-", "users")
+	if code != 1 || !strings.Contains(errOut, "read-only") {
+		t.Errorf("TestConfigAndMode: register in read-only mode returned %d %q", code, errOut)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	_, env := newServer(t)
+	if code, _, errOut := runCLI(t, env, userV1, "register", "-file", "-", "users"); code != 0 {
+		t.Fatal("TestDelete: register failed", errOut)
+	}
+
+	code, out, _ := runCLI(t, env, "", "delete", "users", "1")
+	if code != 0 || out != "DELETED VERSION\n1\n" {
+		t.Errorf("TestDelete: delete version returned %d %q", code, out)
+	}
+	code, _, errOut := runCLI(t, env, "", "delete", "-permanent", "users")
+	if code != 0 {
+		t.Errorf("TestDelete: permanent delete returned %d %q", code, errOut)
+	}
+	code, out, _ = runCLI(t, env, "", "subjects")
+	if code != 0 || out != "SUBJECT\n" {
+		t.Errorf("TestDelete: subjects after delete returned %d %q", code, out)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	srv, _ := newServer(t)
+	file := writeFile(t, "srctl.json", `{"url":"`+srv.URL+`","output":"json","username":"user","password":"secret"}`)
+
+	var user, password string
+	client := &authRecorder{client: http.DefaultClient, user: &user, password: &password}
+	var stdout, stderr bytes.Buffer
+	env := map[string]string{envConfig: file}
+	code := run([]string{"subjects"}, func(key string) string { return env[key] }, client, nil, &stdout, &stderr)
+	if code != 0 || strings.TrimSpace(stdout.String()) != "[]" {
+		t.Errorf("TestConfigFile: subjects returned %d %q %q", code, stdout.String(), stderr.String())
+	}
+	if user != "user" || password != "secret" {
+		t.Errorf("TestConfigFile: basic auth not set, got %q %q", user, password)
+	}
+}
+
+func TestNormalizeFlag(t *testing.T) {
+	file := writeFile(t, "srctl.json", `{"url":"http://localhost:8081","normalize":true}`)
+	getenv := func(string) string { return "" }
+
+	for args, want := range map[string]bool{"": true, "-normalize=false": false, "-normalize": true} {
+		fs := flag.NewFlagSet("srctl", flag.ContinueOnError)
+		flags, _ := globalFlags(fs)
+		if err := fs.Parse(strings.Fields(args)); err != nil {
+			t.Fatal("TestNormalizeFlag: ", err)
+		}
+		cfg, err := loadConfig(flags, setFlags(fs), file, getenv)
+		if err != nil {
+			t.Fatal("TestNormalizeFlag: ", err)
+		}
+		if cfg.Normalize != want {
+			t.Errorf("TestNormalizeFlag: %q got normalize %v want %v", args, cfg.Normalize, want)
+		}
+	}
+}
+
+type authRecorder struct {
+	client         *http.Client
+	user, password *string
+}
+
+func (a *authRecorder) Do(req *http.Request) (*http.Response, error) {
+	*a.user, *a.password, _ = req.BasicAuth()
+	return a.client.Do(req)
+}
+
+func TestUsageErrors(t *testing.T) {
+	_, env := newServer(t)
+
+	if code, _, _ := runCLI(t, env, ""); code != 2 {
+		t.Error("TestUsageErrors: missing command returned", code)
+	}
+	if code, _, _ := runCLI(t, env, "", "unknown"); code != 2 {
+		t.Error("TestUsageErrors: unknown command returned", code)
+	}
+	if code, _, errOut := runCLI(t, env, "", "versions"); code != 2 || !strings.Contains(errOut, "usage: srctl versions") {
+		t.Errorf("TestUsageErrors: missing subject returned %d %q", code, errOut)
+	}
+	if code, _, _ := runCLI(t, nil, "", "subjects"); code != 2 {
+		t.Error("TestUsageErrors: missing url returned", code)
+	}
+	if code, _, _ := runCLI(t, env, "", "-output", "yaml", "subjects"); code != 2 {
+		t.Error("TestUsageErrors: unknown output returned", code)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	_, env := newServer(t)
+	userV2 := `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"age","type":"int","default":0}]}`
+	for _, schema := range []string{userV1, userV2} {
+		if code, _, errOut := runCLI(t, env, schema, "register", "-file", "-", "users"); code != 0 {
+			t.Fatal("TestDiff: register failed", errOut)
+		}
+	}
+
+	code, out, _ := runCLI(t, env, "", "diff", "users", "1", "latest")
+	if code != 0 || out != "users: version 1 -> 2\n+ field com.test.User.age: int (default 0)\n" {
+		t.Errorf("TestDiff: diff returned %d %q", code, out)
+	}
+	code, out, _ = runCLI(t, env, "", "-output", "json", "diff", "users", "1", "2")
+	if code != 0 || !strings.Contains(out, `"kind": "ADDED"`) {
+		t.Errorf("TestDiff: json diff returned %d %q", code, out)
+	}
+}
+
+func TestGen(t *testing.T) {
+	_, env := newServer(t)
+	if code, _, errOut := runCLI(t, env, userV1, "register", "-file", "-", "users"); code != 0 {
+		t.Fatal("TestGen: register failed", errOut)
+	}
+
+	code, out, _ := runCLI(t, env, "", "gen", "-package", "events", "users", "1")
+	if code != 0 || !strings.Contains(out, "package events\n") || !strings.Contains(out, "type User struct {") ||
+		!strings.Contains(out, `UserSubject  = "users"`) {
+		t.Errorf("TestGen: gen returned %d %q", code, out)
+	}
+	code, byID, _ := runCLI(t, env, "", "gen", "-package", "events", "-id", "1")
+	if code != 0 || byID != out {
+		t.Errorf("TestGen: gen -id returned %d %q", code, byID)
+	}
+	if code, _, _ := runCLI(t, env, "", "gen", "-id", "1", "users"); code != 2 {
+		t.Error("TestGen: gen with id and subject returned", code)
+	}
+}
+
+func TestContexts(t *testing.T) {
+	_, env := newServer(t)
+	if code, _, errOut := runCLI(t, env, userV1, "-context", "tenant", "register", "-file", "-", "users"); code != 0 {
+		t.Fatal("TestContexts: register failed", errOut)
+	}
+
+	code, out, _ := runCLI(t, env, "", "contexts")
+	if code != 0 || out != "CONTEXT\n.\n.tenant\n" {
+		t.Errorf("TestContexts: contexts returned %d %q", code, out)
+	}
+	code, out, _ = runCLI(t, env, "", "-context", "tenant", "subjects")
+	if code != 0 || out != "SUBJECT\n:.tenant:users\n" {
+		t.Errorf("TestContexts: subjects returned %d %q", code, out)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	timeout := requestTimeout
+	requestTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { requestTimeout = timeout })
+
+	start := time.Now()
+	code, _, errOut := runCLI(t, map[string]string{envURL: srv.URL}, "", "subjects")
+	if code == 0 || time.Since(start) > 500*time.Millisecond {
+		t.Error("TestRequestTimeout: request not timed out", code, errOut)
+	}
+}
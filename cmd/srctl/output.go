@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// result is the output of a command. JSON output encodes the value, table
+// output prints the header and rows, or the text if it is set.
+type result struct {
+	value  interface{}
+	header []string
+	rows   [][]string
+	text   string
+}
+
+func (r result) write(w io.Writer, format string) error {
+	if format == outputJSON {
+		b, err := jsoniter.MarshalIndent(r.value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	if r.text != "" {
+		_, err := io.WriteString(w, r.text)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(r.header) > 0 {
+		fmt.Fprintln(tw, strings.Join(r.header, "\t"))
+	}
+	for _, row := range r.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// listResult prints one value per row under the header.
+func listResult(value interface{}, header string, items []string) result {
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, []string{item})
+	}
+
+	return result{value: value, header: []string{header}, rows: rows}
+}
@@ -0,0 +1,204 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hamba/avro"
+)
+
+// avroGen generates the Go types of an AVRO schema.
+type avroGen struct {
+	*file
+	types map[string]string
+}
+
+// avro declares the types of the schema and returns the name of its root type.
+func (f *file) avro(schema string, cache *avro.SchemaCache, rootName string) (string, error) {
+	s, err := avro.ParseWithCache(schema, "", cache)
+	if err != nil {
+		return "", fmt.Errorf("error parsing avro schema err:%w", err)
+	}
+
+	g := &avroGen{file: f, types: make(map[string]string)}
+	if _, ok := s.(avro.NamedSchema); ok {
+		typ := g.typ(s)
+		return strings.TrimPrefix(typ, "*"), nil
+	}
+
+	rootName = f.names.typeName(rootName)
+	idx := f.reserve()
+	f.decls[idx] = fmt.Sprintf("// %s is the root type of the schema.\ntype %s %s\n", rootName, rootName, g.typ(s))
+
+	return rootName, nil
+}
+
+// typ returns the Go type of the schema, declaring named types as needed.
+func (g *avroGen) typ(s avro.Schema) string {
+	switch s := s.(type) {
+	case *avro.RefSchema:
+		return g.typ(s.Schema())
+	case *avro.PrimitiveSchema:
+		return g.primitive(s)
+	case *avro.RecordSchema:
+		return g.record(s)
+	case *avro.EnumSchema:
+		return g.enum(s)
+	case *avro.FixedSchema:
+		return g.fixed(s)
+	case *avro.ArraySchema:
+		return "[]" + g.typ(s.Items())
+	case *avro.MapSchema:
+		return "map[string]" + g.typ(s.Values())
+	case *avro.UnionSchema:
+		return g.union(s)
+	}
+
+	return "interface{}"
+}
+
+func (g *avroGen) primitive(s *avro.PrimitiveSchema) string {
+	var logical avro.LogicalType
+	if ls := s.Logical(); ls != nil {
+		logical = ls.Type()
+	}
+
+	switch s.Type() {
+	case avro.Boolean:
+		return "bool"
+	case avro.Int:
+		switch logical {
+		case avro.Date:
+			g.imports["time"] = true
+			return "time.Time"
+		case avro.TimeMillis:
+			g.imports["time"] = true
+			return "time.Duration"
+		}
+		return "int"
+	case avro.Long:
+		switch logical {
+		case avro.TimestampMillis, avro.TimestampMicros:
+			g.imports["time"] = true
+			return "time.Time"
+		case avro.TimeMicros:
+			g.imports["time"] = true
+			return "time.Duration"
+		}
+		return "int64"
+	case avro.Float:
+		return "float32"
+	case avro.Double:
+		return "float64"
+	case avro.Bytes:
+		if logical == avro.Decimal {
+			g.imports["math/big"] = true
+			return "*big.Rat"
+		}
+		return "[]byte"
+	case avro.String:
+		return "string"
+	}
+
+	return "interface{}"
+}
+
+// union returns a pointer for nullable unions, the branch type for single
+// branch unions and interface{} otherwise.
+func (g *avroGen) union(s *avro.UnionSchema) string {
+	types := s.Types()
+	if len(types) == 1 {
+		return g.typ(types[0])
+	}
+	if !s.Nullable() {
+		for _, t := range types {
+			g.typ(t)
+		}
+		return "interface{}"
+	}
+
+	for _, t := range types {
+		if t.Type() == avro.Null {
+			continue
+		}
+		typ := g.typ(t)
+		if strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || typ == "interface{}" {
+			return typ
+		}
+		return "*" + typ
+	}
+
+	return "interface{}"
+}
+
+func (g *avroGen) record(s *avro.RecordSchema) string {
+	if name, ok := g.types[s.FullName()]; ok {
+		return name
+	}
+	name := g.names.typeName(s.FullName())
+	g.types[s.FullName()] = name
+	idx := g.reserve()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s is the %s record.\n", name, s.FullName())
+	if s.Doc() != "" {
+		b.WriteString("//\n" + comment(s.Doc()))
+	}
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+
+	fields := make(map[string]bool)
+	for _, field := range s.Fields() {
+		fieldName := goName(field.Name())
+		for i := 2; fields[fieldName]; i++ {
+			fieldName = goName(field.Name()) + strconv.Itoa(i)
+		}
+		fields[fieldName] = true
+
+		if field.Doc() != "" {
+			b.WriteString(comment(field.Doc()))
+		}
+		fmt.Fprintf(&b, "%s %s `avro:%s`\n", fieldName, g.typ(field.Type()), strconv.Quote(field.Name()))
+	}
+	b.WriteString("}\n")
+
+	g.decls[idx] = b.String()
+
+	return name
+}
+
+func (g *avroGen) enum(s *avro.EnumSchema) string {
+	if name, ok := g.types[s.FullName()]; ok {
+		return name
+	}
+	name := g.names.typeName(s.FullName())
+	g.types[s.FullName()] = name
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s is the %s enum.\ntype %s string\n\n", name, s.FullName(), name)
+	fmt.Fprintf(&b, "// %s symbols.\nconst (\n", name)
+	for _, symbol := range s.Symbols() {
+		fmt.Fprintf(&b, "%s%s %s = %s\n", name, goName(strings.ToLower(symbol)), name, strconv.Quote(symbol))
+	}
+	b.WriteString(")\n")
+
+	g.decls = append(g.decls, b.String())
+
+	return name
+}
+
+func (g *avroGen) fixed(s *avro.FixedSchema) string {
+	if ls := s.Logical(); ls != nil && ls.Type() == avro.Decimal {
+		g.imports["math/big"] = true
+		return "big.Rat"
+	}
+	if name, ok := g.types[s.FullName()]; ok {
+		return name
+	}
+	name := g.names.typeName(s.FullName())
+	g.types[s.FullName()] = name
+
+	g.decls = append(g.decls, fmt.Sprintf("// %s is the %s fixed.\ntype %s [%d]byte\n", name, s.FullName(), name, s.Size()))
+
+	return name
+}
@@ -0,0 +1,232 @@
+// Package codegen generates Go types from registry schemas.
+//
+// AVRO schemas become structs with avro tags that hamba/avro encodes and
+// decodes directly, JSONSCHEMA schemas become structs with json tags. The
+// generated source also carries the schema with its subject, version and id,
+// so it can be handed to the serializers without copying the schema around.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hamba/avro"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+// Options configures the generated code.
+type Options struct {
+	// Package is the package name of the generated file, "schemas" if empty.
+	Package string
+	// TypeName is the name of the root type when the schema does not name it,
+	// as for JSONSCHEMA schemas or AVRO schemas that are not named types. It
+	// defaults to a name derived from the subject.
+	TypeName string
+}
+
+// source is a schema with the registry metadata of where it came from.
+type source struct {
+	subject    string
+	version    int
+	id         int
+	schemaType schemaregistry.SchemaType
+	schema     string
+	cache      *avro.SchemaCache
+}
+
+// Generate generates Go types from the schema registered under the subject
+// version, which may be schemaregistry.LatestVersion.
+func Generate(c *schemaregistry.SchemaClient, subject string, version int, opts Options) ([]byte, error) {
+	var resp schemaregistry.SchemaResponse
+	var err error
+	if schemaregistry.Version(version) == schemaregistry.LatestVersion {
+		resp, err = c.GetLatestSchema(subject)
+	} else {
+		resp, err = c.GetSchemaByVersion(subject, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error obtaining subject:%s version:%d err:%w", subject, version, err)
+	}
+
+	src := source{
+		subject:    resp.Subject,
+		version:    resp.Version,
+		id:         resp.ID,
+		schemaType: schemaregistry.AVRO,
+		schema:     resp.Schema,
+		cache:      &avro.SchemaCache{},
+	}
+	if src.subject == "" {
+		src.subject = subject
+	}
+	if resp.SchemaType != nil && *resp.SchemaType != "" {
+		src.schemaType = *resp.SchemaType
+	}
+	if src.schemaType == schemaregistry.AVRO {
+		if err := parseReferences(c, resp.References, src.cache, map[string]bool{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return generate(src, opts)
+}
+
+// GenerateByID generates Go types from the schema with the given id, using
+// the first subject version the schema is registered under.
+func GenerateByID(c *schemaregistry.SchemaClient, id int, opts Options) ([]byte, error) {
+	versions, err := c.GetSubjectVersionByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error obtaining subject and version for schema id:%d err:%w", id, err)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("schema id:%d is not registered under a subject", id)
+	}
+
+	return Generate(c, versions[0].Subject, versions[0].Version, opts)
+}
+
+// GenerateAvro generates Go types from an AVRO schema without references.
+func GenerateAvro(schema string, opts Options) ([]byte, error) {
+	return generate(source{schemaType: schemaregistry.AVRO, schema: schema, cache: &avro.SchemaCache{}}, opts)
+}
+
+// GenerateJSONSchema generates Go types from a JSONSCHEMA schema.
+func GenerateJSONSchema(schema string, opts Options) ([]byte, error) {
+	return generate(source{schemaType: schemaregistry.JSONSCHEMA, schema: schema}, opts)
+}
+
+// parseReferences parses the referenced schemas into the cache, dependencies first.
+func parseReferences(c *schemaregistry.SchemaClient, refs []schemaregistry.Reference, cache *avro.SchemaCache, seen map[string]bool) error {
+	for _, ref := range refs {
+		key := ref.Subject + "/" + strconv.Itoa(ref.Version)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		resp, err := c.GetSchemaByVersion(ref.Subject, ref.Version)
+		if err != nil {
+			return fmt.Errorf("error obtaining reference %s err:%w", ref.Name, err)
+		}
+		if err := parseReferences(c, resp.References, cache, seen); err != nil {
+			return err
+		}
+		if _, err := avro.ParseWithCache(resp.Schema, "", cache); err != nil {
+			return fmt.Errorf("error parsing reference %s err:%w", ref.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// file collects the declarations of a generated file.
+type file struct {
+	names   *names
+	imports map[string]bool
+	decls   []string
+}
+
+func newFile() *file {
+	return &file{names: newNames(), imports: make(map[string]bool)}
+}
+
+// reserve adds an empty declaration to be filled in later, so that a type is
+// declared before the types it uses.
+func (f *file) reserve() int {
+	f.decls = append(f.decls, "")
+	return len(f.decls) - 1
+}
+
+func generate(src source, opts Options) ([]byte, error) {
+	f := newFile()
+
+	var root string
+	var err error
+	switch src.schemaType {
+	case schemaregistry.AVRO:
+		root, err = f.avro(src.schema, src.cache, rootTypeName(src, opts))
+	case schemaregistry.JSONSCHEMA, "JSON":
+		root, err = f.jsonSchema(src.schema, rootTypeName(src, opts))
+	default:
+		err = fmt.Errorf("code generation is not supported for schema type [%s]", src.schemaType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "schemas"
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by schema_registry codegen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	if len(f.imports) > 0 {
+		imports := make([]string, 0, len(f.imports))
+		for imp := range f.imports {
+			imports = append(imports, strconv.Quote(imp))
+		}
+		sort.Strings(imports)
+		fmt.Fprintf(&b, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+
+	if src.subject != "" {
+		fmt.Fprintf(&b, "// Registry metadata of the %s schema.\nconst (\n", root)
+		fmt.Fprintf(&b, "%sSubject = %s\n", root, strconv.Quote(src.subject))
+		fmt.Fprintf(&b, "%sVersion = %d\n", root, src.version)
+		fmt.Fprintf(&b, "%sSchemaID = %d\n", root, src.id)
+		b.WriteString(")\n\n")
+	}
+	fmt.Fprintf(&b, "// %sSchema is the schema the types were generated from.\n", root)
+	fmt.Fprintf(&b, "const %sSchema = %s\n\n", root, quote(src.schema))
+
+	for _, decl := range f.decls {
+		b.WriteString(decl)
+		b.WriteString("\n")
+	}
+
+	out, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated code err:%w", err)
+	}
+
+	return out, nil
+}
+
+func rootTypeName(src source, opts Options) string {
+	if opts.TypeName != "" {
+		return opts.TypeName
+	}
+	if src.subject != "" {
+		return goName(strings.TrimSuffix(strings.TrimSuffix(src.subject, "-value"), "-key"))
+	}
+
+	return "Value"
+}
+
+// quote returns the string as a raw string literal if possible.
+func quote(s string) string {
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") {
+		return strconv.Quote(s)
+	}
+
+	return "`" + s + "`"
+}
+
+// comment formats text as a doc comment.
+func comment(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		b.WriteString("// ")
+		b.WriteString(strings.TrimSpace(line))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,288 @@
+package codegen
+
+import (
+	"flag"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/registrytest"
+)
+
+const userSchema = `{"type":"record","name":"User","namespace":"com.test","doc":"A user.","fields":[
+	{"name":"user_id","type":"long"},
+	{"name":"email","type":["null","string"],"default":null,"doc":"Contact address."},
+	{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","IN_PROGRESS"]}},
+	{"name":"hash","type":{"type":"fixed","name":"MD5","size":16}},
+	{"name":"created","type":{"type":"long","logicalType":"timestamp-millis"}},
+	{"name":"price","type":{"type":"bytes","logicalType":"decimal","precision":9,"scale":2}},
+	{"name":"tags","type":{"type":"array","items":"string"}},
+	{"name":"address","type":["null",{"type":"record","name":"Address","fields":[{"name":"city","type":"string"}]}]},
+	{"name":"other","type":["int","string"]}
+]}`
+
+// userGolden is the code GenerateAvro emits for userSchema.
+const userGolden = "user_avro_test.go"
+
+var update = flag.Bool("update", false, "write the golden generated files")
+
+func parseGenerated(t *testing.T, name string, src []byte) {
+	t.Helper()
+	if _, err := parser.ParseFile(token.NewFileSet(), "generated.go", src, parser.ParseComments); err != nil {
+		t.Fatalf("%s: generated code does not parse: %v\n%s", name, err, src)
+	}
+}
+
+func TestGenerateAvro(t *testing.T) {
+	out, err := GenerateAvro(userSchema, Options{Package: "users"})
+	if err != nil {
+		t.Fatal("TestGenerateAvro: ", err)
+	}
+	parseGenerated(t, "TestGenerateAvro", out)
+
+	for _, want := range []string{
+		"// Code generated by schema_registry codegen. DO NOT EDIT.\n\npackage users\n",
+		"import (\n\t\"math/big\"\n\t\"time\"\n)\n",
+		"const UserSchema = `{\"type\":\"record\"",
+		"// User is the com.test.User record.\n//\n// A user.\ntype User struct {\n",
+		"\tUserID int64 `avro:\"user_id\"`\n\t// Contact address.\n\tEmail   *string     `avro:\"email\"`\n",
+		"\tStatus  Status      `avro:\"status\"`\n",
+		"\tHash    MD5         `avro:\"hash\"`\n",
+		"\tCreated time.Time   `avro:\"created\"`\n",
+		"\tPrice   *big.Rat    `avro:\"price\"`\n",
+		"\tTags    []string    `avro:\"tags\"`\n",
+		"\tAddress *Address    `avro:\"address\"`\n",
+		"\tOther   interface{} `avro:\"other\"`\n",
+		"type Status string\n",
+		"\tStatusActive     Status = \"ACTIVE\"\n\tStatusInProgress Status = \"IN_PROGRESS\"\n",
+		"// MD5 is the com.test.MD5 fixed.\ntype MD5 [16]byte\n",
+		"// Address is the com.test.Address record.\ntype Address struct {\n\tCity string `avro:\"city\"`\n}\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("TestGenerateAvro: output is missing %q\n%s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "UserSubject") {
+		t.Error("TestGenerateAvro: registry metadata emitted without a subject")
+	}
+}
+
+// TestGenerateAvroGolden checks that GenerateAvro still emits
+// user_avro_test.go, which TestGenerateAvroRoundTrip compiles against. Run the
+// tests with -update to write it again.
+func TestGenerateAvroGolden(t *testing.T) {
+	out, err := GenerateAvro(userSchema, Options{Package: "codegen"})
+	if err != nil {
+		t.Fatal("TestGenerateAvroGolden: ", err)
+	}
+	if *update {
+		if err := ioutil.WriteFile(userGolden, out, 0o644); err != nil {
+			t.Fatal("TestGenerateAvroGolden: ", err)
+		}
+	}
+
+	golden, err := ioutil.ReadFile(userGolden)
+	if err != nil {
+		t.Fatal("TestGenerateAvroGolden: ", err)
+	}
+	if string(out) != string(golden) {
+		t.Errorf("TestGenerateAvroGolden: output differs from %s, run the tests with -update\n%s", userGolden, out)
+	}
+}
+
+func TestGenerateAvroRoundTrip(t *testing.T) {
+	schema := avro.MustParse(UserSchema)
+	email := "jane@example.com"
+	in := User{
+		UserID:  7,
+		Email:   &email,
+		Status:  StatusInProgress,
+		Hash:    MD5{1, 2, 3},
+		Created: time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC),
+		Price:   big.NewRat(1999, 100),
+		Tags:    []string{"a", "b"},
+		Address: &Address{City: "Colombo"},
+		Other:   "text",
+	}
+
+	b, err := avro.Marshal(schema, in)
+	if err != nil {
+		t.Fatal("TestGenerateAvroRoundTrip: ", err)
+	}
+	var out User
+	if err := avro.Unmarshal(schema, b, &out); err != nil {
+		t.Fatal("TestGenerateAvroRoundTrip: ", err)
+	}
+
+	if out.UserID != in.UserID || *out.Email != email || out.Status != in.Status || out.Hash != in.Hash ||
+		!out.Created.Equal(in.Created) || out.Price.Cmp(in.Price) != 0 || len(out.Tags) != 2 ||
+		out.Address.City != "Colombo" || out.Other != "text" {
+		t.Errorf("TestGenerateAvroRoundTrip: got %+v want %+v", out, in)
+	}
+}
+
+func TestGenerateAvroPrimitiveRoot(t *testing.T) {
+	out, err := GenerateAvro(`{"type":"array","items":{"type":"int","logicalType":"date"}}`, Options{TypeName: "Days"})
+	if err != nil {
+		t.Fatal("TestGenerateAvroPrimitiveRoot: ", err)
+	}
+	parseGenerated(t, "TestGenerateAvroPrimitiveRoot", out)
+	if !strings.Contains(string(out), "type Days []time.Time\n") {
+		t.Errorf("TestGenerateAvroPrimitiveRoot: unexpected output\n%s", out)
+	}
+}
+
+func TestGenerateAvroNameCollision(t *testing.T) {
+	out, err := GenerateAvro(`{"type":"record","name":"Event","namespace":"a","fields":[
+		{"name":"id","type":{"type":"record","name":"ID","namespace":"b","fields":[{"name":"v","type":"string"}]}},
+		{"name":"other","type":{"type":"record","name":"ID","namespace":"c","fields":[{"name":"v","type":"int"}]}}
+	]}`, Options{})
+	if err != nil {
+		t.Fatal("TestGenerateAvroNameCollision: ", err)
+	}
+	parseGenerated(t, "TestGenerateAvroNameCollision", out)
+	if !strings.Contains(string(out), "type ID struct") || !strings.Contains(string(out), "type CID struct") {
+		t.Errorf("TestGenerateAvroNameCollision: unexpected output\n%s", out)
+	}
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	out, err := GenerateJSONSchema(`{"title":"Order","type":"object","properties":{
+		"id":{"type":"integer"},
+		"created":{"type":"string","format":"date-time"},
+		"note":{"type":["string","null"],"description":"Free text."},
+		"items":{"type":"array","items":{"type":"object","properties":{"sku":{"type":"string"}},"required":["sku"]}},
+		"customer":{"$ref":"#/definitions/Customer"},
+		"labels":{"type":"object","additionalProperties":{"type":"string"}}
+	},"required":["id","created"],"definitions":{"Customer":{"type":"object","description":"A customer.","properties":{"name":{"type":"string"}}}}}`, Options{TypeName: "Order"})
+	if err != nil {
+		t.Fatal("TestGenerateJSONSchema: ", err)
+	}
+	parseGenerated(t, "TestGenerateJSONSchema", out)
+
+	for _, want := range []string{
+		"// Order is the Order object.\ntype Order struct {\n",
+		"\tID      int64     `json:\"id\"`\n\tCreated time.Time `json:\"created\"`\n",
+		"\t// Free text.\n\tNote     *string           `json:\"note,omitempty\"`\n",
+		"\tItems    []OrderItemsItem  `json:\"items,omitempty\"`\n",
+		"\tCustomer *Customer         `json:\"customer,omitempty\"`\n",
+		"\tLabels   map[string]string `json:\"labels,omitempty\"`\n",
+		"type OrderItemsItem struct {\n\tSku string `json:\"sku\"`\n}\n",
+		"// Customer is the Customer definition.\n//\n// A customer.\ntype Customer struct {\n\tName *string `json:\"name,omitempty\"`\n}\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("TestGenerateJSONSchema: output is missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestGenerateJSONSchemaNameCollision(t *testing.T) {
+	for _, order := range []string{
+		`"address":{"type":"object","properties":{"city":{"type":"string"}}},"billing":{"$ref":"#/definitions/UserAddress"}`,
+		`"billing":{"$ref":"#/definitions/UserAddress"},"address":{"type":"object","properties":{"city":{"type":"string"}}}`,
+	} {
+		out, err := GenerateJSONSchema(`{"type":"object","properties":{`+order+`},
+			"definitions":{"UserAddress":{"type":"object","properties":{"street":{"type":"string"}}}}}`, Options{TypeName: "User"})
+		if err != nil {
+			t.Fatal("TestGenerateJSONSchemaNameCollision: ", err)
+		}
+		parseGenerated(t, "TestGenerateJSONSchemaNameCollision", out)
+		if strings.Count(string(out), "type UserAddress struct") != 1 || strings.Count(string(out), "type UserAddress2 struct") != 1 {
+			t.Errorf("TestGenerateJSONSchemaNameCollision: unexpected output\n%s", out)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := schemaregistry.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal("TestGenerate: ", err)
+	}
+	if _, err := c.CreateSchema("address", `{"type":"record","name":"Address","namespace":"com.test","fields":[{"name":"city","type":"string"}]}`, schemaregistry.AVRO); err != nil {
+		t.Fatal("TestGenerate: ", err)
+	}
+	id, err := c.CreateSchema("users-value", `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"address","type":"com.test.Address"}]}`,
+		schemaregistry.AVRO, schemaregistry.Reference{Name: "com.test.Address", Subject: "address", Version: 1})
+	if err != nil {
+		t.Fatal("TestGenerate: ", err)
+	}
+
+	out, err := Generate(c, "users-value", int(schemaregistry.LatestVersion), Options{})
+	if err != nil {
+		t.Fatal("TestGenerate: ", err)
+	}
+	parseGenerated(t, "TestGenerate", out)
+	for _, want := range []string{
+		"UserSubject  = \"users-value\"\n",
+		"UserVersion  = 1\n",
+		"UserSchemaID = " + strconv.Itoa(id) + "\n",
+		"type User struct {\n\tAddress Address `avro:\"address\"`\n}\n",
+		"type Address struct {\n\tCity string `avro:\"city\"`\n}\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("TestGenerate: output is missing %q\n%s", want, out)
+		}
+	}
+
+	byID, err := GenerateByID(c, id, Options{})
+	if err != nil {
+		t.Fatal("TestGenerate: ", err)
+	}
+	if string(byID) != string(out) {
+		t.Errorf("TestGenerate: GenerateByID got\n%s\nwant\n%s", byID, out)
+	}
+}
+
+func TestGenerateJSONSchemaSubject(t *testing.T) {
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := schemaregistry.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal("TestGenerateJSONSchemaSubject: ", err)
+	}
+	if _, err := c.CreateSchema("orders-value", `{"type":"object","properties":{"id":{"type":"integer"}},"required":["id"]}`, schemaregistry.JSONSCHEMA); err != nil {
+		t.Fatal("TestGenerateJSONSchemaSubject: ", err)
+	}
+
+	out, err := Generate(c, "orders-value", 1, Options{})
+	if err != nil {
+		t.Fatal("TestGenerateJSONSchemaSubject: ", err)
+	}
+	parseGenerated(t, "TestGenerateJSONSchemaSubject", out)
+	if !strings.Contains(string(out), "OrdersSubject  = \"orders-value\"") || !strings.Contains(string(out), "type Orders struct {\n\tID int64 `json:\"id\"`\n}\n") {
+		t.Errorf("TestGenerateJSONSchemaSubject: unexpected output\n%s", out)
+	}
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	_, err := generate(source{schemaType: schemaregistry.PROTOBUF, schema: `syntax = "proto3";`}, Options{})
+	if err == nil {
+		t.Error("TestGenerateUnsupported: expected an error for PROTOBUF schemas")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for in, want := range map[string]string{
+		"user_id":     "UserID",
+		"createdAt":   "CreatedAt",
+		"HTTPServer":  "HTTPServer",
+		"url":         "URL",
+		"2fa":         "X2fa",
+		"in-progress": "InProgress",
+		"":            "X",
+	} {
+		if got := goName(in); got != want {
+			t.Errorf("TestGoName: goName(%q) got %q want %q", in, got, want)
+		}
+	}
+}
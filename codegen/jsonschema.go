@@ -0,0 +1,250 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonNode is a JSONSCHEMA schema or sub schema.
+type jsonNode map[string]json.RawMessage
+
+// jsonGen generates the Go types of a JSONSCHEMA schema.
+type jsonGen struct {
+	*file
+	root  jsonNode
+	types map[string]string
+}
+
+// jsonSchema declares the types of the schema and returns the name of its
+// root type.
+func (f *file) jsonSchema(schema, rootName string) (string, error) {
+	var root jsonNode
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		return "", fmt.Errorf("error parsing json schema err:%w", err)
+	}
+
+	g := &jsonGen{file: f, root: root, types: make(map[string]string)}
+	name := f.names.typeName(rootName)
+	if root.isObject() {
+		return g.object(root, name, "#", "a generated object type"), nil
+	}
+
+	idx := f.reserve()
+	f.decls[idx] = fmt.Sprintf("// %s is the root type of the schema.\ntype %s %s\n", name, name, g.typ(root, name, "#"))
+
+	return name, nil
+}
+
+// typ returns the Go type of the node at the path, declaring structs as
+// needed. The name is used if the node is an object with properties.
+func (g *jsonGen) typ(n jsonNode, name, path string) string {
+	if ref := n.str("$ref"); ref != "" {
+		return g.ref(ref)
+	}
+
+	switch n.kind() {
+	case "string":
+		if n.str("format") == "date-time" {
+			g.imports["time"] = true
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		items := n.node("items")
+		if items == nil {
+			return "[]interface{}"
+		}
+		return "[]" + g.typ(items, name+"Item", path+"/items")
+	case "object":
+		if n.isObject() {
+			return g.object(n, g.names.nestedName(path, name), path, "a generated object type")
+		}
+		if values := n.node("additionalProperties"); values != nil {
+			return "map[string]" + g.typ(values, name+"Value", path+"/additionalProperties")
+		}
+		return "map[string]interface{}"
+	}
+
+	return "interface{}"
+}
+
+// ref returns the type of a local definition. Other references are kept as
+// raw JSON.
+func (g *jsonGen) ref(ref string) string {
+	if name, ok := g.types[ref]; ok {
+		return name
+	}
+
+	var def jsonNode
+	var defName string
+	for _, prefix := range []string{"#/definitions/", "#/$defs/"} {
+		if strings.HasPrefix(ref, prefix) {
+			defName = strings.TrimPrefix(ref, prefix)
+			def = g.root.node(prefix[2 : len(prefix)-1]).node(defName)
+		}
+	}
+	if def == nil {
+		g.imports["encoding/json"] = true
+		return "json.RawMessage"
+	}
+
+	name := g.names.typeName(defName)
+	g.types[ref] = name
+	if def.isObject() {
+		return g.object(def, name, ref, "the "+defName+" definition")
+	}
+
+	idx := g.reserve()
+	g.decls[idx] = fmt.Sprintf("// %s is the %s definition.\ntype %s %s\n", name, defName, name, g.typ(def, name, ref))
+
+	return name
+}
+
+// object declares a struct named name for an object with properties at the
+// path, documented with its title if it has one.
+func (g *jsonGen) object(n jsonNode, name, path, what string) string {
+	idx := g.reserve()
+
+	required := make(map[string]bool)
+	var req []string
+	_ = json.Unmarshal(n["required"], &req)
+	for _, r := range req {
+		required[r] = true
+	}
+
+	var b strings.Builder
+	if title := n.str("title"); title != "" {
+		what = "the " + title + " object"
+	}
+	fmt.Fprintf(&b, "// %s is %s.\n", name, what)
+	if desc := n.str("description"); desc != "" {
+		b.WriteString("//\n" + comment(desc))
+	}
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+
+	properties := n.node("properties")
+	fields := make(map[string]bool)
+	for _, property := range keys(n["properties"]) {
+		prop := properties.node(property)
+
+		fieldName := goName(property)
+		for i := 2; fields[fieldName]; i++ {
+			fieldName = goName(property) + strconv.Itoa(i)
+		}
+		fields[fieldName] = true
+
+		typ := g.typ(prop, name+fieldName, path+"/properties/"+pointerEscape(property))
+		tag := property
+		if !required[property] || prop.nullable() {
+			tag += ",omitempty"
+			if !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "interface{}" && typ != "json.RawMessage" {
+				typ = "*" + typ
+			}
+		}
+
+		if desc := prop.str("description"); desc != "" {
+			b.WriteString(comment(desc))
+		}
+		fmt.Fprintf(&b, "%s %s `json:%s`\n", fieldName, typ, strconv.Quote(tag))
+	}
+	b.WriteString("}\n")
+
+	g.decls[idx] = b.String()
+
+	return name
+}
+
+// pointerEscape escapes a property name for a JSON pointer.
+func pointerEscape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+func (n jsonNode) str(key string) string {
+	var s string
+	_ = json.Unmarshal(n[key], &s)
+	return s
+}
+
+func (n jsonNode) node(key string) jsonNode {
+	var node jsonNode
+	if err := json.Unmarshal(n[key], &node); err != nil {
+		return nil
+	}
+	return node
+}
+
+// types returns the types of the node, which may be a single type or a list.
+func (n jsonNode) types() []string {
+	var typ string
+	if err := json.Unmarshal(n["type"], &typ); err == nil {
+		return []string{typ}
+	}
+	var types []string
+	_ = json.Unmarshal(n["type"], &types)
+	return types
+}
+
+// kind returns the non null type of the node, or an empty string if the node
+// has none or several.
+func (n jsonNode) kind() string {
+	var kind string
+	for _, typ := range n.types() {
+		if typ == "null" {
+			continue
+		}
+		if kind != "" {
+			return ""
+		}
+		kind = typ
+	}
+	if kind == "" && n["properties"] != nil {
+		return "object"
+	}
+	return kind
+}
+
+func (n jsonNode) nullable() bool {
+	for _, typ := range n.types() {
+		if typ == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+func (n jsonNode) isObject() bool {
+	return n.kind() == "object" && n.node("properties") != nil
+}
+
+// keys returns the keys of a JSON object in document order.
+func keys(raw json.RawMessage) []string {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+
+	var out []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return out
+		}
+		key, _ := tok.(string)
+		out = append(out, key)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return out
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,113 @@
+package codegen
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// initialisms are written in upper case in Go names, following the Go style.
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "IP": true, "JSON": true, "SQL": true,
+	"URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// goName converts a schema name such as "user_id" or "createdAt" to an
+// exported Go identifier such as "UserID" or "CreatedAt".
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range words(name) {
+		upper := strings.ToUpper(word)
+		if initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	s := b.String()
+	if s == "" {
+		return "X"
+	}
+	if !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+
+	return s
+}
+
+// words splits a name on non alphanumeric characters and lower to upper case
+// transitions.
+func words(name string) []string {
+	var out []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			out = append(out, string(current))
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+
+	return out
+}
+
+// names hands out unique Go type names.
+type names struct {
+	used map[string]string
+}
+
+func newNames() *names {
+	return &names{used: make(map[string]string)}
+}
+
+// typeName returns the Go type name of the schema type with the given full
+// name, qualifying it with its namespace if the short name is taken.
+func (n *names) typeName(fullName string) string {
+	short := fullName
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		short = fullName[i+1:]
+	}
+
+	return n.claim(fullName, goName(short), goName(strings.Replace(fullName, ".", "_", -1)))
+}
+
+// nestedName returns the Go type name of a type without a name of its own,
+// such as a nested JSONSCHEMA object, found at the path of the schema. It is
+// numbered if the name is taken.
+func (n *names) nestedName(path, name string) string {
+	return n.claim(path, name, name)
+}
+
+// claim returns the name for the owner if it is free, or else the first free
+// base name, numbered from 2.
+func (n *names) claim(owner, name, base string) string {
+	if current, ok := n.used[name]; !ok || current == owner {
+		n.used[name] = owner
+		return name
+	}
+
+	name = base
+	for i := 2; ; i++ {
+		if current, ok := n.used[name]; !ok || current == owner {
+			n.used[name] = owner
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
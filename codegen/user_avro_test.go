@@ -0,0 +1,54 @@
+// Code generated by schema_registry codegen. DO NOT EDIT.
+
+package codegen
+
+import (
+	"math/big"
+	"time"
+)
+
+// UserSchema is the schema the types were generated from.
+const UserSchema = `{"type":"record","name":"User","namespace":"com.test","doc":"A user.","fields":[
+	{"name":"user_id","type":"long"},
+	{"name":"email","type":["null","string"],"default":null,"doc":"Contact address."},
+	{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","IN_PROGRESS"]}},
+	{"name":"hash","type":{"type":"fixed","name":"MD5","size":16}},
+	{"name":"created","type":{"type":"long","logicalType":"timestamp-millis"}},
+	{"name":"price","type":{"type":"bytes","logicalType":"decimal","precision":9,"scale":2}},
+	{"name":"tags","type":{"type":"array","items":"string"}},
+	{"name":"address","type":["null",{"type":"record","name":"Address","fields":[{"name":"city","type":"string"}]}]},
+	{"name":"other","type":["int","string"]}
+]}`
+
+// User is the com.test.User record.
+//
+// A user.
+type User struct {
+	UserID int64 `avro:"user_id"`
+	// Contact address.
+	Email   *string     `avro:"email"`
+	Status  Status      `avro:"status"`
+	Hash    MD5         `avro:"hash"`
+	Created time.Time   `avro:"created"`
+	Price   *big.Rat    `avro:"price"`
+	Tags    []string    `avro:"tags"`
+	Address *Address    `avro:"address"`
+	Other   interface{} `avro:"other"`
+}
+
+// Status is the com.test.Status enum.
+type Status string
+
+// Status symbols.
+const (
+	StatusActive     Status = "ACTIVE"
+	StatusInProgress Status = "IN_PROGRESS"
+)
+
+// MD5 is the com.test.MD5 fixed.
+type MD5 [16]byte
+
+// Address is the com.test.Address record.
+type Address struct {
+	City string `avro:"city"`
+}
@@ -0,0 +1,373 @@
+package compatibility
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hamba/avro"
+	jsoniter "github.com/json-iterator/go"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/internal/avroname"
+)
+
+// CheckAvro checks an AVRO schema against previous schemas, ordered oldest
+// first, at the given compatibility level. Non transitive levels only check
+// against the last previous schema.
+func CheckAvro(level schemaregistry.CompatibilityLevel, schema string, previous ...string) (Result, error) {
+	candidate, err := parseAvro(schema, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	parsed := make([]*AvroSchema, len(previous))
+	for i := range previous {
+		if parsed[i], err = parseAvro(previous[i], nil); err != nil {
+			return Result{}, fmt.Errorf("invalid previous schema %d: %w", i, err)
+		}
+	}
+
+	return CheckAvroSchemas(level, candidate, parsed...)
+}
+
+// CheckAvroSchemas is CheckAvro with parsed schemas, such as schemas parsed
+// together with the schemas they reference.
+func CheckAvroSchemas(level schemaregistry.CompatibilityLevel, candidate *AvroSchema, previous ...*AvroSchema) (Result, error) {
+	indices, directions, err := plan(level, len(previous))
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, i := range indices {
+		existing := previous[i]
+		for _, direction := range directions {
+			reader, writer := candidate, existing
+			if direction == Forward {
+				reader, writer = existing, candidate
+			}
+			for _, inc := range AvroReadable(reader, writer) {
+				inc.Previous = i
+				inc.Direction = direction
+				result.Incompatibilities = append(result.Incompatibilities, inc)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// AvroSchema is a parsed AVRO schema together with the attributes of the
+// schema text the parser does not retain, such as aliases and enum defaults.
+type AvroSchema struct {
+	Schema avro.Schema
+
+	aliases      map[string][]string
+	fieldAliases map[string][]string
+	enumDefaults map[string]string
+}
+
+// ParseAvro parses an AVRO schema for compatibility checks. The schemas it
+// references are given dependencies first.
+func ParseAvro(schema string, references ...string) (*AvroSchema, error) {
+	return parseAvro(schema, references)
+}
+
+func parseAvro(schema string, references []string) (*AvroSchema, error) {
+	s := &AvroSchema{
+		aliases:      make(map[string][]string),
+		fieldAliases: make(map[string][]string),
+		enumDefaults: make(map[string]string),
+	}
+
+	cache := &avro.SchemaCache{}
+	for _, text := range append(references, schema) {
+		parsed, err := avro.ParseWithCache(text, "", cache)
+		if err != nil {
+			return nil, err
+		}
+
+		var raw interface{}
+		if err := jsoniter.UnmarshalFromString(text, &raw); err != nil {
+			return nil, err
+		}
+		s.Schema = parsed
+		s.collect(raw, "")
+	}
+
+	return s, nil
+}
+
+// collect walks the schema JSON recording aliases and enum defaults by full name.
+func (s *AvroSchema) collect(v interface{}, namespace string) {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, branch := range t {
+			s.collect(branch, namespace)
+		}
+	case map[string]interface{}:
+		typ, _ := t["type"].(string)
+		switch typ {
+		case "record", "error", "enum", "fixed":
+		case "array":
+			s.collect(t["items"], namespace)
+			return
+		case "map":
+			s.collect(t["values"], namespace)
+			return
+		default:
+			s.collect(t["type"], namespace)
+			return
+		}
+
+		fullName := avroname.FullName(t, namespace)
+		namespace = avroname.Namespace(fullName)
+
+		s.aliases[fullName] = qualifyAll(t["aliases"], namespace)
+		if def, ok := t["default"].(string); ok && typ == "enum" {
+			s.enumDefaults[fullName] = def
+		}
+
+		fields, _ := t["fields"].([]interface{})
+		for _, f := range fields {
+			field, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fieldName, _ := field["name"].(string)
+			s.fieldAliases[fullName+"."+fieldName] = stringsOf(field["aliases"])
+			s.collect(field["type"], namespace)
+		}
+	}
+}
+
+func qualifyAll(v interface{}, namespace string) []string {
+	names := stringsOf(v)
+	for i, name := range names {
+		names[i] = avroname.Qualify(name, namespace)
+	}
+
+	return names
+}
+
+func stringsOf(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+// AvroReadable returns the incompatibilities preventing data written with the
+// writer schema from being read with the reader schema, following the AVRO
+// schema resolution rules. Aliases and enum defaults are taken from the reader.
+func AvroReadable(reader, writer *AvroSchema) []Incompatibility {
+	c := &avroChecker{reader: reader, seen: make(map[[2]string]bool)}
+	c.check(reader.Schema, writer.Schema, "")
+
+	return c.incompatibilities
+}
+
+type avroChecker struct {
+	reader            *AvroSchema
+	seen              map[[2]string]bool
+	incompatibilities []Incompatibility
+}
+
+func (c *avroChecker) report(kind Kind, path, message string) {
+	if path == "" {
+		path = "/"
+	}
+	c.incompatibilities = append(c.incompatibilities, Incompatibility{Kind: kind, Path: path, Message: message})
+}
+
+func deref(s avro.Schema) avro.Schema {
+	if ref, ok := s.(*avro.RefSchema); ok {
+		return ref.Schema()
+	}
+
+	return s
+}
+
+func (c *avroChecker) check(reader, writer avro.Schema, path string) {
+	reader, writer = deref(reader), deref(writer)
+
+	if writer.Type() == avro.Union {
+		c.checkWriterUnion(reader, writer.(*avro.UnionSchema), path)
+		return
+	}
+	if reader.Type() == avro.Union {
+		c.checkReaderUnion(reader.(*avro.UnionSchema), writer, path)
+		return
+	}
+	if reader.Type() != writer.Type() {
+		if !promotable(writer.Type(), reader.Type()) {
+			c.report(TypeMismatch, path, "reader type: "+string(reader.Type())+" not compatible with writer type: "+string(writer.Type()))
+		}
+		return
+	}
+
+	switch r := reader.(type) {
+	case *avro.ArraySchema:
+		c.check(r.Items(), writer.(*avro.ArraySchema).Items(), path+"/items")
+	case *avro.MapSchema:
+		c.check(r.Values(), writer.(*avro.MapSchema).Values(), path+"/values")
+	case *avro.FixedSchema:
+		w := writer.(*avro.FixedSchema)
+		if !c.nameMatches(r, w, path) {
+			return
+		}
+		if r.Size() != w.Size() {
+			c.report(FixedSizeMismatch, path+"/size", "expected: "+strconv.Itoa(w.Size())+", found: "+strconv.Itoa(r.Size()))
+		}
+	case *avro.EnumSchema:
+		w := writer.(*avro.EnumSchema)
+		if !c.nameMatches(r, w, path) {
+			return
+		}
+		if _, ok := c.reader.enumDefaults[r.FullName()]; ok {
+			return
+		}
+		var missing []string
+		for _, symbol := range w.Symbols() {
+			if !contains(r.Symbols(), symbol) {
+				missing = append(missing, symbol)
+			}
+		}
+		if len(missing) > 0 {
+			c.report(MissingEnumSymbols, path+"/symbols", "["+strings.Join(missing, ", ")+"]")
+		}
+	case *avro.RecordSchema:
+		w := writer.(*avro.RecordSchema)
+		if !c.nameMatches(r, w, path) {
+			return
+		}
+		key := [2]string{r.FullName(), w.FullName()}
+		if c.seen[key] {
+			return
+		}
+		c.seen[key] = true
+		c.checkFields(r, w, path)
+	}
+}
+
+// checkWriterUnion requires every writer branch to be readable.
+func (c *avroChecker) checkWriterUnion(reader avro.Schema, writer *avro.UnionSchema, path string) {
+	for i, branch := range writer.Types() {
+		branchPath := path + "/" + strconv.Itoa(i)
+		if reader.Type() == avro.Union {
+			if !c.readerUnionMatches(reader.(*avro.UnionSchema), branch) {
+				c.report(MissingUnionBranch, branchPath, "reader union lacking writer type: "+typeName(branch))
+			}
+			continue
+		}
+		c.check(reader, branch, branchPath)
+	}
+}
+
+// checkReaderUnion requires a reader branch able to read the writer schema.
+func (c *avroChecker) checkReaderUnion(reader *avro.UnionSchema, writer avro.Schema, path string) {
+	if !c.readerUnionMatches(reader, writer) {
+		c.report(MissingUnionBranch, path, "reader union lacking writer type: "+typeName(writer))
+	}
+}
+
+func (c *avroChecker) readerUnionMatches(reader *avro.UnionSchema, writer avro.Schema) bool {
+	for _, branch := range reader.Types() {
+		// Check each branch on a copy of the recursion guard so that a failed
+		// attempt does not hide incompatibilities from later checks.
+		seen := make(map[[2]string]bool, len(c.seen))
+		for k, v := range c.seen {
+			seen[k] = v
+		}
+		sub := &avroChecker{reader: c.reader, seen: seen}
+		sub.check(branch, writer, "")
+		if len(sub.incompatibilities) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *avroChecker) checkFields(reader, writer *avro.RecordSchema, path string) {
+	for i, field := range reader.Fields() {
+		fieldPath := path + "/fields/" + strconv.Itoa(i)
+		wField := c.writerField(reader, field, writer)
+		if wField == nil {
+			if !field.HasDefault() {
+				c.report(ReaderFieldMissingDefaultValue, fieldPath, field.Name())
+			}
+			continue
+		}
+		c.check(field.Type(), wField.Type(), fieldPath+"/type")
+	}
+}
+
+// writerField finds the writer field for a reader field by name or by the
+// reader field aliases.
+func (c *avroChecker) writerField(reader *avro.RecordSchema, field *avro.Field, writer *avro.RecordSchema) *avro.Field {
+	names := append([]string{field.Name()}, c.reader.fieldAliases[reader.FullName()+"."+field.Name()]...)
+	for _, name := range names {
+		for _, wField := range writer.Fields() {
+			if wField.Name() == name {
+				return wField
+			}
+		}
+	}
+
+	return nil
+}
+
+// nameMatches compares the unqualified names of named schemas, falling back
+// to the reader aliases, and reports a mismatch.
+func (c *avroChecker) nameMatches(reader, writer avro.NamedSchema, path string) bool {
+	if reader.Name() == writer.Name() || contains(c.reader.aliases[reader.FullName()], writer.FullName()) {
+		return true
+	}
+	c.report(NameMismatch, path+"/name", "expected: "+writer.FullName())
+
+	return false
+}
+
+// promotable reports whether a writer primitive can be promoted to the reader type.
+func promotable(writer, reader avro.Type) bool {
+	switch writer {
+	case avro.Int:
+		return reader == avro.Long || reader == avro.Float || reader == avro.Double
+	case avro.Long:
+		return reader == avro.Float || reader == avro.Double
+	case avro.Float:
+		return reader == avro.Double
+	case avro.String:
+		return reader == avro.Bytes
+	case avro.Bytes:
+		return reader == avro.String
+	}
+
+	return false
+}
+
+func typeName(s avro.Schema) string {
+	s = deref(s)
+	if named, ok := s.(avro.NamedSchema); ok {
+		return named.FullName()
+	}
+
+	return string(s.Type())
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,204 @@
+package compatibility
+
+import (
+	"testing"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+const userV1 = `{"type":"record","name":"User","namespace":"com.test","fields":[
+	{"name":"name","type":"string"},
+	{"name":"age","type":"int"},
+	{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}
+]}`
+
+func kinds(r Result) []Kind {
+	var out []Kind
+	for _, i := range r.Incompatibilities {
+		out = append(out, i.Kind)
+	}
+
+	return out
+}
+
+func TestCheckAvro(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  schemaregistry.CompatibilityLevel
+		schema string
+		want   []Kind
+		path   string
+	}{
+		{
+			name:  "added field with default is backward compatible",
+			level: schemaregistry.CompatibilityFull,
+			schema: `{"type":"record","name":"User","namespace":"com.test","fields":[
+				{"name":"name","type":"string"},{"name":"age","type":"int"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}},
+				{"name":"email","type":["null","string"],"default":null}]}`,
+		},
+		{
+			name:  "added field without default",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"record","name":"User","namespace":"com.test","fields":[
+				{"name":"name","type":"string"},{"name":"age","type":"int"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}},
+				{"name":"email","type":"string"}]}`,
+			want: []Kind{ReaderFieldMissingDefaultValue},
+			path: "/fields/3",
+		},
+		{
+			name:  "int promoted to long",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"record","name":"User","namespace":"com.test","fields":[
+				{"name":"name","type":"string"},{"name":"age","type":"long"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}]}`,
+		},
+		{
+			name:  "long not readable as int",
+			level: schemaregistry.CompatibilityForward,
+			schema: `{"type":"record","name":"User","namespace":"com.test","fields":[
+				{"name":"name","type":"string"},{"name":"age","type":"long"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}]}`,
+			want: []Kind{TypeMismatch},
+			path: "/fields/1/type",
+		},
+		{
+			name:  "removed enum symbol",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"record","name":"User","namespace":"com.test","fields":[
+				{"name":"name","type":"string"},{"name":"age","type":"int"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE"]}}]}`,
+			want: []Kind{MissingEnumSymbols},
+			path: "/fields/2/type/symbols",
+		},
+		{
+			name:  "removed enum symbol with reader default",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"record","name":"User","namespace":"com.test","fields":[
+				{"name":"name","type":"string"},{"name":"age","type":"int"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","UNKNOWN"],"default":"UNKNOWN"}}]}`,
+		},
+		{
+			name:  "renamed field with alias",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"record","name":"User","namespace":"com.test","fields":[
+				{"name":"fullName","type":"string","aliases":["name"]},{"name":"age","type":"int"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}]}`,
+		},
+		{
+			name:  "renamed record with alias",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"record","name":"Person","namespace":"com.test","aliases":["User"],"fields":[
+				{"name":"name","type":"string"},{"name":"age","type":"int"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}]}`,
+		},
+		{
+			name:  "renamed record without alias",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"record","name":"Person","namespace":"com.test","fields":[
+				{"name":"name","type":"string"},{"name":"age","type":"int"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}]}`,
+			want: []Kind{NameMismatch},
+			path: "/name",
+		},
+		{
+			name:  "field widened to union",
+			level: schemaregistry.CompatibilityForward,
+			schema: `{"type":"record","name":"User","namespace":"com.test","fields":[
+				{"name":"name","type":["null","string"],"default":null},{"name":"age","type":"int"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}]}`,
+			want: []Kind{TypeMismatch},
+			path: "/fields/0/type/0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := CheckAvro(tt.level, tt.schema, userV1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := kinds(res)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, res.Messages())
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %v, got %v", tt.want, res.Messages())
+				}
+			}
+			if tt.path != "" && res.Incompatibilities[0].Path != tt.path {
+				t.Errorf("expected path %s, got %s", tt.path, res.Incompatibilities[0].Path)
+			}
+		})
+	}
+}
+
+func TestCheckAvroTransitive(t *testing.T) {
+	v1 := `{"type":"record","name":"R","fields":[{"name":"a","type":"string"}]}`
+	v2 := `{"type":"record","name":"R","fields":[{"name":"a","type":"string"},{"name":"b","type":"int","default":0}]}`
+	v3 := `{"type":"record","name":"R","fields":[{"name":"a","type":"string"},{"name":"b","type":"int"}]}`
+
+	res, err := CheckAvro(schemaregistry.CompatibilityBackward, v3, v1, v2)
+	if err != nil || !res.Compatible() {
+		t.Error("TestCheckAvroTransitive: expected compatible with latest", res.Messages(), err)
+	}
+
+	res, err = CheckAvro(schemaregistry.CompatibilityBackwardTransitive, v3, v1, v2)
+	if err != nil || res.Compatible() || res.Incompatibilities[0].Previous != 0 {
+		t.Error("TestCheckAvroTransitive: expected incompatible with first version", res.Messages(), err)
+	}
+
+	if _, err := CheckAvro("SIDEWAYS", v3, v1); err != ErrUnknownLevel {
+		t.Error("TestCheckAvroTransitive: expected ErrUnknownLevel, got", err)
+	}
+}
+
+func TestCheckAvroRecursive(t *testing.T) {
+	v1 := `{"type":"record","name":"LongList","fields":[{"name":"value","type":"long"},{"name":"next","type":["null","LongList"],"default":null}]}`
+	v2 := `{"type":"record","name":"LongList","fields":[{"name":"value","type":"long"},{"name":"next","type":["null","LongList"],"default":null},{"name":"tag","type":"string","default":""}]}`
+
+	res, err := CheckAvro(schemaregistry.CompatibilityFull, v2, v1)
+	if err != nil || !res.Compatible() {
+		t.Error("TestCheckAvroRecursive: expected compatible", res.Messages(), err)
+	}
+}
+
+func TestCheckAvroUndefinedName(t *testing.T) {
+	defining := `{"type":"record","name":"R","fields":[{"name":"a","type":"string"}]}`
+	if _, err := CheckAvro(schemaregistry.CompatibilityNone, defining); err != nil {
+		t.Fatal("TestCheckAvroUndefinedName: ", err)
+	}
+
+	undefined := `{"type":"record","name":"X","fields":[{"name":"r","type":"R"}]}`
+	if _, err := CheckAvro(schemaregistry.CompatibilityNone, undefined); err == nil {
+		t.Error("TestCheckAvroUndefinedName: undefined name resolved from an earlier schema")
+	}
+}
+
+func TestCheckAvroSchemasReferences(t *testing.T) {
+	addressV1 := `{"type":"record","name":"Address","namespace":"com.test","fields":[{"name":"city","type":"string"}]}`
+	addressV2 := `{"type":"record","name":"Address","namespace":"com.test","fields":[{"name":"city","type":"string"},{"name":"zip","type":"string"}]}`
+	user := `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"address","type":"com.test.Address"}]}`
+
+	previous, err := ParseAvro(user, addressV1)
+	if err != nil {
+		t.Fatal("TestCheckAvroSchemasReferences: ", err)
+	}
+	candidate, err := ParseAvro(user, addressV2)
+	if err != nil {
+		t.Fatal("TestCheckAvroSchemasReferences: ", err)
+	}
+	res, err := CheckAvroSchemas(schemaregistry.CompatibilityBackward, candidate, previous)
+	if err != nil {
+		t.Fatal("TestCheckAvroSchemasReferences: ", err)
+	}
+	if res.Compatible() {
+		t.Error("TestCheckAvroSchemasReferences: referenced field without default reported compatible")
+	}
+
+	if _, err := ParseAvro(user); err == nil {
+		t.Error("TestCheckAvroSchemasReferences: unresolved reference parsed")
+	}
+}
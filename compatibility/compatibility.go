@@ -0,0 +1,135 @@
+// Package compatibility checks schema compatibility locally, without a round
+// trip to the registry, following the rules the registry applies for each
+// compatibility level.
+package compatibility
+
+import (
+	"errors"
+	"strconv"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+// Kind classifies an incompatibility.
+type Kind string
+
+const (
+	NameMismatch                   Kind = "NAME_MISMATCH"
+	FixedSizeMismatch              Kind = "FIXED_SIZE_MISMATCH"
+	MissingEnumSymbols             Kind = "MISSING_ENUM_SYMBOLS"
+	ReaderFieldMissingDefaultValue Kind = "READER_FIELD_MISSING_DEFAULT_VALUE"
+	TypeMismatch                   Kind = "TYPE_MISMATCH"
+	MissingUnionBranch             Kind = "MISSING_UNION_BRANCH"
+
+	PackageChanged             Kind = "PACKAGE_CHANGED"
+	MessageRemoved             Kind = "MESSAGE_REMOVED"
+	FieldKindChanged           Kind = "FIELD_KIND_CHANGED"
+	FieldScalarKindChanged     Kind = "FIELD_SCALAR_KIND_CHANGED"
+	FieldNamedTypeChanged      Kind = "FIELD_NAMED_TYPE_CHANGED"
+	RequiredFieldAdded         Kind = "REQUIRED_FIELD_ADDED"
+	RequiredFieldRemoved       Kind = "REQUIRED_FIELD_REMOVED"
+	OneofFieldRemoved          Kind = "ONEOF_FIELD_REMOVED"
+	MultipleFieldsMovedToOneof Kind = "MULTIPLE_FIELDS_MOVED_TO_ONEOF"
+	FieldMovedToExistingOneof  Kind = "FIELD_MOVED_TO_EXISTING_ONEOF"
+
+	// Source incompatible changes, reported as warnings because the registry
+	// accepts them.
+	FieldRemovedNotReserved Kind = "FIELD_REMOVED_NOT_RESERVED"
+	ReservedFieldReused     Kind = "RESERVED_FIELD_REUSED"
+	FieldNameChanged        Kind = "FIELD_NAME_CHANGED"
+	FieldLabelChanged       Kind = "FIELD_LABEL_CHANGED"
+	EnumConstRemoved        Kind = "ENUM_CONST_REMOVED"
+	EnumConstChanged        Kind = "ENUM_CONST_CHANGED"
+)
+
+// Direction tells which way data could not be read: BACKWARD when the new
+// schema cannot read data written with a previous schema, FORWARD when a
+// previous schema cannot read data written with the new one.
+type Direction string
+
+const (
+	Backward Direction = "BACKWARD"
+	Forward  Direction = "FORWARD"
+)
+
+// Incompatibility is a single breaking change between the new schema and a
+// previous schema.
+type Incompatibility struct {
+	Kind Kind
+	// Path locates the incompatibility in the reader schema.
+	Path    string
+	Message string
+	// Previous is the index of the previous schema the new schema was checked against.
+	Previous  int
+	Direction Direction
+}
+
+// String formats the incompatibility like the registry verbose messages.
+func (i Incompatibility) String() string {
+	return "{direction:" + string(i.Direction) + ", previous:" + strconv.Itoa(i.Previous) + ", errorType:" + string(i.Kind) + ", description:" + i.Message + ", location:" + i.Path + "}"
+}
+
+// Result is the outcome of a compatibility check.
+type Result struct {
+	Incompatibilities []Incompatibility
+	// Warnings are changes the registry accepts but that break generated code
+	// or risk misreading old data, such as removing a field without reserving it.
+	Warnings []Incompatibility
+}
+
+// Compatible reports whether no incompatibilities were found.
+func (r Result) Compatible() bool {
+	return len(r.Incompatibilities) == 0
+}
+
+// Messages returns the incompatibilities formatted as strings.
+func (r Result) Messages() []string {
+	messages := make([]string, 0, len(r.Incompatibilities))
+	for _, i := range r.Incompatibilities {
+		messages = append(messages, i.String())
+	}
+
+	return messages
+}
+
+// ErrUnknownLevel is returned for an unsupported compatibility level.
+var ErrUnknownLevel = errors.New("unknown compatibility level")
+
+// plan resolves the previous schemas to check against and the directions to
+// check for a compatibility level, given the number of previous schemas
+// ordered oldest first.
+func plan(level schemaregistry.CompatibilityLevel, previous int) ([]int, []Direction, error) {
+	var directions []Direction
+	transitive := false
+	switch level {
+	case schemaregistry.CompatibilityNone:
+		return nil, nil, nil
+	case schemaregistry.CompatibilityBackward:
+		directions = []Direction{Backward}
+	case schemaregistry.CompatibilityBackwardTransitive:
+		directions, transitive = []Direction{Backward}, true
+	case schemaregistry.CompatibilityForward:
+		directions = []Direction{Forward}
+	case schemaregistry.CompatibilityForwardTransitive:
+		directions, transitive = []Direction{Forward}, true
+	case schemaregistry.CompatibilityFull:
+		directions = []Direction{Backward, Forward}
+	case schemaregistry.CompatibilityFullTransitive:
+		directions, transitive = []Direction{Backward, Forward}, true
+	default:
+		return nil, nil, ErrUnknownLevel
+	}
+
+	if previous == 0 {
+		return nil, directions, nil
+	}
+	if !transitive {
+		return []int{previous - 1}, directions, nil
+	}
+	indices := make([]int, previous)
+	for i := range indices {
+		indices[i] = i
+	}
+
+	return indices, directions, nil
+}
@@ -0,0 +1,559 @@
+package compatibility
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+// JSON Schema incompatibility kinds, named after the registry differences.
+const (
+	TypeNarrowed                          Kind = "TYPE_NARROWED"
+	EnumArrayNarrowed                     Kind = "ENUM_ARRAY_NARROWED"
+	ConstChanged                          Kind = "CONST_CHANGED"
+	RequiredAttributeAdded                Kind = "REQUIRED_ATTRIBUTE_ADDED"
+	AdditionalPropertiesRemoved           Kind = "ADDITIONAL_PROPERTIES_REMOVED"
+	AdditionalPropertiesNarrowed          Kind = "ADDITIONAL_PROPERTIES_NARROWED"
+	PropertyAddedToOpenContentModel       Kind = "PROPERTY_ADDED_TO_OPEN_CONTENT_MODEL"
+	PropertyRemovedFromClosedContentModel Kind = "PROPERTY_REMOVED_FROM_CLOSED_CONTENT_MODEL"
+	MaximumAdded                          Kind = "MAXIMUM_ADDED"
+	MaximumDecreased                      Kind = "MAXIMUM_DECREASED"
+	MinimumAdded                          Kind = "MINIMUM_ADDED"
+	MinimumIncreased                      Kind = "MINIMUM_INCREASED"
+	ExclusiveMaximumAdded                 Kind = "EXCLUSIVE_MAXIMUM_ADDED"
+	ExclusiveMaximumDecreased             Kind = "EXCLUSIVE_MAXIMUM_DECREASED"
+	ExclusiveMinimumAdded                 Kind = "EXCLUSIVE_MINIMUM_ADDED"
+	ExclusiveMinimumIncreased             Kind = "EXCLUSIVE_MINIMUM_INCREASED"
+	MultipleOfAdded                       Kind = "MULTIPLE_OF_ADDED"
+	MultipleOfChanged                     Kind = "MULTIPLE_OF_CHANGED"
+	MaxLengthAdded                        Kind = "MAX_LENGTH_ADDED"
+	MaxLengthDecreased                    Kind = "MAX_LENGTH_DECREASED"
+	MinLengthAdded                        Kind = "MIN_LENGTH_ADDED"
+	MinLengthIncreased                    Kind = "MIN_LENGTH_INCREASED"
+	PatternAdded                          Kind = "PATTERN_ADDED"
+	PatternChanged                        Kind = "PATTERN_CHANGED"
+	MaxItemsAdded                         Kind = "MAX_ITEMS_ADDED"
+	MaxItemsDecreased                     Kind = "MAX_ITEMS_DECREASED"
+	MinItemsAdded                         Kind = "MIN_ITEMS_ADDED"
+	MinItemsIncreased                     Kind = "MIN_ITEMS_INCREASED"
+	UniqueItemsAdded                      Kind = "UNIQUE_ITEMS_ADDED"
+	MaxPropertiesAdded                    Kind = "MAX_PROPERTIES_ADDED"
+	MaxPropertiesDecreased                Kind = "MAX_PROPERTIES_DECREASED"
+	MinPropertiesAdded                    Kind = "MIN_PROPERTIES_ADDED"
+	MinPropertiesIncreased                Kind = "MIN_PROPERTIES_INCREASED"
+	SumTypeNarrowed                       Kind = "SUM_TYPE_NARROWED"
+	ProductTypeExtended                   Kind = "PRODUCT_TYPE_EXTENDED"
+	CombinedTypeChanged                   Kind = "COMBINED_TYPE_CHANGED"
+)
+
+// CheckJSONSchema checks a JSONSCHEMA schema against previous schemas, ordered
+// oldest first, at the given compatibility level. A schema can read data
+// written with another when it accepts every document the other accepts;
+// incompatibility paths are JSON pointers into the reading schema.
+func CheckJSONSchema(level schemaregistry.CompatibilityLevel, schema string, previous ...string) (Result, error) {
+	indices, directions, err := plan(level, len(previous))
+	if err != nil {
+		return Result{}, err
+	}
+
+	candidate, err := parseJSONSchema(schema)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	var result Result
+	for _, i := range indices {
+		existing, err := parseJSONSchema(previous[i])
+		if err != nil {
+			return Result{}, fmt.Errorf("invalid previous schema %d: %w", i, err)
+		}
+
+		for _, direction := range directions {
+			reader, writer := candidate, existing
+			if direction == Forward {
+				reader, writer = existing, candidate
+			}
+			d := &jsonDiff{writerRoot: writer, readerRoot: reader, seen: map[[2]string]bool{}}
+			d.schema(writer, reader, "#")
+			for _, inc := range d.incompatibilities {
+				inc.Previous = i
+				inc.Direction = direction
+				result.Incompatibilities = append(result.Incompatibilities, inc)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func parseJSONSchema(schema string) (interface{}, error) {
+	var v interface{}
+	if err := jsoniter.UnmarshalFromString(schema, &v); err != nil {
+		return nil, err
+	}
+	switch v.(type) {
+	case map[string]interface{}, bool:
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("schema must be an object or a boolean")
+}
+
+// jsonDiff compares the schema data was written with to the schema reading it.
+type jsonDiff struct {
+	writerRoot, readerRoot interface{}
+	seen                   map[[2]string]bool
+	incompatibilities      []Incompatibility
+}
+
+func (d *jsonDiff) report(kind Kind, path, message string) {
+	d.incompatibilities = append(d.incompatibilities, Incompatibility{Kind: kind, Path: path, Message: message})
+}
+
+// resolve follows local $ref pointers such as #/definitions/Name.
+func resolve(root interface{}, v interface{}) (interface{}, string) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v, ""
+	}
+	ref, ok := m["$ref"].(string)
+	if !ok || !strings.HasPrefix(ref, "#") {
+		return v, ""
+	}
+
+	cur := root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return v, ref
+		}
+		if cur, ok = obj[token]; !ok {
+			return v, ref
+		}
+	}
+
+	return cur, ref
+}
+
+// schema reports the ways the reader rejects documents the writer accepts.
+func (d *jsonDiff) schema(writer, reader interface{}, path string) {
+	writer, wRef := resolve(d.writerRoot, writer)
+	reader, rRef := resolve(d.readerRoot, reader)
+	if wRef != "" && rRef != "" {
+		key := [2]string{wRef, rRef}
+		if d.seen[key] {
+			return
+		}
+		d.seen[key] = true
+	}
+
+	if rb, ok := reader.(bool); ok {
+		if !rb && writer != false {
+			d.report(TypeNarrowed, path, "reader schema rejects every document")
+		}
+		return
+	}
+	r, _ := reader.(map[string]interface{})
+	if wb, ok := writer.(bool); ok {
+		if !wb {
+			return
+		}
+		writer = map[string]interface{}{}
+	}
+	w, _ := writer.(map[string]interface{})
+
+	d.types(w, r, path)
+	d.enum(w, r, path)
+	d.numbers(w, r, path)
+	d.strings(w, r, path)
+	d.arrays(w, r, path)
+	d.objects(w, r, path)
+	d.combined(w, r, path)
+}
+
+func typeSet(m map[string]interface{}) map[string]bool {
+	switch t := m["type"].(type) {
+	case string:
+		return map[string]bool{t: true}
+	case []interface{}:
+		out := map[string]bool{}
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				out[s] = true
+			}
+		}
+		return out
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+
+	return out
+}
+
+func (d *jsonDiff) types(w, r map[string]interface{}, path string) {
+	rTypes := typeSet(r)
+	if rTypes == nil {
+		return
+	}
+	wTypes := typeSet(w)
+	if wTypes == nil {
+		d.report(TypeNarrowed, path+"/type", "type restricted to "+strings.Join(sortedKeys(rTypes), ", "))
+		return
+	}
+
+	var missing []string
+	for _, t := range sortedKeys(wTypes) {
+		if rTypes[t] || (t == "integer" && rTypes["number"]) {
+			continue
+		}
+		missing = append(missing, t)
+	}
+	if len(missing) > 0 {
+		d.report(TypeNarrowed, path+"/type", "type no longer accepts "+strings.Join(missing, ", "))
+	}
+}
+
+func encode(v interface{}) string {
+	s, _ := jsoniter.ConfigCompatibleWithStandardLibrary.MarshalToString(v)
+	return s
+}
+
+func (d *jsonDiff) enum(w, r map[string]interface{}, path string) {
+	if rConst, ok := r["const"]; ok {
+		if wConst, ok := w["const"]; !ok || encode(wConst) != encode(rConst) {
+			d.report(ConstChanged, path+"/const", "const "+encode(rConst)+" restricts previously accepted values")
+		}
+	}
+
+	rEnum, ok := r["enum"].([]interface{})
+	if !ok {
+		return
+	}
+	accepted := map[string]bool{}
+	for _, v := range rEnum {
+		accepted[encode(v)] = true
+	}
+
+	wEnum, ok := w["enum"].([]interface{})
+	if !ok {
+		d.report(EnumArrayNarrowed, path+"/enum", "enum added")
+		return
+	}
+	var missing []string
+	for _, v := range wEnum {
+		if !accepted[encode(v)] {
+			missing = append(missing, encode(v))
+		}
+	}
+	if len(missing) > 0 {
+		d.report(EnumArrayNarrowed, path+"/enum", "enum no longer accepts "+strings.Join(missing, ", "))
+	}
+}
+
+func number(m map[string]interface{}, key string) (float64, bool) {
+	v, ok := m[key].(float64)
+	return v, ok
+}
+
+// divides reports whether d divides n. The numbers are compared as the
+// decimals they were written as, so that 0.1 divides 0.3 although their
+// float64 values do not.
+func divides(d, n float64) bool {
+	rd, ok := new(big.Rat).SetString(strconv.FormatFloat(d, 'g', -1, 64))
+	if !ok || rd.Sign() == 0 {
+		return false
+	}
+	rn, ok := new(big.Rat).SetString(strconv.FormatFloat(n, 'g', -1, 64))
+	if !ok {
+		return false
+	}
+
+	return rn.Quo(rn, rd).IsInt()
+}
+
+// upperBound reports an upper bound added or lowered in the reader.
+func (d *jsonDiff) upperBound(w, r map[string]interface{}, key string, added, decreased Kind, path string) {
+	rv, ok := number(r, key)
+	if !ok {
+		return
+	}
+	wv, ok := number(w, key)
+	switch {
+	case !ok:
+		d.report(added, path+"/"+key, key+" added")
+	case rv < wv:
+		d.report(decreased, path+"/"+key, key+" decreased")
+	}
+}
+
+// lowerBound reports a lower bound added or raised in the reader.
+func (d *jsonDiff) lowerBound(w, r map[string]interface{}, key string, added, increased Kind, path string) {
+	rv, ok := number(r, key)
+	if !ok {
+		return
+	}
+	wv, ok := number(w, key)
+	switch {
+	case !ok:
+		d.report(added, path+"/"+key, key+" added")
+	case rv > wv:
+		d.report(increased, path+"/"+key, key+" increased")
+	}
+}
+
+func (d *jsonDiff) numbers(w, r map[string]interface{}, path string) {
+	d.upperBound(w, r, "maximum", MaximumAdded, MaximumDecreased, path)
+	d.lowerBound(w, r, "minimum", MinimumAdded, MinimumIncreased, path)
+	d.upperBound(w, r, "exclusiveMaximum", ExclusiveMaximumAdded, ExclusiveMaximumDecreased, path)
+	d.lowerBound(w, r, "exclusiveMinimum", ExclusiveMinimumAdded, ExclusiveMinimumIncreased, path)
+
+	rm, ok := number(r, "multipleOf")
+	if !ok {
+		return
+	}
+	wm, ok := number(w, "multipleOf")
+	switch {
+	case !ok:
+		d.report(MultipleOfAdded, path+"/multipleOf", "multipleOf added")
+	case !divides(rm, wm):
+		d.report(MultipleOfChanged, path+"/multipleOf", "multipleOf changed to a value that does not divide the previous one")
+	}
+}
+
+func (d *jsonDiff) strings(w, r map[string]interface{}, path string) {
+	d.upperBound(w, r, "maxLength", MaxLengthAdded, MaxLengthDecreased, path)
+	d.lowerBound(w, r, "minLength", MinLengthAdded, MinLengthIncreased, path)
+
+	rp, ok := r["pattern"].(string)
+	if !ok {
+		return
+	}
+	wp, ok := w["pattern"].(string)
+	switch {
+	case !ok:
+		d.report(PatternAdded, path+"/pattern", "pattern added")
+	case wp != rp:
+		d.report(PatternChanged, path+"/pattern", "pattern changed")
+	}
+}
+
+func (d *jsonDiff) arrays(w, r map[string]interface{}, path string) {
+	d.upperBound(w, r, "maxItems", MaxItemsAdded, MaxItemsDecreased, path)
+	d.lowerBound(w, r, "minItems", MinItemsAdded, MinItemsIncreased, path)
+	if r["uniqueItems"] == true && w["uniqueItems"] != true {
+		d.report(UniqueItemsAdded, path+"/uniqueItems", "uniqueItems added")
+	}
+
+	rItems, ok := r["items"]
+	if !ok {
+		return
+	}
+	wItems, ok := w["items"]
+	if !ok {
+		wItems = true
+	}
+	// Tuple validation is compared position by position.
+	if rTuple, ok := rItems.([]interface{}); ok {
+		wTuple, _ := wItems.([]interface{})
+		for i, item := range rTuple {
+			var wItem interface{} = true
+			if i < len(wTuple) {
+				wItem = wTuple[i]
+			}
+			d.schema(wItem, item, fmt.Sprintf("%s/items/%d", path, i))
+		}
+		return
+	}
+	if _, ok := wItems.([]interface{}); ok {
+		wItems = true
+	}
+	d.schema(wItems, rItems, path+"/items")
+}
+
+// additional returns the additionalProperties schema, true when absent.
+func additional(m map[string]interface{}) interface{} {
+	v, ok := m["additionalProperties"]
+	if !ok {
+		return true
+	}
+
+	return v
+}
+
+func properties(m map[string]interface{}) map[string]interface{} {
+	props, _ := m["properties"].(map[string]interface{})
+	return props
+}
+
+func required(m map[string]interface{}) map[string]bool {
+	out := map[string]bool{}
+	list, _ := m["required"].([]interface{})
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out[s] = true
+		}
+	}
+
+	return out
+}
+
+func sortedNames(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+
+	return out
+}
+
+func (d *jsonDiff) objects(w, r map[string]interface{}, path string) {
+	d.upperBound(w, r, "maxProperties", MaxPropertiesAdded, MaxPropertiesDecreased, path)
+	d.lowerBound(w, r, "minProperties", MinPropertiesAdded, MinPropertiesIncreased, path)
+
+	wProps, rProps := properties(w), properties(r)
+	wAdditional, rAdditional := additional(w), additional(r)
+	wRequired, rRequired := required(w), required(r)
+
+	for _, name := range sortedKeys(rRequired) {
+		if !wRequired[name] {
+			d.report(RequiredAttributeAdded, path+"/required", "property "+name+" is now required")
+		}
+	}
+
+	switch {
+	case rAdditional == false && wAdditional != false:
+		d.report(AdditionalPropertiesRemoved, path+"/additionalProperties", "content model closed")
+	case rAdditional != false && rAdditional != true && wAdditional != false:
+		d.schema(wAdditional, rAdditional, path+"/additionalProperties")
+	}
+
+	for _, name := range sortedNames(rProps) {
+		propPath := path + "/properties/" + escapePointer(name)
+		wProp, ok := wProps[name]
+		if ok {
+			d.schema(wProp, rProps[name], propPath)
+			continue
+		}
+		// The writer accepted the property as an additional property.
+		if wAdditional == false {
+			continue
+		}
+		sub := &jsonDiff{writerRoot: d.writerRoot, readerRoot: d.readerRoot, seen: d.seen}
+		sub.schema(wAdditional, rProps[name], propPath)
+		if len(sub.incompatibilities) > 0 {
+			d.report(PropertyAddedToOpenContentModel, propPath, "property "+name+" added to an open content model")
+		}
+	}
+
+	for _, name := range sortedNames(wProps) {
+		if _, ok := rProps[name]; ok {
+			continue
+		}
+		propPath := path + "/properties/" + escapePointer(name)
+		if rAdditional == false {
+			d.report(PropertyRemovedFromClosedContentModel, propPath, "property "+name+" removed from a closed content model")
+			continue
+		}
+		if rAdditional != true {
+			d.schema(wProps[name], rAdditional, propPath)
+		}
+	}
+}
+
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// combined compares oneOf, anyOf and allOf. Every writer alternative must be
+// readable by a reader alternative, and the reader must not add constraints.
+func (d *jsonDiff) combined(w, r map[string]interface{}, path string) {
+	for _, key := range []string{"oneOf", "anyOf", "allOf"} {
+		rList, rOK := r[key].([]interface{})
+		wList, wOK := w[key].([]interface{})
+		if !rOK && !wOK {
+			continue
+		}
+		keyPath := path + "/" + key
+		switch {
+		case !rOK:
+			// Dropping a combination only widens what is accepted.
+			continue
+		case !wOK:
+			if combinedKey(w) != "" {
+				d.report(CombinedTypeChanged, keyPath, "combined type changed from "+combinedKey(w)+" to "+key)
+				continue
+			}
+			if key == "allOf" {
+				d.report(ProductTypeExtended, keyPath, "allOf added")
+			} else {
+				d.report(SumTypeNarrowed, keyPath, key+" added")
+			}
+			continue
+		}
+
+		if key == "allOf" {
+			for i, rItem := range rList {
+				if d.matchAny(wList, rItem, false) < 0 {
+					d.report(ProductTypeExtended, fmt.Sprintf("%s/%d", keyPath, i), "allOf constraint added")
+				}
+			}
+			continue
+		}
+		for i, wItem := range wList {
+			if d.matchAny(rList, wItem, true) < 0 {
+				d.report(SumTypeNarrowed, fmt.Sprintf("%s/%d", keyPath, i), key+" no longer accepts alternative "+fmt.Sprint(i))
+			}
+		}
+	}
+}
+
+func combinedKey(m map[string]interface{}) string {
+	for _, key := range []string{"oneOf", "anyOf", "allOf"} {
+		if _, ok := m[key]; ok {
+			return key
+		}
+	}
+
+	return ""
+}
+
+// matchAny returns the index of the first item of list compatible with the
+// schema, taking list items as readers when asReader is set, or -1.
+func (d *jsonDiff) matchAny(list []interface{}, schema interface{}, asReader bool) int {
+	for i, item := range list {
+		seen := make(map[[2]string]bool, len(d.seen))
+		for k, v := range d.seen {
+			seen[k] = v
+		}
+		sub := &jsonDiff{writerRoot: d.writerRoot, readerRoot: d.readerRoot, seen: seen}
+		if asReader {
+			sub.schema(schema, item, "")
+		} else {
+			sub.schema(item, schema, "")
+		}
+		if len(sub.incompatibilities) == 0 {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,179 @@
+package compatibility
+
+import (
+	"testing"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+const personV1 = `{
+  "type": "object",
+  "properties": {
+    "name": {"type": "string", "maxLength": 50},
+    "age": {"type": "integer", "minimum": 0},
+    "status": {"enum": ["ACTIVE", "INACTIVE"]},
+    "tags": {"type": "array", "items": {"type": "string"}}
+  },
+  "required": ["name"],
+  "additionalProperties": false
+}`
+
+func TestCheckJSONSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  schemaregistry.CompatibilityLevel
+		schema string
+		want   Kind
+		path   string
+	}{
+		{
+			name:  "optional property added to closed model",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"object","properties":{
+				"name":{"type":"string","maxLength":50},"age":{"type":"integer","minimum":0},
+				"status":{"enum":["ACTIVE","INACTIVE"]},"tags":{"type":"array","items":{"type":"string"}},
+				"email":{"type":"string"}},"required":["name"],"additionalProperties":false}`,
+		},
+		{
+			name:  "integer widened to number and enum extended",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"object","properties":{
+				"name":{"type":"string","maxLength":80},"age":{"type":"number"},
+				"status":{"enum":["ACTIVE","INACTIVE","BANNED"]},"tags":{"type":"array","items":{"type":"string"}}},
+				"required":["name"],"additionalProperties":false}`,
+		},
+		{
+			name:  "required property added",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"object","properties":{
+				"name":{"type":"string","maxLength":50},"age":{"type":"integer","minimum":0},
+				"status":{"enum":["ACTIVE","INACTIVE"]},"tags":{"type":"array","items":{"type":"string"}}},
+				"required":["name","age"],"additionalProperties":false}`,
+			want: RequiredAttributeAdded,
+			path: "#/required",
+		},
+		{
+			name:  "enum narrowed",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"object","properties":{
+				"name":{"type":"string","maxLength":50},"age":{"type":"integer","minimum":0},
+				"status":{"enum":["ACTIVE"]},"tags":{"type":"array","items":{"type":"string"}}},
+				"required":["name"],"additionalProperties":false}`,
+			want: EnumArrayNarrowed,
+			path: "#/properties/status/enum",
+		},
+		{
+			name:  "max length decreased",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"object","properties":{
+				"name":{"type":"string","maxLength":20},"age":{"type":"integer","minimum":0},
+				"status":{"enum":["ACTIVE","INACTIVE"]},"tags":{"type":"array","items":{"type":"string"}}},
+				"required":["name"],"additionalProperties":false}`,
+			want: MaxLengthDecreased,
+			path: "#/properties/name/maxLength",
+		},
+		{
+			name:  "property removed from closed model",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"object","properties":{
+				"name":{"type":"string","maxLength":50},"age":{"type":"integer","minimum":0},
+				"status":{"enum":["ACTIVE","INACTIVE"]}},
+				"required":["name"],"additionalProperties":false}`,
+			want: PropertyRemovedFromClosedContentModel,
+			path: "#/properties/tags",
+		},
+		{
+			name:  "array items narrowed",
+			level: schemaregistry.CompatibilityBackward,
+			schema: `{"type":"object","properties":{
+				"name":{"type":"string","maxLength":50},"age":{"type":"integer","minimum":0},
+				"status":{"enum":["ACTIVE","INACTIVE"]},"tags":{"type":"array","items":{"type":"integer"}}},
+				"required":["name"],"additionalProperties":false}`,
+			want: TypeNarrowed,
+			path: "#/properties/tags/items/type",
+		},
+		{
+			name:  "optional property added to closed model is not forward compatible when removed",
+			level: schemaregistry.CompatibilityForward,
+			schema: `{"type":"object","properties":{
+				"name":{"type":"string","maxLength":50},"age":{"type":"integer","minimum":0},
+				"status":{"enum":["ACTIVE","INACTIVE"]},"tags":{"type":"array","items":{"type":"string"}},
+				"email":{"type":"string"}},"required":["name"],"additionalProperties":false}`,
+			want: PropertyRemovedFromClosedContentModel,
+			path: "#/properties/email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := CheckJSONSchema(tt.level, tt.schema, personV1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if !res.Compatible() {
+					t.Fatalf("expected compatible, got %v", res.Messages())
+				}
+				return
+			}
+			if len(res.Incompatibilities) != 1 || res.Incompatibilities[0].Kind != tt.want || res.Incompatibilities[0].Path != tt.path {
+				t.Fatalf("expected %s at %s, got %v", tt.want, tt.path, res.Messages())
+			}
+		})
+	}
+}
+
+func TestCheckJSONSchemaOpenContentModel(t *testing.T) {
+	v1 := `{"type":"object","properties":{"id":{"type":"string"}}}`
+	v2 := `{"type":"object","properties":{"id":{"type":"string"},"count":{"type":"integer"}}}`
+
+	res, err := CheckJSONSchema(schemaregistry.CompatibilityBackward, v2, v1)
+	if err != nil {
+		t.Fatal("TestCheckJSONSchemaOpenContentModel: ", err)
+	}
+	if len(res.Incompatibilities) != 1 || res.Incompatibilities[0].Kind != PropertyAddedToOpenContentModel {
+		t.Error("TestCheckJSONSchemaOpenContentModel: expected PROPERTY_ADDED_TO_OPEN_CONTENT_MODEL, got", res.Messages())
+	}
+
+	res, err = CheckJSONSchema(schemaregistry.CompatibilityForward, v2, v1)
+	if err != nil || !res.Compatible() {
+		t.Error("TestCheckJSONSchemaOpenContentModel: expected forward compatible", res.Messages(), err)
+	}
+}
+
+func TestCheckJSONSchemaCombined(t *testing.T) {
+	v1 := `{"oneOf":[{"type":"string"},{"type":"integer"}]}`
+	v2 := `{"oneOf":[{"type":"string"},{"type":"number"},{"type":"boolean"}]}`
+	v3 := `{"oneOf":[{"type":"string"}]}`
+
+	if res, err := CheckJSONSchema(schemaregistry.CompatibilityBackward, v2, v1); err != nil || !res.Compatible() {
+		t.Error("TestCheckJSONSchemaCombined: expected extended oneOf to be compatible", res.Messages(), err)
+	}
+	res, err := CheckJSONSchema(schemaregistry.CompatibilityBackward, v3, v1)
+	if err != nil || len(res.Incompatibilities) != 1 || res.Incompatibilities[0].Path != "#/oneOf/1" {
+		t.Error("TestCheckJSONSchemaCombined: expected narrowed oneOf, got", res.Messages(), err)
+	}
+}
+
+func TestCheckJSONSchemaRefs(t *testing.T) {
+	v1 := `{"definitions":{"id":{"type":"string"}},"type":"object","properties":{"id":{"$ref":"#/definitions/id"}}}`
+	v2 := `{"definitions":{"id":{"type":"string","pattern":"^[a-z]+$"}},"type":"object","properties":{"id":{"$ref":"#/definitions/id"}}}`
+
+	res, err := CheckJSONSchema(schemaregistry.CompatibilityBackward, v2, v1)
+	if err != nil || len(res.Incompatibilities) != 1 || res.Incompatibilities[0].Kind != PatternAdded {
+		t.Error("TestCheckJSONSchemaRefs: expected PATTERN_ADDED, got", res.Messages(), err)
+	}
+}
+
+func TestCheckJSONSchemaMultipleOf(t *testing.T) {
+	v1 := `{"type":"number","multipleOf":0.3}`
+
+	res, err := CheckJSONSchema(schemaregistry.CompatibilityBackward, `{"type":"number","multipleOf":0.1}`, v1)
+	if err != nil || !res.Compatible() {
+		t.Error("TestCheckJSONSchemaMultipleOf: expected multipleOf dividing the previous one to be compatible", res.Messages(), err)
+	}
+	res, err = CheckJSONSchema(schemaregistry.CompatibilityBackward, `{"type":"number","multipleOf":0.2}`, v1)
+	if err != nil || len(res.Incompatibilities) != 1 || res.Incompatibilities[0].Kind != MultipleOfChanged {
+		t.Error("TestCheckJSONSchemaMultipleOf: expected MULTIPLE_OF_CHANGED, got", res.Messages(), err)
+	}
+}
@@ -0,0 +1,262 @@
+package compatibility
+
+import (
+	"fmt"
+	"strconv"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/internal/protoschema"
+)
+
+// ProtobufSchema is the text of a .proto schema and of the schemas it imports.
+type ProtobufSchema struct {
+	Schema string
+	// References maps import paths to the text of the imported schemas.
+	References map[string]string
+}
+
+// CheckProtobuf checks a PROTOBUF schema against previous schemas, ordered
+// oldest first, at the given compatibility level. Incompatibilities follow the
+// registry rules; changes the registry accepts but that break generated code
+// or risk misreading data, such as removing a field without reserving its
+// number, are reported as warnings.
+func CheckProtobuf(level schemaregistry.CompatibilityLevel, schema ProtobufSchema, previous ...ProtobufSchema) (Result, error) {
+	indices, directions, err := plan(level, len(previous))
+	if err != nil {
+		return Result{}, err
+	}
+
+	candidate, err := protoschema.Parse(schema.Schema, schema.References)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	var result Result
+	for _, i := range indices {
+		existing, err := protoschema.Parse(previous[i].Schema, previous[i].References)
+		if err != nil {
+			return Result{}, fmt.Errorf("invalid previous schema %d: %w", i, err)
+		}
+
+		for _, direction := range directions {
+			original, update := existing, candidate
+			if direction == Forward {
+				original, update = candidate, existing
+			}
+			d := &protoDiff{}
+			d.files(original, update)
+			for _, inc := range d.incompatibilities {
+				inc.Previous = i
+				inc.Direction = direction
+				result.Incompatibilities = append(result.Incompatibilities, inc)
+			}
+		}
+
+		d := &protoDiff{}
+		d.files(existing, candidate)
+		for _, w := range d.warnings {
+			w.Previous = i
+			w.Direction = Backward
+			result.Warnings = append(result.Warnings, w)
+		}
+	}
+
+	return result, nil
+}
+
+// protoDiff compares an original schema, used to write data, with an update
+// used to read it.
+type protoDiff struct {
+	incompatibilities []Incompatibility
+	warnings          []Incompatibility
+}
+
+func (d *protoDiff) incompatible(kind Kind, path, message string) {
+	d.incompatibilities = append(d.incompatibilities, Incompatibility{Kind: kind, Path: path, Message: message})
+}
+
+func (d *protoDiff) warn(kind Kind, path, message string) {
+	d.warnings = append(d.warnings, Incompatibility{Kind: kind, Path: path, Message: message})
+}
+
+func (d *protoDiff) files(original, update *protoschema.File) {
+	if original.Package != update.Package {
+		d.incompatible(PackageChanged, "#/", "package changed from '"+original.Package+"' to '"+update.Package+"'")
+	}
+
+	for _, name := range original.MessageNames() {
+		o, _ := original.Message(name)
+		u, ok := update.Message(name)
+		if !ok {
+			d.incompatible(MessageRemoved, "#/"+name, "message "+name+" removed")
+			continue
+		}
+		d.message(o, u)
+	}
+
+	for _, name := range original.EnumNames() {
+		o, _ := original.Enum(name)
+		if u, ok := update.Enum(name); ok {
+			d.enum(o, u)
+		}
+	}
+}
+
+func fieldsByNumber(m *protoschema.Message) map[int]*protoschema.Field {
+	out := make(map[int]*protoschema.Field, len(m.Fields))
+	for _, f := range m.Fields {
+		out[f.Number] = f
+	}
+
+	return out
+}
+
+func reserved(ranges []protoschema.Range, names []string, number int, name string) bool {
+	for _, r := range ranges {
+		if r.Contains(number) {
+			return true
+		}
+	}
+
+	return contains(names, name)
+}
+
+func hasOneof(m *protoschema.Message, name string) bool {
+	for _, o := range m.Oneofs {
+		if o.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *protoDiff) message(o, u *protoschema.Message) {
+	oFields, uFields := fieldsByNumber(o), fieldsByNumber(u)
+	path := "#/" + o.FullName
+
+	for _, of := range o.Fields {
+		fieldPath := path + "/" + strconv.Itoa(of.Number)
+		uf, ok := uFields[of.Number]
+		if !ok {
+			switch {
+			case of.Required:
+				d.incompatible(RequiredFieldRemoved, fieldPath, "required field "+of.Name+" removed")
+			case of.Oneof != "":
+				d.incompatible(OneofFieldRemoved, fieldPath, "field "+of.Name+" removed from oneof "+of.Oneof)
+			}
+			if !reserved(u.ReservedRanges, u.ReservedNames, of.Number, "") {
+				d.warn(FieldRemovedNotReserved, fieldPath, "field "+of.Name+" removed without reserving number "+strconv.Itoa(of.Number))
+			}
+			continue
+		}
+		d.field(of, uf, fieldPath)
+	}
+
+	movedToNewOneof := map[string]int{}
+	for _, uf := range u.Fields {
+		fieldPath := path + "/" + strconv.Itoa(uf.Number)
+		of, ok := oFields[uf.Number]
+		if !ok {
+			if uf.Required {
+				d.incompatible(RequiredFieldAdded, fieldPath, "required field "+uf.Name+" added")
+			}
+			if reserved(o.ReservedRanges, o.ReservedNames, uf.Number, uf.Name) {
+				d.warn(ReservedFieldReused, fieldPath, "field "+uf.Name+" reuses reserved number or name")
+			}
+			continue
+		}
+
+		switch {
+		case of.Oneof == "" && uf.Oneof != "":
+			if hasOneof(o, uf.Oneof) {
+				d.incompatible(FieldMovedToExistingOneof, fieldPath, "field "+uf.Name+" moved to existing oneof "+uf.Oneof)
+			} else {
+				movedToNewOneof[uf.Oneof]++
+			}
+		case of.Oneof != "" && uf.Oneof != of.Oneof:
+			d.incompatible(OneofFieldRemoved, fieldPath, "field "+of.Name+" removed from oneof "+of.Oneof)
+		}
+	}
+	for _, oneof := range u.Oneofs {
+		if movedToNewOneof[oneof.Name] > 1 {
+			d.incompatible(MultipleFieldsMovedToOneof, path+"/"+oneof.Name, "multiple fields moved to new oneof "+oneof.Name)
+		}
+	}
+}
+
+// wireGroups holds the scalar types that share an encoding and can be changed
+// into each other.
+var wireGroups = map[string]int{
+	"int32": 1, "uint32": 1, "int64": 1, "uint64": 1, "bool": 1,
+	"sint32": 2, "sint64": 2,
+	"fixed32": 3, "sfixed32": 3,
+	"fixed64": 4, "sfixed64": 4,
+	"string": 5, "bytes": 5,
+}
+
+func named(kind protoschema.Kind) bool {
+	return kind == protoschema.MessageKind || kind == protoschema.EnumKind || kind == protoschema.Unresolved
+}
+
+func (d *protoDiff) field(o, u *protoschema.Field, path string) {
+	if o.Name != u.Name {
+		d.warn(FieldNameChanged, path, "field name changed from "+o.Name+" to "+u.Name)
+	}
+
+	switch {
+	case (o.Kind == protoschema.MapKind) != (u.Kind == protoschema.MapKind):
+		d.incompatible(FieldKindChanged, path, "field "+u.Name+" changed between map and non-map")
+		return
+	case o.Kind == protoschema.MapKind:
+		if o.KeyType != u.KeyType {
+			d.incompatible(FieldScalarKindChanged, path, "map key type changed from "+o.KeyType+" to "+u.KeyType)
+		}
+		d.fieldType(u.Name, o.ResolvedType, o.ValueKind, u.ResolvedType, u.ValueKind, path)
+	default:
+		d.fieldType(u.Name, o.ResolvedType, o.Kind, u.ResolvedType, u.Kind, path)
+	}
+
+	if o.Repeated != u.Repeated {
+		d.warn(FieldLabelChanged, path, "field "+u.Name+" changed between repeated and singular")
+	}
+	if o.Required && !u.Required {
+		d.incompatible(RequiredFieldRemoved, path, "field "+u.Name+" is no longer required")
+	}
+	if !o.Required && u.Required {
+		d.incompatible(RequiredFieldAdded, path, "field "+u.Name+" is now required")
+	}
+}
+
+func (d *protoDiff) fieldType(name, oType string, oKind protoschema.Kind, uType string, uKind protoschema.Kind, path string) {
+	switch {
+	case oKind == protoschema.Scalar && uKind == protoschema.Scalar:
+		if oType != uType && wireGroups[oType] != wireGroups[uType] {
+			d.incompatible(FieldScalarKindChanged, path, "field "+name+" type changed from "+oType+" to "+uType)
+		}
+	case named(oKind) && named(uKind) && (oKind == uKind || oKind == protoschema.Unresolved || uKind == protoschema.Unresolved):
+		if oType != uType {
+			d.incompatible(FieldNamedTypeChanged, path, "field "+name+" type changed from "+oType+" to "+uType)
+		}
+	default:
+		d.incompatible(FieldKindChanged, path, "field "+name+" kind changed from "+oType+" to "+uType)
+	}
+}
+
+func (d *protoDiff) enum(o, u *protoschema.Enum) {
+	values := make(map[int]string, len(u.Values))
+	for _, v := range u.Values {
+		values[v.Number] = v.Name
+	}
+
+	path := "#/" + o.FullName
+	for _, v := range o.Values {
+		name, ok := values[v.Number]
+		switch {
+		case !ok:
+			d.warn(EnumConstRemoved, path+"/"+strconv.Itoa(v.Number), "enum constant "+v.Name+" removed")
+		case name != v.Name:
+			d.warn(EnumConstChanged, path+"/"+strconv.Itoa(v.Number), "enum constant "+v.Name+" renamed to "+name)
+		}
+	}
+}
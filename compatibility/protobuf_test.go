@@ -0,0 +1,251 @@
+package compatibility
+
+import (
+	"testing"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+const orderV1 = `syntax = "proto3";
+package com.test;
+
+import "common/money.proto";
+
+message Order {
+  string id = 1;
+  int32 quantity = 2;
+  common.Money price = 3;
+  oneof payment {
+    string card = 4;
+    string voucher = 5;
+  }
+  string note = 6;
+  string channel = 7;
+}
+`
+
+const money = `syntax = "proto3";
+package common;
+
+message Money {
+  int64 units = 1;
+  string currency = 2;
+}
+`
+
+func checkOrder(t *testing.T, level schemaregistry.CompatibilityLevel, schema string) Result {
+	t.Helper()
+	refs := map[string]string{"common/money.proto": money}
+	res, err := CheckProtobuf(level,
+		ProtobufSchema{Schema: schema, References: refs},
+		ProtobufSchema{Schema: orderV1, References: refs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func hasKind(list []Incompatibility, kind Kind) bool {
+	for _, i := range list {
+		if i.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestCheckProtobuf(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		want     Kind
+		warnings []Kind
+	}{
+		{
+			name: "add field and widen int32",
+			schema: `syntax = "proto3";
+package com.test;
+import "common/money.proto";
+message Order {
+  string id = 1;
+  int64 quantity = 2;
+  .common.Money price = 3;
+  oneof payment {
+    string card = 4;
+    string voucher = 5;
+  }
+  string note = 6;
+  string channel = 7;
+  bool gift = 8;
+}`,
+		},
+		{
+			name: "field number reused with another type",
+			schema: `syntax = "proto3";
+package com.test;
+import "common/money.proto";
+message Order {
+  string id = 1;
+  double quantity = 2;
+  common.Money price = 3;
+  oneof payment {
+    string card = 4;
+    string voucher = 5;
+  }
+  string note = 6;
+  string channel = 7;
+}`,
+			want: FieldScalarKindChanged,
+		},
+		{
+			name: "message field changed to scalar",
+			schema: `syntax = "proto3";
+package com.test;
+message Order {
+  string id = 1;
+  int32 quantity = 2;
+  string price = 3;
+  oneof payment {
+    string card = 4;
+    string voucher = 5;
+  }
+  string note = 6;
+  string channel = 7;
+}`,
+			want: FieldKindChanged,
+		},
+		{
+			name: "field removed without reserving",
+			schema: `syntax = "proto3";
+package com.test;
+import "common/money.proto";
+message Order {
+  string id = 1;
+  int32 quantity = 2;
+  common.Money price = 3;
+  oneof payment {
+    string card = 4;
+    string voucher = 5;
+  }
+  string channel = 7;
+}`,
+			warnings: []Kind{FieldRemovedNotReserved},
+		},
+		{
+			name: "field removed and reserved",
+			schema: `syntax = "proto3";
+package com.test;
+import "common/money.proto";
+message Order {
+  reserved 6;
+  string id = 1;
+  int32 quantity = 2;
+  common.Money price = 3;
+  oneof payment {
+    string card = 4;
+    string voucher = 5;
+  }
+  string channel = 7;
+}`,
+		},
+		{
+			name: "oneof field removed",
+			schema: `syntax = "proto3";
+package com.test;
+import "common/money.proto";
+message Order {
+  reserved 5;
+  string id = 1;
+  int32 quantity = 2;
+  common.Money price = 3;
+  oneof payment {
+    string card = 4;
+  }
+  string note = 6;
+  string channel = 7;
+}`,
+			want: OneofFieldRemoved,
+		},
+		{
+			name: "field moved to existing oneof",
+			schema: `syntax = "proto3";
+package com.test;
+import "common/money.proto";
+message Order {
+  string id = 1;
+  int32 quantity = 2;
+  common.Money price = 3;
+  oneof payment {
+    string card = 4;
+    string voucher = 5;
+    string note = 6;
+  }
+  string channel = 7;
+}`,
+			want: FieldMovedToExistingOneof,
+		},
+		{
+			name: "multiple fields moved to new oneof",
+			schema: `syntax = "proto3";
+package com.test;
+import "common/money.proto";
+message Order {
+  string id = 1;
+  int32 quantity = 2;
+  common.Money price = 3;
+  oneof payment {
+    string card = 4;
+    string voucher = 5;
+  }
+  oneof extra {
+    string note = 6;
+    string channel = 7;
+  }
+}`,
+			want: MultipleFieldsMovedToOneof,
+		},
+		{
+			name: "package changed",
+			schema: `syntax = "proto3";
+package com.other;
+import "common/money.proto";
+message Order {
+  string id = 1;
+}`,
+			want: PackageChanged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := checkOrder(t, schemaregistry.CompatibilityBackward, tt.schema)
+			if tt.want == "" && !res.Compatible() {
+				t.Fatalf("expected compatible, got %v", res.Messages())
+			}
+			if tt.want != "" && !hasKind(res.Incompatibilities, tt.want) {
+				t.Fatalf("expected %s, got %v", tt.want, res.Messages())
+			}
+			for _, w := range tt.warnings {
+				if !hasKind(res.Warnings, w) {
+					t.Errorf("expected warning %s, got %v", w, res.Warnings)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckProtobufForward(t *testing.T) {
+	withMessage := orderV1 + `
+message Refund {
+  string order_id = 1;
+}
+`
+	if res := checkOrder(t, schemaregistry.CompatibilityBackward, withMessage); !res.Compatible() {
+		t.Error("TestCheckProtobufForward: adding a message should be backward compatible", res.Messages())
+	}
+	if res := checkOrder(t, schemaregistry.CompatibilityForward, withMessage); !hasKind(res.Incompatibilities, MessageRemoved) {
+		t.Error("TestCheckProtobufForward: adding a message should not be forward compatible", res.Messages())
+	}
+}
@@ -16,3 +16,44 @@ type Version int
 
 const LatestVersion Version = -1
 const AllVersions Version = -2
+
+type CompatibilityLevel string
+
+const (
+	CompatibilityNone               CompatibilityLevel = "NONE"
+	CompatibilityBackward           CompatibilityLevel = "BACKWARD"
+	CompatibilityBackwardTransitive CompatibilityLevel = "BACKWARD_TRANSITIVE"
+	CompatibilityForward            CompatibilityLevel = "FORWARD"
+	CompatibilityForwardTransitive  CompatibilityLevel = "FORWARD_TRANSITIVE"
+	CompatibilityFull               CompatibilityLevel = "FULL"
+	CompatibilityFullTransitive     CompatibilityLevel = "FULL_TRANSITIVE"
+)
+
+type Mode string
+
+const (
+	ModeReadWrite Mode = "READWRITE"
+	ModeReadOnly  Mode = "READONLY"
+	ModeImport    Mode = "IMPORT"
+)
+
+// RuleKind tells whether a rule transforms data or checks a condition on it.
+type RuleKind string
+
+const (
+	RuleKindTransform RuleKind = "TRANSFORM"
+	RuleKindCondition RuleKind = "CONDITION"
+)
+
+// RuleMode tells when a rule applies: migration rules apply on UPGRADE,
+// DOWNGRADE or both, domain rules on WRITE, READ or both.
+type RuleMode string
+
+const (
+	RuleModeUpgrade   RuleMode = "UPGRADE"
+	RuleModeDowngrade RuleMode = "DOWNGRADE"
+	RuleModeUpDown    RuleMode = "UPDOWN"
+	RuleModeWrite     RuleMode = "WRITE"
+	RuleModeRead      RuleMode = "READ"
+	RuleModeWriteRead RuleMode = "WRITEREAD"
+)
@@ -0,0 +1,238 @@
+package diff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/anjulapaulus/schema_registry/internal/avroname"
+)
+
+func avroChanges(from, to string) ([]Change, error) {
+	var a, b interface{}
+	if err := rawJSON.UnmarshalFromString(from, &a); err != nil {
+		return nil, err
+	}
+	if err := rawJSON.UnmarshalFromString(to, &b); err != nil {
+		return nil, err
+	}
+
+	d := &avroDiff{}
+	d.compare(avroName(a, ""), avroNode{a, ""}, avroNode{b, ""})
+
+	return d.changes, nil
+}
+
+// avroNode is a schema with the namespace its names are resolved in.
+type avroNode struct {
+	schema    interface{}
+	namespace string
+}
+
+type avroDiff struct {
+	recorder
+}
+
+// avroName returns the full name of a named type, or the empty string.
+func avroName(v interface{}, namespace string) string {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	switch m["type"] {
+	case "record", "error", "enum", "fixed":
+	default:
+		return ""
+	}
+
+	return avroname.FullName(m, namespace)
+}
+
+// inner returns the namespace names nested in the named type resolve in.
+func inner(fullName, namespace string) string {
+	if fullName == "" {
+		return namespace
+	}
+
+	return avroname.Namespace(fullName)
+}
+
+// describe returns a short description of the type, such as "long",
+// "com.acme.User", "array<string>" or "union[null,string]".
+func describe(n avroNode) string {
+	switch v := n.schema.(type) {
+	case string:
+		return avroname.Qualify(v, n.namespace)
+	case []interface{}:
+		branches := make([]string, 0, len(v))
+		for _, branch := range v {
+			branches = append(branches, describe(avroNode{branch, n.namespace}))
+		}
+		return "union[" + strings.Join(branches, ",") + "]"
+	case map[string]interface{}:
+		if name := avroName(v, n.namespace); name != "" {
+			return name
+		}
+		switch t := v["type"].(type) {
+		case string:
+			switch t {
+			case "array":
+				return "array<" + describe(avroNode{v["items"], n.namespace}) + ">"
+			case "map":
+				return "map<" + describe(avroNode{v["values"], n.namespace}) + ">"
+			}
+			if logical, ok := v["logicalType"].(string); ok {
+				return t + "(" + logical + ")"
+			}
+			return avroname.Qualify(t, n.namespace)
+		default:
+			return describe(avroNode{t, n.namespace})
+		}
+	}
+
+	return "invalid"
+}
+
+// definition returns the object defining the type, unwrapping {"type": {...}}.
+func definition(v interface{}) (map[string]interface{}, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if nested, ok := m["type"].(map[string]interface{}); ok {
+		return definition(nested)
+	}
+
+	return m, true
+}
+
+func (d *avroDiff) compare(path string, a, b avroNode) {
+	da, db := describe(a), describe(b)
+	if da != db {
+		d.add(Change{Kind: TypeChanged, Element: "type", Path: path, Attribute: "type", Old: da, New: db})
+		return
+	}
+
+	if ua, ok := a.schema.([]interface{}); ok {
+		ub := b.schema.([]interface{})
+		for i := range ua {
+			d.compare(path, avroNode{ua[i], a.namespace}, avroNode{ub[i], b.namespace})
+		}
+		return
+	}
+
+	ma, okA := definition(a.schema)
+	mb, okB := definition(b.schema)
+	if !okA || !okB {
+		return
+	}
+
+	name := avroName(ma, a.namespace)
+	nsA, nsB := inner(name, a.namespace), inner(avroName(mb, b.namespace), b.namespace)
+	element := "type"
+	if t, ok := ma["type"].(string); ok && name != "" {
+		element = t
+	}
+	d.compareAttribute(DocChanged, element, path, "doc", ma, mb)
+
+	switch ma["type"] {
+	case "record", "error":
+		d.compareFields(path, ma, mb, nsA, nsB)
+	case "enum":
+		d.compareSymbols(path, ma, mb)
+		d.compareAttribute(DefaultChanged, "enum", path, "default", ma, mb)
+	case "fixed":
+		d.compareAttribute(Modified, "fixed", path, "size", ma, mb)
+	case "array":
+		d.compare(path+"[]", avroNode{ma["items"], nsA}, avroNode{mb["items"], nsB})
+	case "map":
+		d.compare(path+"{}", avroNode{ma["values"], nsA}, avroNode{mb["values"], nsB})
+	}
+}
+
+func fieldsByName(m map[string]interface{}) (map[string]map[string]interface{}, []string) {
+	fields, _ := m["fields"].([]interface{})
+	byName := make(map[string]map[string]interface{}, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		field, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := field["name"].(string)
+		byName[name] = field
+		names = append(names, name)
+	}
+
+	return byName, names
+}
+
+func (d *avroDiff) compareFields(path string, a, b map[string]interface{}, nsA, nsB string) {
+	fa, namesA := fieldsByName(a)
+	fb, namesB := fieldsByName(b)
+
+	for _, name := range namesA {
+		if _, ok := fb[name]; !ok {
+			d.add(Change{Kind: Removed, Element: "field", Path: path + "." + name, Old: fieldSummary(fa[name], nsA)})
+		}
+	}
+	for _, name := range namesB {
+		if _, ok := fa[name]; !ok {
+			d.add(Change{Kind: Added, Element: "field", Path: path + "." + name, New: fieldSummary(fb[name], nsB)})
+		}
+	}
+	for _, name := range namesB {
+		field, ok := fa[name]
+		if !ok {
+			continue
+		}
+		fieldPath := path + "." + name
+		d.compare(fieldPath, avroNode{field["type"], nsA}, avroNode{fb[name]["type"], nsB})
+		d.compareAttribute(DefaultChanged, "field", fieldPath, "default", field, fb[name])
+		d.compareAttribute(DocChanged, "field", fieldPath, "doc", field, fb[name])
+	}
+}
+
+func fieldSummary(field map[string]interface{}, namespace string) string {
+	s := describe(avroNode{field["type"], namespace})
+	if def, ok := field["default"]; ok {
+		s += " (default " + encode(def) + ")"
+	}
+
+	return s
+}
+
+func (d *avroDiff) compareSymbols(path string, a, b map[string]interface{}) {
+	sa, sb := stringSet(a["symbols"]), stringSet(b["symbols"])
+	for _, s := range sortedKeys(sa) {
+		if !sb[s] {
+			d.add(Change{Kind: Removed, Element: "enum symbol", Path: path + "." + s})
+		}
+	}
+	for _, s := range sortedKeys(sb) {
+		if !sa[s] {
+			d.add(Change{Kind: Added, Element: "enum symbol", Path: path + "." + s})
+		}
+	}
+}
+
+func stringSet(v interface{}) map[string]bool {
+	items, _ := v.([]interface{})
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			set[s] = true
+		}
+	}
+
+	return set
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
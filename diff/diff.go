@@ -0,0 +1,234 @@
+// Package diff describes the semantic differences between two schemas, such
+// as fields added or removed, types, defaults and docs changed, for AVRO,
+// PROTOBUF and JSONSCHEMA schemas.
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+// Kind is the kind of a change.
+type Kind string
+
+const (
+	Added          Kind = "ADDED"
+	Removed        Kind = "REMOVED"
+	TypeChanged    Kind = "TYPE_CHANGED"
+	DefaultChanged Kind = "DEFAULT_CHANGED"
+	DocChanged     Kind = "DOC_CHANGED"
+	Renamed        Kind = "RENAMED"
+	Modified       Kind = "MODIFIED"
+)
+
+// Change is a single difference between two schemas. Element names what
+// changed, such as a field or an enum symbol, and Path locates it: fully
+// qualified names for AVRO and PROTOBUF and JSON pointers for JSONSCHEMA.
+// Attribute names the changed attribute of modifications.
+type Change struct {
+	Kind      Kind   `json:"kind"`
+	Element   string `json:"element"`
+	Path      string `json:"path"`
+	Attribute string `json:"attribute,omitempty"`
+	Old       string `json:"old,omitempty"`
+	New       string `json:"new,omitempty"`
+}
+
+// String formats the change as a single line.
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return "+ " + c.Element + " " + c.Path + suffix(c.New)
+	case Removed:
+		return "- " + c.Element + " " + c.Path + suffix(c.Old)
+	}
+
+	return "~ " + c.Element + " " + c.Path + " " + c.Attribute + ": " + orNone(c.Old) + " -> " + orNone(c.New)
+}
+
+func suffix(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return ": " + s
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+
+	return s
+}
+
+// Diff lists the changes from one schema to another. Subject and the versions
+// are set when the schemas were fetched from the registry.
+type Diff struct {
+	Subject    string                    `json:"subject,omitempty"`
+	From       int                       `json:"from,omitempty"`
+	To         int                       `json:"to,omitempty"`
+	SchemaType schemaregistry.SchemaType `json:"schemaType"`
+	Changes    []Change                  `json:"changes"`
+}
+
+// String formats the diff for review, one change per line.
+func (d *Diff) String() string {
+	var b strings.Builder
+	if d.Subject != "" {
+		fmt.Fprintf(&b, "%s: version %d -> %d\n", d.Subject, d.From, d.To)
+	}
+	if len(d.Changes) == 0 {
+		b.WriteString("No changes.\n")
+	}
+	for _, c := range d.Changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// Schemas returns the changes from the schema from to the schema to. References maps
+// PROTOBUF import names to the imported schemas and is used to resolve type
+// names; it is ignored for other schema types.
+func Schemas(schemaType schemaregistry.SchemaType, from, to string, references map[string]string) (*Diff, error) {
+	d := &Diff{SchemaType: schemaType, Changes: []Change{}}
+
+	var changes []Change
+	var err error
+	switch schemaType {
+	case schemaregistry.AVRO, "":
+		d.SchemaType = schemaregistry.AVRO
+		changes, err = avroChanges(from, to)
+	case schemaregistry.PROTOBUF:
+		changes, err = protobufChanges(from, to, references)
+	case schemaregistry.JSONSCHEMA, "JSON":
+		d.SchemaType = schemaregistry.JSONSCHEMA
+		changes, err = jsonSchemaChanges(from, to)
+	default:
+		return nil, fmt.Errorf("unknown schema type [%s]", schemaType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.Changes = append(d.Changes, changes...)
+
+	return d, nil
+}
+
+// Versions returns the changes between two versions of the subject. Either
+// version may be schemaregistry.LatestVersion.
+func Versions(c *schemaregistry.SchemaClient, subject string, from, to int) (*Diff, error) {
+	fromResp, err := getVersion(c, subject, from)
+	if err != nil {
+		return nil, err
+	}
+	toResp, err := getVersion(c, subject, to)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaType := schemaregistry.AVRO
+	if toResp.SchemaType != nil && *toResp.SchemaType != "" {
+		schemaType = *toResp.SchemaType
+	}
+	references := map[string]string{}
+	if schemaType == schemaregistry.PROTOBUF {
+		for _, resp := range []schemaregistry.SchemaResponse{fromResp, toResp} {
+			if err := collectReferences(c, resp.References, references); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	d, err := Schemas(schemaType, fromResp.Schema, toResp.Schema, references)
+	if err != nil {
+		return nil, err
+	}
+	d.Subject = subject
+	d.From = fromResp.Version
+	d.To = toResp.Version
+
+	return d, nil
+}
+
+func getVersion(c *schemaregistry.SchemaClient, subject string, version int) (schemaregistry.SchemaResponse, error) {
+	var resp schemaregistry.SchemaResponse
+	var err error
+	if schemaregistry.Version(version) == schemaregistry.LatestVersion {
+		resp, err = c.GetLatestSchema(subject)
+	} else {
+		resp, err = c.GetSchemaByVersion(subject, version)
+	}
+	if err != nil {
+		return schemaregistry.SchemaResponse{}, fmt.Errorf("error obtaining subject:%s version:%d err:%w", subject, version, err)
+	}
+
+	return resp, nil
+}
+
+// collectReferences adds the text of the referenced schemas and of their own
+// references to the map, keyed by reference name.
+func collectReferences(c *schemaregistry.SchemaClient, refs []schemaregistry.Reference, out map[string]string) error {
+	for _, ref := range refs {
+		if _, ok := out[ref.Name]; ok {
+			continue
+		}
+		resp, err := c.GetSchemaByVersion(ref.Subject, ref.Version)
+		if err != nil {
+			return fmt.Errorf("error obtaining reference %s err:%w", ref.Name, err)
+		}
+		out[ref.Name] = resp.Schema
+		if err := collectReferences(c, resp.References, out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rawJSON decodes schemas keeping numbers as written and encodes attribute
+// values with sorted keys.
+var rawJSON = jsoniter.Config{SortMapKeys: true, UseNumber: true}.Froze()
+
+// recorder collects the changes found while comparing two schemas.
+type recorder struct {
+	changes []Change
+}
+
+func (r *recorder) add(c Change) {
+	r.changes = append(r.changes, c)
+}
+
+// compareAttribute records a change if the attribute differs between the
+// objects, including when it is present in only one of them.
+func (r *recorder) compareAttribute(kind Kind, element, path, attribute string, a, b map[string]interface{}) {
+	if ea, eb := encodeOptional(a, attribute), encodeOptional(b, attribute); ea != eb {
+		r.changes = append(r.changes, Change{Kind: kind, Element: element, Path: path, Attribute: attribute, Old: ea, New: eb})
+	}
+}
+
+func encode(v interface{}) string {
+	s, err := rawJSON.MarshalToString(v)
+	if err != nil {
+		return "invalid"
+	}
+
+	return s
+}
+
+// encodeOptional returns the encoded attribute, or the empty string if the
+// object does not have it.
+func encodeOptional(m map[string]interface{}, attribute string) string {
+	v, ok := m[attribute]
+	if !ok {
+		return ""
+	}
+
+	return encode(v)
+}
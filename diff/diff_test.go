@@ -0,0 +1,194 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/registrytest"
+)
+
+func changeLines(d *Diff) string {
+	lines := make([]string, 0, len(d.Changes))
+	for _, c := range d.Changes {
+		lines = append(lines, c.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestAvroDiff(t *testing.T) {
+	from := `{"type":"record","name":"User","namespace":"com.test","doc":"A user.","fields":[
+		{"name":"name","type":"string"},
+		{"name":"age","type":"int","default":0},
+		{"name":"email","type":["null","string"],"default":null},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","DISABLED"]}},
+		{"name":"address","type":{"type":"record","name":"Address","fields":[{"name":"city","type":"string"}]}}
+	]}`
+	to := `{"type":"record","name":"User","namespace":"com.test","doc":"A registered user.","fields":[
+		{"name":"name","type":"string","doc":"Full name."},
+		{"name":"age","type":"long","default":18},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","BANNED"]}},
+		{"name":"address","type":{"type":"record","name":"Address","fields":[{"name":"city","type":"string"},{"name":"zip","type":"string","default":""}]}},
+		{"name":"created","type":{"type":"long","logicalType":"timestamp-millis"}}
+	]}`
+
+	d, err := Schemas(schemaregistry.AVRO, from, to, nil)
+	if err != nil {
+		t.Fatal("TestAvroDiff: ", err)
+	}
+	want := strings.Join([]string{
+		`~ record com.test.User doc: "A user." -> "A registered user."`,
+		`- field com.test.User.email: union[null,string] (default null)`,
+		`+ field com.test.User.created: long(timestamp-millis)`,
+		`~ field com.test.User.name doc: (none) -> "Full name."`,
+		`~ type com.test.User.age type: int -> long`,
+		`~ field com.test.User.age default: 0 -> 18`,
+		`- enum symbol com.test.User.status.DISABLED`,
+		`+ enum symbol com.test.User.status.BANNED`,
+		`+ field com.test.User.address.zip: string (default "")`,
+	}, "\n")
+	if got := changeLines(d); got != want {
+		t.Errorf("TestAvroDiff: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAvroDiffNoChanges(t *testing.T) {
+	d, err := Schemas(schemaregistry.AVRO, `{"type":"record","name":"A","fields":[{"name":"f","type":"int"}]}`, `{"name":"A","type":"record","fields":[{"type":{"type":"int"},"name":"f"}]}`, nil)
+	if err != nil {
+		t.Fatal("TestAvroDiffNoChanges: ", err)
+	}
+	if len(d.Changes) != 0 || d.String() != "No changes.\n" {
+		t.Error("TestAvroDiffNoChanges: unexpected changes", d)
+	}
+}
+
+func TestProtobufDiff(t *testing.T) {
+	from := `syntax = "proto3";
+package acme;
+
+// An order.
+message Order {
+  string id = 1;
+  int32 qty = 2;
+  Status status = 3;
+  string note = 4;
+}
+
+enum Status {
+  UNKNOWN = 0;
+  OPEN = 1;
+}
+
+service Orders {
+  rpc Get(Order) returns (Order);
+}
+`
+	to := `syntax = "proto3";
+package acme;
+
+// An order placed by a customer.
+message Order {
+  string id = 1;
+  int64 quantity = 2;
+  Status status = 3;
+  repeated string tags = 5;
+}
+
+message Empty {}
+
+enum Status {
+  UNKNOWN = 0;
+  OPEN = 1;
+  CLOSED = 2;
+}
+
+service Orders {
+  rpc Get(Order) returns (stream Order);
+  rpc Ping(Empty) returns (Empty);
+}
+`
+	d, err := Schemas(schemaregistry.PROTOBUF, from, to, nil)
+	if err != nil {
+		t.Fatal("TestProtobufDiff: ", err)
+	}
+	want := strings.Join([]string{
+		`+ message acme.Empty`,
+		`~ message acme.Order doc: An order. -> An order placed by a customer.`,
+		`- field acme.Order.note: string note = 4`,
+		`+ field acme.Order.tags: repeated string tags = 5`,
+		`~ field acme.Order.quantity name: qty -> quantity`,
+		`~ field acme.Order.quantity type: int32 -> int64`,
+		`+ enum value acme.Status.CLOSED: 2`,
+		`~ rpc acme.Orders.Get returns: .acme.Order -> stream .acme.Order`,
+		`+ rpc acme.Orders.Ping: (.acme.Empty) returns (.acme.Empty)`,
+	}, "\n")
+	if got := changeLines(d); got != want {
+		t.Errorf("TestProtobufDiff: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJSONSchemaDiff(t *testing.T) {
+	from := `{"type":"object","properties":{
+		"id":{"type":"integer"},
+		"name":{"type":"string","description":"The name."},
+		"tags":{"type":"array","items":{"type":"string"}},
+		"legacy":{"type":"boolean"}
+	},"required":["id"],"additionalProperties":false}`
+	to := `{"type":"object","properties":{
+		"id":{"type":["integer","string"]},
+		"name":{"type":"string","description":"The display name.","default":"anonymous"},
+		"tags":{"type":"array","items":{"type":"string","maxLength":10}},
+		"email":{"type":"string","format":"email"}
+	},"required":["id","email"],"additionalProperties":true}`
+
+	d, err := Schemas(schemaregistry.JSONSCHEMA, from, to, nil)
+	if err != nil {
+		t.Fatal("TestJSONSchemaDiff: ", err)
+	}
+	want := strings.Join([]string{
+		`+ required property #/properties/email`,
+		`- property #/properties/legacy: boolean`,
+		`+ property #/properties/email: string`,
+		`~ schema #/properties/id type: integer -> integer,string`,
+		`~ schema #/properties/name description: "The name." -> "The display name."`,
+		`~ schema #/properties/name default: (none) -> "anonymous"`,
+		`~ schema #/properties/tags/items maxLength: (none) -> 10`,
+		`~ schema #/additionalProperties schema: false -> true`,
+	}, "\n")
+	if got := changeLines(d); got != want {
+		t.Errorf("TestJSONSchemaDiff: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSchemasUnknownType(t *testing.T) {
+	if _, err := Schemas("XML", "<a/>", "<b/>", nil); err == nil {
+		t.Error("TestSchemasUnknownType: unknown schema type not handled")
+	}
+}
+
+func TestVersions(t *testing.T) {
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := schemaregistry.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal("TestVersions: ", err)
+	}
+	for _, schema := range []string{
+		`{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}`,
+		`{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"int","default":0}]}`,
+	} {
+		if _, err := c.CreateSchema("users", schema, schemaregistry.AVRO); err != nil {
+			t.Fatal("TestVersions: ", err)
+		}
+	}
+
+	d, err := Versions(c, "users", 1, int(schemaregistry.LatestVersion))
+	if err != nil {
+		t.Fatal("TestVersions: ", err)
+	}
+	want := "users: version 1 -> 2\n+ field User.age: int (default 0)\n"
+	if d.String() != want {
+		t.Errorf("TestVersions: got %q want %q", d.String(), want)
+	}
+}
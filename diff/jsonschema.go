@@ -0,0 +1,219 @@
+package diff
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonSchemaAttributes are the keywords compared as plain values.
+var jsonSchemaAttributes = []string{
+	"$ref", "const", "enum", "format", "pattern",
+	"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf",
+	"minLength", "maxLength", "minItems", "maxItems", "uniqueItems",
+	"minProperties", "maxProperties",
+}
+
+// jsonSchemaMaps are the keywords holding named subschemas.
+var jsonSchemaMaps = map[string]string{
+	"properties":        "property",
+	"patternProperties": "pattern property",
+	"definitions":       "definition",
+	"$defs":             "definition",
+	"dependentSchemas":  "dependent schema",
+}
+
+// jsonSchemaLists are the keywords holding lists of subschemas.
+var jsonSchemaLists = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+
+// jsonSchemaSubschemas are the keywords holding a single subschema.
+var jsonSchemaSubschemas = []string{
+	"items", "additionalItems", "additionalProperties", "contains", "not", "if", "then", "else", "propertyNames",
+}
+
+func jsonSchemaChanges(from, to string) ([]Change, error) {
+	var a, b interface{}
+	if err := rawJSON.UnmarshalFromString(from, &a); err != nil {
+		return nil, err
+	}
+	if err := rawJSON.UnmarshalFromString(to, &b); err != nil {
+		return nil, err
+	}
+
+	d := &jsonSchemaDiff{}
+	d.compare("#", a, b)
+
+	return d.changes, nil
+}
+
+type jsonSchemaDiff struct {
+	recorder
+}
+
+func pointer(path string, tokens ...string) string {
+	for _, token := range tokens {
+		token = strings.Replace(token, "~", "~0", -1)
+		token = strings.Replace(token, "/", "~1", -1)
+		path += "/" + token
+	}
+
+	return path
+}
+
+func (d *jsonSchemaDiff) compare(path string, a, b interface{}) {
+	ma, okA := a.(map[string]interface{})
+	mb, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		// Boolean schemas, or a boolean schema replaced by an object.
+		if ea, eb := encode(a), encode(b); ea != eb {
+			d.add(Change{Kind: Modified, Element: "schema", Path: path, Attribute: "schema", Old: ea, New: eb})
+		}
+		return
+	}
+
+	if ta, tb := jsonTypes(ma["type"]), jsonTypes(mb["type"]); ta != tb {
+		d.add(Change{Kind: TypeChanged, Element: "schema", Path: path, Attribute: "type", Old: ta, New: tb})
+	}
+	d.compareAttribute(DocChanged, "schema", path, "title", ma, mb)
+	d.compareAttribute(DocChanged, "schema", path, "description", ma, mb)
+	d.compareAttribute(DefaultChanged, "schema", path, "default", ma, mb)
+	for _, attribute := range jsonSchemaAttributes {
+		d.compareAttribute(Modified, "schema", path, attribute, ma, mb)
+	}
+	d.compareRequired(path, ma, mb)
+
+	for _, keyword := range sortedMapKeys(jsonSchemaMaps) {
+		d.compareMap(pointer(path, keyword), jsonSchemaMaps[keyword], ma[keyword], mb[keyword])
+	}
+	for _, keyword := range jsonSchemaLists {
+		d.compareList(pointer(path, keyword), ma[keyword], mb[keyword])
+	}
+	for _, keyword := range jsonSchemaSubschemas {
+		d.compareSubschema(pointer(path, keyword), ma, mb, keyword)
+	}
+}
+
+// compareSubschema compares a keyword holding a single subschema, which may
+// be absent from either schema.
+func (d *jsonSchemaDiff) compareSubschema(path string, a, b map[string]interface{}, keyword string) {
+	va, okA := a[keyword]
+	vb, okB := b[keyword]
+	switch {
+	case okA && okB:
+		if la, ok := va.([]interface{}); ok {
+			// Draft 4 tuple items.
+			d.compareList(path, la, vb)
+			return
+		}
+		d.compare(path, va, vb)
+	case okA:
+		d.add(Change{Kind: Removed, Element: keyword, Path: path, Old: encode(va)})
+	case okB:
+		d.add(Change{Kind: Added, Element: keyword, Path: path, New: encode(vb)})
+	}
+}
+
+func (d *jsonSchemaDiff) compareMap(path, element string, a, b interface{}) {
+	ma, _ := a.(map[string]interface{})
+	mb, _ := b.(map[string]interface{})
+
+	for _, name := range sortedObjectKeys(ma) {
+		if _, ok := mb[name]; !ok {
+			d.add(Change{Kind: Removed, Element: element, Path: pointer(path, name), Old: summary(ma[name])})
+		}
+	}
+	for _, name := range sortedObjectKeys(mb) {
+		if _, ok := ma[name]; !ok {
+			d.add(Change{Kind: Added, Element: element, Path: pointer(path, name), New: summary(mb[name])})
+		}
+	}
+	for _, name := range sortedObjectKeys(mb) {
+		if va, ok := ma[name]; ok {
+			d.compare(pointer(path, name), va, mb[name])
+		}
+	}
+}
+
+func (d *jsonSchemaDiff) compareList(path string, a, b interface{}) {
+	la, _ := a.([]interface{})
+	lb, _ := b.([]interface{})
+
+	for i := 0; i < len(la) || i < len(lb); i++ {
+		p := pointer(path, strconv.Itoa(i))
+		switch {
+		case i >= len(lb):
+			d.add(Change{Kind: Removed, Element: "subschema", Path: p, Old: summary(la[i])})
+		case i >= len(la):
+			d.add(Change{Kind: Added, Element: "subschema", Path: p, New: summary(lb[i])})
+		default:
+			d.compare(p, la[i], lb[i])
+		}
+	}
+}
+
+func (d *jsonSchemaDiff) compareRequired(path string, a, b map[string]interface{}) {
+	ra, rb := stringSet(a["required"]), stringSet(b["required"])
+	for _, name := range sortedKeys(ra) {
+		if !rb[name] {
+			d.add(Change{Kind: Removed, Element: "required property", Path: pointer(path, "properties", name)})
+		}
+	}
+	for _, name := range sortedKeys(rb) {
+		if !ra[name] {
+			d.add(Change{Kind: Added, Element: "required property", Path: pointer(path, "properties", name)})
+		}
+	}
+}
+
+// jsonTypes returns the type keyword as a sorted, comma separated list.
+func jsonTypes(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		sort.Strings(types)
+		return strings.Join(types, ",")
+	}
+
+	return ""
+}
+
+// summary describes a subschema by its type, or by its encoding if it has none.
+func summary(v interface{}) string {
+	if m, ok := v.(map[string]interface{}); ok {
+		if t := jsonTypes(m["type"]); t != "" {
+			return t
+		}
+		if ref, ok := m["$ref"].(string); ok {
+			return ref
+		}
+	}
+
+	return encode(v)
+}
+
+func sortedObjectKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func sortedMapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
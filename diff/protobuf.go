@@ -0,0 +1,263 @@
+package diff
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/anjulapaulus/schema_registry/internal/protoschema"
+)
+
+func protobufChanges(from, to string, references map[string]string) ([]Change, error) {
+	a, err := protoschema.Parse(from, references)
+	if err != nil {
+		return nil, err
+	}
+	b, err := protoschema.Parse(to, references)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &protobufDiff{}
+	d.compareValue(Modified, "file", "", "package", a.Package, b.Package)
+	d.compareMessages(a, b)
+	d.compareEnums(a, b)
+	d.compareServices(a, b)
+
+	return d.changes, nil
+}
+
+type protobufDiff struct {
+	recorder
+}
+
+func (d *protobufDiff) compareValue(kind Kind, element, path, attribute, a, b string) {
+	if a != b {
+		d.add(Change{Kind: kind, Element: element, Path: path, Attribute: attribute, Old: a, New: b})
+	}
+}
+
+func (d *protobufDiff) compareMessages(a, b *protoschema.File) {
+	for _, name := range a.MessageNames() {
+		if _, ok := b.Message(name); !ok {
+			d.add(Change{Kind: Removed, Element: "message", Path: name})
+		}
+	}
+	for _, name := range b.MessageNames() {
+		ma, ok := a.Message(name)
+		if !ok {
+			d.add(Change{Kind: Added, Element: "message", Path: name})
+			continue
+		}
+		mb, _ := b.Message(name)
+		d.compareValue(DocChanged, "message", name, "doc", ma.Doc, mb.Doc)
+		d.compareFields(name, ma.Fields, mb.Fields)
+	}
+}
+
+func fieldsByNumber(fields []*protoschema.Field) (map[int]*protoschema.Field, []int) {
+	byNumber := make(map[int]*protoschema.Field, len(fields))
+	numbers := make([]int, 0, len(fields))
+	for _, f := range fields {
+		byNumber[f.Number] = f
+		numbers = append(numbers, f.Number)
+	}
+	sort.Ints(numbers)
+
+	return byNumber, numbers
+}
+
+// fieldType describes the field type with its label, such as "repeated string",
+// "map<string, .acme.Item>" or "optional .acme.Status".
+func fieldType(f *protoschema.Field) string {
+	kind := f.Kind
+	if kind == protoschema.MapKind {
+		kind = f.ValueKind
+	}
+	typ := f.Type
+	if kind == protoschema.MessageKind || kind == protoschema.EnumKind {
+		typ = "." + f.ResolvedType
+	}
+	if f.Kind == protoschema.MapKind {
+		return "map<" + f.KeyType + ", " + typ + ">"
+	}
+
+	return typ
+}
+
+func fieldLabel(f *protoschema.Field) string {
+	switch {
+	case f.Repeated:
+		return "repeated"
+	case f.Required:
+		return "required"
+	case f.Optional:
+		return "optional"
+	}
+
+	return ""
+}
+
+func fieldSignature(f *protoschema.Field) string {
+	s := fieldType(f) + " " + f.Name + " = " + strconv.Itoa(f.Number)
+	if label := fieldLabel(f); label != "" {
+		s = label + " " + s
+	}
+
+	return s
+}
+
+func optionValue(opts []protoschema.Option, name string) string {
+	for _, o := range opts {
+		if o.Name == name {
+			return o.Value
+		}
+	}
+
+	return ""
+}
+
+func (d *protobufDiff) compareFields(message string, a, b []*protoschema.Field) {
+	fa, numbersA := fieldsByNumber(a)
+	fb, numbersB := fieldsByNumber(b)
+
+	for _, n := range numbersA {
+		if _, ok := fb[n]; !ok {
+			d.add(Change{Kind: Removed, Element: "field", Path: message + "." + fa[n].Name, Old: fieldSignature(fa[n])})
+		}
+	}
+	for _, n := range numbersB {
+		if _, ok := fa[n]; !ok {
+			d.add(Change{Kind: Added, Element: "field", Path: message + "." + fb[n].Name, New: fieldSignature(fb[n])})
+		}
+	}
+	for _, n := range numbersB {
+		x, ok := fa[n]
+		if !ok {
+			continue
+		}
+		y := fb[n]
+		path := message + "." + y.Name
+		d.compareValue(Renamed, "field", path, "name", x.Name, y.Name)
+		d.compareValue(TypeChanged, "field", path, "type", fieldType(x), fieldType(y))
+		d.compareValue(Modified, "field", path, "label", fieldLabel(x), fieldLabel(y))
+		d.compareValue(Modified, "field", path, "oneof", x.Oneof, y.Oneof)
+		d.compareValue(DefaultChanged, "field", path, "default", optionValue(x.Options, "default"), optionValue(y.Options, "default"))
+		d.compareValue(DocChanged, "field", path, "doc", x.Doc, y.Doc)
+	}
+}
+
+func (d *protobufDiff) compareEnums(a, b *protoschema.File) {
+	for _, name := range a.EnumNames() {
+		if _, ok := b.Enum(name); !ok {
+			d.add(Change{Kind: Removed, Element: "enum", Path: name})
+		}
+	}
+	for _, name := range b.EnumNames() {
+		ea, ok := a.Enum(name)
+		if !ok {
+			d.add(Change{Kind: Added, Element: "enum", Path: name})
+			continue
+		}
+		eb, _ := b.Enum(name)
+		d.compareValue(DocChanged, "enum", name, "doc", ea.Doc, eb.Doc)
+
+		va := make(map[string]*protoschema.EnumValue, len(ea.Values))
+		for _, v := range ea.Values {
+			va[v.Name] = v
+		}
+		vb := make(map[string]*protoschema.EnumValue, len(eb.Values))
+		for _, v := range eb.Values {
+			vb[v.Name] = v
+		}
+		for _, v := range ea.Values {
+			if _, ok := vb[v.Name]; !ok {
+				d.add(Change{Kind: Removed, Element: "enum value", Path: name + "." + v.Name, Old: strconv.Itoa(v.Number)})
+			}
+		}
+		for _, v := range eb.Values {
+			x, ok := va[v.Name]
+			if !ok {
+				d.add(Change{Kind: Added, Element: "enum value", Path: name + "." + v.Name, New: strconv.Itoa(v.Number)})
+				continue
+			}
+			d.compareValue(Modified, "enum value", name+"."+v.Name, "number", strconv.Itoa(x.Number), strconv.Itoa(v.Number))
+			d.compareValue(DocChanged, "enum value", name+"."+v.Name, "doc", x.Doc, v.Doc)
+		}
+	}
+}
+
+func rpcType(typ string, streams bool) string {
+	if streams {
+		return "stream " + typ
+	}
+
+	return typ
+}
+
+func (d *protobufDiff) compareServices(a, b *protoschema.File) {
+	prefix := ""
+	if b.Package != "" {
+		prefix = b.Package + "."
+	}
+	sa := make(map[string]*protoschema.Service, len(a.Services))
+	for _, s := range a.Services {
+		sa[s.Name] = s
+	}
+	sb := make(map[string]*protoschema.Service, len(b.Services))
+	for _, s := range b.Services {
+		sb[s.Name] = s
+	}
+
+	for _, s := range a.Services {
+		if _, ok := sb[s.Name]; !ok {
+			d.add(Change{Kind: Removed, Element: "service", Path: prefix + s.Name})
+		}
+	}
+	for _, s := range b.Services {
+		x, ok := sa[s.Name]
+		if !ok {
+			d.add(Change{Kind: Added, Element: "service", Path: prefix + s.Name})
+			continue
+		}
+		path := prefix + s.Name
+		d.compareValue(DocChanged, "service", path, "doc", x.Doc, s.Doc)
+
+		ra := make(map[string]*protoschema.RPC, len(x.RPCs))
+		for _, rpc := range x.RPCs {
+			ra[rpc.Name] = rpc
+		}
+		rb := make(map[string]*protoschema.RPC, len(s.RPCs))
+		for _, rpc := range s.RPCs {
+			rb[rpc.Name] = rpc
+		}
+		for _, rpc := range x.RPCs {
+			if _, ok := rb[rpc.Name]; !ok {
+				d.add(Change{Kind: Removed, Element: "rpc", Path: path + "." + rpc.Name, Old: rpcSignature(rpc)})
+			}
+		}
+		for _, rpc := range s.RPCs {
+			old, ok := ra[rpc.Name]
+			if !ok {
+				d.add(Change{Kind: Added, Element: "rpc", Path: path + "." + rpc.Name, New: rpcSignature(rpc)})
+				continue
+			}
+			rpcPath := path + "." + rpc.Name
+			d.compareValue(TypeChanged, "rpc", rpcPath, "request", rpcType(resolvedName(old.RequestType, old.ResolvedRequestType, old.RequestKind), old.StreamsRequest), rpcType(resolvedName(rpc.RequestType, rpc.ResolvedRequestType, rpc.RequestKind), rpc.StreamsRequest))
+			d.compareValue(TypeChanged, "rpc", rpcPath, "returns", rpcType(resolvedName(old.ReturnsType, old.ResolvedReturnsType, old.ReturnsKind), old.StreamsReturns), rpcType(resolvedName(rpc.ReturnsType, rpc.ResolvedReturnsType, rpc.ReturnsKind), rpc.StreamsReturns))
+			d.compareValue(DocChanged, "rpc", rpcPath, "doc", old.Doc, rpc.Doc)
+		}
+	}
+}
+
+func resolvedName(written, resolved string, kind protoschema.Kind) string {
+	if kind == protoschema.MessageKind || kind == protoschema.EnumKind {
+		return "." + resolved
+	}
+
+	return written
+}
+
+func rpcSignature(rpc *protoschema.RPC) string {
+	return "(" + rpcType(resolvedName(rpc.RequestType, rpc.ResolvedRequestType, rpc.RequestKind), rpc.StreamsRequest) + ") returns (" +
+		rpcType(resolvedName(rpc.ReturnsType, rpc.ResolvedReturnsType, rpc.ReturnsKind), rpc.StreamsReturns) + ")"
+}
@@ -0,0 +1,166 @@
+package schemaregistry
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// diskCache persists fetched schemas in a directory of JSON files, so that
+// they survive restarts and registry outages. Each registry gets its own
+// directory, named after its escaped base URL:
+//
+//	<registry>/ids/<id>.json
+//	<registry>/contexts/<context>/ids/<id>.json
+//	<registry>/subjects/<subject>/versions/<version>.json
+//	<registry>/subjects/<subject>/latest.json
+//
+// Schemas by id never change, so their files are written once and read before
+// the registry is asked. A subject version can be deleted and registered again
+// with another schema, so subject files are overwritten on every fetch and
+// only read when serving stale schemas.
+type diskCache struct {
+	dir string
+}
+
+func newDiskCache(dir, baseURL string) (*diskCache, error) {
+	dir = filepath.Join(dir, url.QueryEscape(strings.TrimSuffix(baseURL, "/")))
+	for _, sub := range []string{"ids", "subjects"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &diskCache{dir: dir}, nil
+}
+
+func (d *diskCache) idPath(schemaContext string, id int) string {
+	if schemaContext != "" {
+		return filepath.Join(d.dir, "contexts", url.PathEscape(schemaContext), "ids", strconv.Itoa(id)+".json")
+	}
+
+	return filepath.Join(d.dir, "ids", strconv.Itoa(id)+".json")
+}
+
+func (d *diskCache) subjectDir(subject string) string {
+	return filepath.Join(d.dir, "subjects", url.PathEscape(subject))
+}
+
+func (d *diskCache) versionPath(subject string, version int) string {
+	return filepath.Join(d.subjectDir(subject), "versions", strconv.Itoa(version)+".json")
+}
+
+func (d *diskCache) latestPath(subject string) string {
+	return filepath.Join(d.subjectDir(subject), "latest.json")
+}
+
+// get reads the schema stored in the file.
+func (d *diskCache) get(path string) (*Schema, bool) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var resp SchemaResponse
+	if err := jsoniter.Unmarshal(b, &resp); err != nil || resp.Schema == "" {
+		return nil, false
+	}
+
+	return newSchema(resp), true
+}
+
+// put writes the schema by id, unless it is stored already, and by subject
+// version.
+func (d *diskCache) put(schema *Schema) error {
+	if path := d.idPath(schema.context, schema.ID); !exists(path) {
+		if err := writeSchema(path, schema); err != nil {
+			return err
+		}
+	}
+	if schema.Subject == "" || schema.Version <= 0 {
+		return nil
+	}
+
+	return writeSchema(d.versionPath(schema.Subject, schema.Version), schema)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// putLatest writes the schema as the latest schema of its subject.
+func (d *diskCache) putLatest(schema *Schema) error {
+	return writeSchema(d.latestPath(schema.Subject), schema)
+}
+
+// find returns the stored version of the subject with the given cache key,
+// computed from its schema, references, metadata and rule set.
+func (d *diskCache) find(subject, key string) (*Schema, bool) {
+	files, err := ioutil.ReadDir(filepath.Join(d.subjectDir(subject), "versions"))
+	if err != nil {
+		return nil, false
+	}
+
+	for _, file := range files {
+		schema, ok := d.get(filepath.Join(d.subjectDir(subject), "versions", file.Name()))
+		if ok && cacheKey(schema.Type(), schema.Schema, schema.References, schema.Metadata, schema.RuleSet) == key {
+			return schema, true
+		}
+	}
+
+	return nil, false
+}
+
+// writeSchema writes the schema to a temporary file renamed into place, so
+// that readers never see a partially written file.
+func writeSchema(path string, schema *Schema) error {
+	b, err := jsoniter.Marshal(SchemaResponse{
+		ID:         schema.ID,
+		Subject:    schema.Subject,
+		Version:    schema.Version,
+		SchemaType: schema.SchemaType,
+		Schema:     schema.Schema,
+		References: schema.References,
+		Metadata:   schema.Metadata,
+		RuleSet:    schema.RuleSet,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// unavailable reports whether the error means the registry could not be
+// reached or failed, rather than rejecting the request.
+func unavailable(err error) bool {
+	var regErr Error
+	if errors.As(err, &regErr) {
+		return regErr.StatusCode >= 500
+	}
+
+	return err != nil
+}
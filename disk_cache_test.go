@@ -0,0 +1,207 @@
+package schemaregistry
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const diskUserSchema = `{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}`
+
+// registryClient answers the requests made for schema 1, version 1 of the
+// users subject until it is taken down.
+func registryClient(down *bool, requests *int) *HTTPClientMock {
+	return &HTTPClientMock{DoFunc: func(r *http.Request) (*http.Response, error) {
+		*requests++
+		if *down {
+			return nil, errors.New("connection refused")
+		}
+
+		body := `{"subject":"users","version":1,"id":1,"schema":"{\"type\":\"record\",\"name\":\"User\",\"fields\":[{\"name\":\"name\",\"type\":\"string\"}]}"}`
+		switch u := r.URL.String(); {
+		case strings.HasSuffix(u, "/schemas/ids/1/versions"):
+			body = `[{"subject":"users","version":1}]`
+		case r.Method == http.MethodPost && strings.HasSuffix(u, "/subjects/users/versions"):
+			body = `{"id":1}`
+		}
+
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(body)), StatusCode: 200}, nil
+	}}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	var down bool
+	var requests int
+	client := registryClient(&down, &requests)
+
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithDiskCache(dir))
+	if err != nil {
+		t.Fatal("TestDiskCache: ", err)
+	}
+	if _, err := reg.GetSchemaByID(1); err != nil {
+		t.Fatal("TestDiskCache: ", err)
+	}
+	if _, err := reg.GetLatestSchema("users"); err != nil {
+		t.Fatal("TestDiskCache: ", err)
+	}
+	for _, path := range []string{"ids/1.json", "subjects/users/versions/1.json", "subjects/users/latest.json"} {
+		if _, err := os.Stat(filepath.Join(dir, "localhost%3A8080", path)); err != nil {
+			t.Error("TestDiskCache: file not written", path, err)
+		}
+	}
+
+	// A restarted process decodes known ids without the registry.
+	down = true
+	requests = 0
+	restarted, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithDiskCache(dir))
+	if err != nil {
+		t.Fatal("TestDiskCache: ", err)
+	}
+	schema, err := restarted.GetSchemaByID(1)
+	if err != nil {
+		t.Fatal("TestDiskCache: ", err)
+	}
+	if schema.Subject != "users" || schema.Version != 1 || schema.Schema != diskUserSchema {
+		t.Error("TestDiskCache: unexpected schema", schema)
+	}
+	if _, err := restarted.ParseAvro(schema); err != nil {
+		t.Error("TestDiskCache: ", err)
+	}
+	if requests != 0 {
+		t.Error("TestDiskCache: registry requested", requests)
+	}
+
+	// Subject versions can be registered again with another schema, so they
+	// are only read from disk when serving stale schemas.
+	another, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithDiskCache(dir))
+	if err != nil {
+		t.Fatal("TestDiskCache: ", err)
+	}
+	if err := another.Register("users", 1); err == nil {
+		t.Error("TestDiskCache: subject version read from disk")
+	}
+
+	// Another registry does not share the cached ids.
+	other, err := NewRegistry("localhost:9090/", WithHTTPClient(client), WithDiskCache(dir))
+	if err != nil {
+		t.Fatal("TestDiskCache: ", err)
+	}
+	if _, err := other.GetSchemaByID(1); err == nil {
+		t.Error("TestDiskCache: id of another registry read from disk")
+	}
+
+	// The latest schema is only served stale if asked to.
+	if _, err := restarted.GetLatestSchema("users"); err == nil {
+		t.Error("TestDiskCache: stale latest schema served")
+	}
+}
+
+func TestDiskCacheServeStale(t *testing.T) {
+	dir := t.TempDir()
+	var down bool
+	var requests int
+	client := registryClient(&down, &requests)
+
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithDiskCache(dir), WithServeStale())
+	if err != nil {
+		t.Fatal("TestDiskCacheServeStale: ", err)
+	}
+	if _, err := reg.GetLatestSchema("users"); err != nil {
+		t.Fatal("TestDiskCacheServeStale: ", err)
+	}
+
+	down = true
+	logger := &recordingLogger{}
+	restarted, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithDiskCache(dir), WithServeStale(), WithLogger(logger))
+	if err != nil {
+		t.Fatal("TestDiskCacheServeStale: ", err)
+	}
+	latest, err := restarted.GetLatestSchema("users")
+	if err != nil || latest.ID != 1 {
+		t.Error("TestDiskCacheServeStale: stale latest schema not served", latest, err)
+	}
+	registered, err := restarted.RegisterSchema("users", `{"name":"User","type":"record","fields":[{"name":"name","type":"string"}]}`, AVRO)
+	if err != nil || registered.ID != 1 {
+		t.Error("TestDiskCacheServeStale: stale registration not served", registered, err)
+	}
+	if _, err := restarted.RegisterSchema("users", `{"type":"string"}`, AVRO); err == nil {
+		t.Error("TestDiskCacheServeStale: unknown schema registered offline")
+	}
+	ref := Reference{Name: "Address", Subject: "address", Version: 1}
+	if _, err := restarted.RegisterSchema("users", diskUserSchema, AVRO, ref); err == nil {
+		t.Error("TestDiskCacheServeStale: schema with other references registered offline")
+	}
+	metadata := &Metadata{Properties: map[string]string{"owner": "team-a"}}
+	if _, err := restarted.RegisterSchemaWithMetadata("users", diskUserSchema, AVRO, metadata, nil); err == nil {
+		t.Error("TestDiskCacheServeStale: schema with other metadata registered offline")
+	}
+	if _, ok := logger.find("WARN", "schema registry unavailable, serving stale schema"); !ok {
+		t.Error("TestDiskCacheServeStale: stale schema not logged", logger.records)
+	}
+
+	another, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithDiskCache(dir), WithServeStale())
+	if err != nil {
+		t.Fatal("TestDiskCacheServeStale: ", err)
+	}
+	if err := another.Register("users", 1); err != nil {
+		t.Error("TestDiskCacheServeStale: stale subject version not served", err)
+	}
+
+	down = false
+	requests = 0
+	if _, err := restarted.RegisterSchema("users", diskUserSchema, AVRO); err != nil || requests == 0 {
+		t.Error("TestDiskCacheServeStale: stale registration cached", requests, err)
+	}
+}
+
+func TestDiskCacheSubjectVersion(t *testing.T) {
+	dir := t.TempDir()
+	var down bool
+	var requests int
+	client := registryClient(&down, &requests)
+
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithDiskCache(dir), WithServeStale())
+	if err != nil {
+		t.Fatal("TestDiskCacheSubjectVersion: ", err)
+	}
+	// The version was deleted and registered again with another schema.
+	old := &Schema{ID: 7, Subject: "users", Version: 1, Schema: `{"type":"string"}`}
+	if err := writeSchema(reg.disk.versionPath("users", 1), old); err != nil {
+		t.Fatal("TestDiskCacheSubjectVersion: ", err)
+	}
+
+	if err := reg.Register("users", 1); err != nil {
+		t.Fatal("TestDiskCacheSubjectVersion: ", err)
+	}
+	if schema := reg.subjectVersionSchema["users"][1]; schema.ID != 1 || requests == 0 {
+		t.Error("TestDiskCacheSubjectVersion: stored subject version served", schema, requests)
+	}
+	stored, ok := reg.disk.get(reg.disk.versionPath("users", 1))
+	if !ok || stored.ID != 1 {
+		t.Error("TestDiskCacheSubjectVersion: stored subject version not overwritten", stored)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	if !unavailable(errors.New("connection refused")) || !unavailable(Error{StatusCode: 503}) {
+		t.Error("TestUnavailable: unavailable registry not detected")
+	}
+	if unavailable(Error{StatusCode: 409, Code: 409}) || unavailable(nil) {
+		t.Error("TestUnavailable: rejected request treated as unavailable")
+	}
+}
+
+func TestNewRegistryDiskCacheError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal("TestNewRegistryDiskCacheError: ", err)
+	}
+	if _, err := NewRegistry("localhost:8080", WithDiskCache(file)); err == nil {
+		t.Error("TestNewRegistryDiskCacheError: invalid directory accepted")
+	}
+}
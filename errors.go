@@ -0,0 +1,78 @@
+package schemaregistry
+
+import "strconv"
+
+// Registry error codes as returned in the error_code field of an error response.
+const (
+	codeSubjectNotFound             = 40401
+	codeVersionNotFound             = 40402
+	codeSchemaNotFound              = 40403
+	codeSubjectSoftDeleted          = 40404
+	codeSubjectNotSoftDeleted       = 40405
+	codeSchemaVersionSoftDeleted    = 40406
+	codeSchemaVersionNotSoftDeleted = 40407
+	codeSubjectLevelCompatNotFound  = 40408
+	codeSubjectLevelModeNotFound    = 40409
+	codeIncompatibleSchema          = 409
+	codeInvalidSchema               = 42201
+	codeInvalidVersion              = 42202
+	codeInvalidCompatibilityLevel   = 42203
+	codeInvalidMode                 = 42204
+	codeOperationNotPermitted       = 42205
+	codeReferenceExists             = 42206
+	codeBackendStore                = 50001
+	codeOperationTimeout            = 50002
+	codeRequestForwarding           = 50003
+)
+
+// Sentinel errors for the known registry error codes. A registry Error matches
+// a sentinel with errors.Is when their codes are equal, regardless of message.
+var (
+	ErrSubjectNotFound              = Error{Code: codeSubjectNotFound, Message: "subject not found"}
+	ErrVersionNotFound              = Error{Code: codeVersionNotFound, Message: "version not found"}
+	ErrSchemaNotFound               = Error{Code: codeSchemaNotFound, Message: "schema not found"}
+	ErrSubjectSoftDeleted           = Error{Code: codeSubjectSoftDeleted, Message: "subject was soft deleted"}
+	ErrSubjectNotSoftDeleted        = Error{Code: codeSubjectNotSoftDeleted, Message: "subject was not deleted first before being permanently deleted"}
+	ErrSchemaVersionSoftDeleted     = Error{Code: codeSchemaVersionSoftDeleted, Message: "schema version was soft deleted"}
+	ErrSchemaVersionNotSoftDeleted  = Error{Code: codeSchemaVersionNotSoftDeleted, Message: "schema version was not deleted first before being permanently deleted"}
+	ErrSubjectCompatibilityNotFound = Error{Code: codeSubjectLevelCompatNotFound, Message: "subject compatibility level not configured"}
+	ErrSubjectModeNotFound          = Error{Code: codeSubjectLevelModeNotFound, Message: "subject mode not configured"}
+	ErrIncompatibleSchema           = Error{Code: codeIncompatibleSchema, Message: "schema being registered is incompatible with an earlier schema"}
+	ErrInvalidSchema                = Error{Code: codeInvalidSchema, Message: "invalid schema"}
+	ErrInvalidVersion               = Error{Code: codeInvalidVersion, Message: "invalid version"}
+	ErrInvalidCompatibilityLevel    = Error{Code: codeInvalidCompatibilityLevel, Message: "invalid compatibility level"}
+	ErrInvalidMode                  = Error{Code: codeInvalidMode, Message: "invalid mode"}
+	ErrOperationNotPermitted        = Error{Code: codeOperationNotPermitted, Message: "operation not permitted"}
+	ErrReferenceExists              = Error{Code: codeReferenceExists, Message: "one or more references exist to the schema"}
+	ErrBackendStore                 = Error{Code: codeBackendStore, Message: "error in the backend data store"}
+	ErrOperationTimeout             = Error{Code: codeOperationTimeout, Message: "operation timed out"}
+	ErrRequestForwarding            = Error{Code: codeRequestForwarding, Message: "error while forwarding the request to the primary"}
+)
+
+// Error is returned by the registry when there is an error.
+type Error struct {
+	StatusCode int `json:"-"`
+
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+// Error returns the error message.
+func (e Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+
+	return "registry error: " + strconv.Itoa(e.StatusCode)
+}
+
+// Is reports whether target is a registry Error carrying the same error code,
+// allowing errors.Is(err, ErrSubjectNotFound) style checks.
+func (e Error) Is(target error) bool {
+	t, ok := target.(Error)
+	if !ok || t.Code == 0 {
+		return false
+	}
+
+	return e.Code == t.Code
+}
@@ -0,0 +1,281 @@
+package gitops
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/registrytest"
+)
+
+const address = `{"type":"record","name":"Address","namespace":"com.test","fields":[{"name":"city","type":"string"}]}`
+
+const user = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"address","type":"com.test.Address"}]}`
+
+const userV2 = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"address","type":"com.test.Address"},{"name":"age","type":"int","default":0}]}`
+
+const userIncompatible = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"address","type":"com.test.Address"},{"name":"email","type":"string"}]}`
+
+const manifest = `{
+	"compatibility": "FULL",
+	"subjects": [
+		{
+			"subject": "user-value",
+			"file": "user.avsc",
+			"compatibility": "BACKWARD_TRANSITIVE",
+			"references": [{"name": "com.test.Address", "subject": "address-value", "version": 1}]
+		},
+		{"subject": "address-value", "file": "address.avsc"}
+	]
+}`
+
+func newClient(t *testing.T) *schemaregistry.SchemaClient {
+	t.Helper()
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+
+	c, err := schemaregistry.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal("newClient: ", err)
+	}
+
+	return c
+}
+
+func writeRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal("writeRepo: ", err)
+		}
+	}
+
+	return filepath.Join(dir, "manifest.json")
+}
+
+func loadManifest(t *testing.T, userSchema string) *Manifest {
+	t.Helper()
+	m, err := LoadManifest(writeRepo(t, map[string]string{
+		"manifest.json": manifest,
+		"address.avsc":  address,
+		"user.avsc":     userSchema,
+	}))
+	if err != nil {
+		t.Fatal("loadManifest: ", err)
+	}
+
+	return m
+}
+
+func TestPlanAndApply(t *testing.T) {
+	c := newClient(t)
+	if _, err := c.CreateSchema("orphan-value", address, schemaregistry.AVRO); err != nil {
+		t.Fatal("TestPlanAndApply: ", err)
+	}
+	m := loadManifest(t, user)
+
+	p, err := NewPlan(c, m, Options{})
+	if err != nil {
+		t.Fatal("TestPlanAndApply: ", err)
+	}
+	var got []string
+	for _, change := range p.Changes {
+		got = append(got, string(change.Type)+" "+change.Subject)
+	}
+	want := "CONFIG ,CONFIG user-value,REGISTER address-value,REGISTER user-value"
+	if strings.Join(got, ",") != want {
+		t.Errorf("TestPlanAndApply: got changes %v want %s", got, want)
+	}
+	if len(p.Drift) != 1 || p.Drift[0].Subject != "orphan-value" {
+		t.Error("TestPlanAndApply: unexpected drift", p.Drift)
+	}
+
+	if err := Apply(c, p); err != nil {
+		t.Fatal("TestPlanAndApply: ", err)
+	}
+	if level, err := c.GetConfig("user-value"); err != nil || level != schemaregistry.CompatibilityBackwardTransitive {
+		t.Error("TestPlanAndApply: subject config not applied", level, err)
+	}
+	if level, err := c.GetConfig(""); err != nil || level != schemaregistry.CompatibilityFull {
+		t.Error("TestPlanAndApply: global config not applied", level, err)
+	}
+
+	p, err = NewPlan(c, m, Options{})
+	if err != nil {
+		t.Fatal("TestPlanAndApply: ", err)
+	}
+	if p.HasChanges() {
+		t.Error("TestPlanAndApply: changes after apply", p)
+	}
+}
+
+func TestPlanPrune(t *testing.T) {
+	c := newClient(t)
+	if _, err := c.CreateSchema("orphan-value", address, schemaregistry.AVRO); err != nil {
+		t.Fatal("TestPlanPrune: ", err)
+	}
+
+	p, err := NewPlan(c, loadManifest(t, user), Options{Prune: true})
+	if err != nil {
+		t.Fatal("TestPlanPrune: ", err)
+	}
+	last := p.Changes[len(p.Changes)-1]
+	if last.Type != ChangeDelete || last.Subject != "orphan-value" || len(p.Drift) != 0 {
+		t.Fatal("TestPlanPrune: prune not planned", p)
+	}
+	if err := Apply(c, p); err != nil {
+		t.Fatal("TestPlanPrune: ", err)
+	}
+	if _, err := c.GetLatestSchema("orphan-value"); !errors.Is(err, schemaregistry.ErrSubjectNotFound) {
+		t.Error("TestPlanPrune: subject not deleted", err)
+	}
+}
+
+func TestApplyRefusesIncompatible(t *testing.T) {
+	c := newClient(t)
+	if err := Apply(c, mustPlan(t, c, loadManifest(t, user))); err != nil {
+		t.Fatal("TestApplyRefusesIncompatible: ", err)
+	}
+
+	p := mustPlan(t, c, loadManifest(t, userIncompatible))
+	if len(p.Incompatible()) != 1 || !strings.Contains(p.String(), "incompatible") {
+		t.Fatal("TestApplyRefusesIncompatible: incompatible schema not reported", p)
+	}
+	if err := Apply(c, p); !errors.Is(err, ErrIncompatible) {
+		t.Fatal("TestApplyRefusesIncompatible: unexpected error", err)
+	}
+	if versions, err := c.GetVersions("user-value"); err != nil || len(versions) != 1 {
+		t.Error("TestApplyRefusesIncompatible: registry changed", versions, err)
+	}
+}
+
+func TestPlanDrift(t *testing.T) {
+	c := newClient(t)
+	if err := Apply(c, mustPlan(t, c, loadManifest(t, user))); err != nil {
+		t.Fatal("TestPlanDrift: ", err)
+	}
+	ref := schemaregistry.Reference{Name: "com.test.Address", Subject: "address-value", Version: 1}
+	if _, err := c.CreateSchema("user-value", userV2, schemaregistry.AVRO, ref); err != nil {
+		t.Fatal("TestPlanDrift: ", err)
+	}
+
+	p := mustPlan(t, c, loadManifest(t, user))
+	if p.HasChanges() || len(p.Drift) != 1 || p.Drift[0].Subject != "user-value" {
+		t.Error("TestPlanDrift: drift not reported", p)
+	}
+}
+
+func TestPlanReferenceInPlan(t *testing.T) {
+	c := newClient(t)
+	if err := Apply(c, mustPlan(t, c, loadManifest(t, user))); err != nil {
+		t.Fatal("TestPlanReferenceInPlan: ", err)
+	}
+
+	addressV2 := `{"type":"record","name":"Address","namespace":"com.test","fields":[{"name":"city","type":"string"},{"name":"zip","type":"string","default":""}]}`
+	m, err := LoadManifest(writeRepo(t, map[string]string{
+		"manifest.json": strings.Replace(manifest, `"version": 1`, `"version": 2`, 1),
+		"address.avsc":  addressV2,
+		"user.avsc":     userV2,
+	}))
+	if err != nil {
+		t.Fatal("TestPlanReferenceInPlan: ", err)
+	}
+
+	p := mustPlan(t, c, m)
+	if len(p.Changes) != 2 || len(p.Incompatible()) != 0 {
+		t.Fatal("TestPlanReferenceInPlan: unexpected plan", p)
+	}
+	if err := Apply(c, p); err != nil {
+		t.Fatal("TestPlanReferenceInPlan: ", err)
+	}
+	latest, err := c.GetLatestSchema("user-value")
+	if err != nil || latest.Version != 2 || len(latest.References) != 1 || latest.References[0].Version != 2 {
+		t.Error("TestPlanReferenceInPlan: dependent not registered", latest, err)
+	}
+}
+
+func TestPlanPlannedLevel(t *testing.T) {
+	c := newClient(t)
+	if err := Apply(c, mustPlan(t, c, loadManifest(t, user))); err != nil {
+		t.Fatal("TestPlanPlannedLevel: ", err)
+	}
+
+	m, err := LoadManifest(writeRepo(t, map[string]string{
+		"manifest.json": strings.Replace(manifest, "BACKWARD_TRANSITIVE", "NONE", 1),
+		"address.avsc":  address,
+		"user.avsc":     userIncompatible,
+	}))
+	if err != nil {
+		t.Fatal("TestPlanPlannedLevel: ", err)
+	}
+	p := mustPlan(t, c, m)
+	if len(p.Incompatible()) != 0 {
+		t.Fatal("TestPlanPlannedLevel: change checked at the current level", p)
+	}
+	if err := Apply(c, p); err != nil {
+		t.Fatal("TestPlanPlannedLevel: ", err)
+	}
+	if versions, err := c.GetVersions("user-value"); err != nil || len(versions) != 2 {
+		t.Error("TestPlanPlannedLevel: schema not registered", versions, err)
+	}
+}
+
+func TestPlanSoftDeletedSubject(t *testing.T) {
+	c := newClient(t)
+	if _, err := c.CreateSchema("person-value", address, schemaregistry.AVRO); err != nil {
+		t.Fatal("TestPlanSoftDeletedSubject: ", err)
+	}
+	if _, err := c.DeleteSubject("person-value", false); err != nil {
+		t.Fatal("TestPlanSoftDeletedSubject: ", err)
+	}
+
+	// The person schema is registered as version 2 of the soft deleted
+	// subject, and does not define the type the account schema references.
+	person := `{"type":"record","name":"Person","namespace":"com.test","fields":[{"name":"name","type":"string"}]}`
+	account := `{"type":"record","name":"Account","namespace":"com.test","fields":[{"name":"owner","type":"com.test.Address"}]}`
+	m, err := LoadManifest(writeRepo(t, map[string]string{
+		"manifest.json": `{"subjects": [
+			{"subject": "account-value", "file": "account.avsc", "references": [{"name": "com.test.Address", "subject": "person-value", "version": 2}]},
+			{"subject": "person-value", "file": "person.avsc"}
+		]}`,
+		"person.avsc":  person,
+		"account.avsc": account,
+	}))
+	if err != nil {
+		t.Fatal("TestPlanSoftDeletedSubject: ", err)
+	}
+
+	p := mustPlan(t, c, m)
+	if len(p.Changes) != 2 || len(p.Incompatible()) != 1 || p.Incompatible()[0].Subject != "account-value" {
+		t.Error("TestPlanSoftDeletedSubject: reference to the planned version not checked", p)
+	}
+}
+
+func mustPlan(t *testing.T, c *schemaregistry.SchemaClient, m *Manifest) *Plan {
+	t.Helper()
+	p, err := NewPlan(c, m, Options{})
+	if err != nil {
+		t.Fatal("mustPlan: ", err)
+	}
+
+	return p
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	if _, err := LoadManifest(writeRepo(t, map[string]string{"manifest.json": `{"subjects":[{"subject":"a"}]}`})); err == nil {
+		t.Error("TestLoadManifestErrors: missing file not handled")
+	}
+	if _, err := LoadManifest(writeRepo(t, map[string]string{
+		"manifest.json": `{"subjects":[{"subject":"a","file":"a.avsc"},{"subject":"a","file":"a.avsc"}]}`,
+		"a.avsc":        address,
+	})); err == nil {
+		t.Error("TestLoadManifestErrors: duplicate subject not handled")
+	}
+	if _, err := LoadManifest(writeRepo(t, map[string]string{"manifest.json": `{"subjects":[{"subject":"a","file":"missing.avsc"}]}`})); err == nil {
+		t.Error("TestLoadManifestErrors: missing schema file not handled")
+	}
+}
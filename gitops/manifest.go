@@ -0,0 +1,86 @@
+package gitops
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	jsoniter "github.com/json-iterator/go"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+// Manifest maps subjects to schema files. It is read from JSON such as
+//
+//	{
+//	  "compatibility": "BACKWARD",
+//	  "subjects": [
+//	    {"subject": "address-value", "file": "address.avsc"},
+//	    {
+//	      "subject": "user-value",
+//	      "file": "user.avsc",
+//	      "compatibility": "FULL",
+//	      "references": [{"name": "com.acme.Address", "subject": "address-value", "version": 1}]
+//	    }
+//	  ]
+//	}
+//
+// where schema files are relative to the manifest.
+type Manifest struct {
+	// Compatibility is the global compatibility level, left unchanged if empty.
+	Compatibility schemaregistry.CompatibilityLevel `json:"compatibility,omitempty"`
+	Subjects      []SubjectSpec                     `json:"subjects"`
+}
+
+// SubjectSpec declares the schema a subject should have as its latest version.
+type SubjectSpec struct {
+	Subject    string                    `json:"subject"`
+	File       string                    `json:"file"`
+	SchemaType schemaregistry.SchemaType `json:"schemaType,omitempty"`
+	// Compatibility is the subject compatibility level, left unchanged if empty.
+	Compatibility schemaregistry.CompatibilityLevel `json:"compatibility,omitempty"`
+	References    []schemaregistry.Reference        `json:"references,omitempty"`
+	// Schema is the content of File, set by LoadManifest.
+	Schema string `json:"-"`
+}
+
+// LoadManifest reads the manifest at path and the schema files it names.
+func LoadManifest(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := jsoniter.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s err:%w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	seen := map[string]bool{}
+	for i := range m.Subjects {
+		spec := &m.Subjects[i]
+		if spec.Subject == "" || spec.File == "" {
+			return nil, errors.New("manifest subjects need a subject and a file")
+		}
+		if seen[spec.Subject] {
+			return nil, fmt.Errorf("subject %s is declared more than once", spec.Subject)
+		}
+		seen[spec.Subject] = true
+		if spec.SchemaType == "" {
+			spec.SchemaType = schemaregistry.AVRO
+		}
+
+		file := spec.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		schema, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		spec.Schema = string(schema)
+	}
+
+	return m, nil
+}
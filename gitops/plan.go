@@ -0,0 +1,551 @@
+// Package gitops reconciles a schema registry with schemas kept as files.
+//
+// A Manifest maps subjects to schema files, references and compatibility
+// levels. NewPlan compares it with the registry and lists the schemas that
+// would be registered, the configs that would change and the subjects that
+// drifted from the manifest. Apply carries the plan out, refusing to register
+// incompatible schemas.
+//
+// Compatibility is checked by the registry unless the plan changes the level
+// the check uses or the schema references a version the plan registers. Those
+// schemas are checked locally, at the planned level and with the planned
+// references, as they would be when the plan is applied.
+package gitops
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/compatibility"
+	"github.com/anjulapaulus/schema_registry/internal/refs"
+)
+
+// ErrIncompatible is returned by Apply when the plan registers a schema that
+// is incompatible with its subject.
+var ErrIncompatible = errors.New("plan registers incompatible schemas")
+
+// ChangeType is the kind of a planned change.
+type ChangeType string
+
+const (
+	// ChangeRegister registers the manifest schema as a new subject version.
+	ChangeRegister ChangeType = "REGISTER"
+	// ChangeConfig sets the compatibility level of a subject, or the global
+	// level if the subject is empty.
+	ChangeConfig ChangeType = "CONFIG"
+	// ChangeDelete soft deletes a subject that is not in the manifest.
+	ChangeDelete ChangeType = "DELETE"
+)
+
+// Change is a planned change to the registry.
+type Change struct {
+	Type    ChangeType `json:"type"`
+	Subject string     `json:"subject"`
+
+	// Spec is the manifest subject of register changes.
+	Spec *SubjectSpec `json:"-"`
+	// Compatible reports whether a register change passed the compatibility
+	// check, with the reasons in Messages if it did not.
+	Compatible bool     `json:"compatible,omitempty"`
+	Messages   []string `json:"messages,omitempty"`
+
+	// From and To are the current and desired levels of config changes.
+	From schemaregistry.CompatibilityLevel `json:"from,omitempty"`
+	To   schemaregistry.CompatibilityLevel `json:"to,omitempty"`
+}
+
+// Drift is a difference between the registry and the manifest that applying
+// the plan does not resolve.
+type Drift struct {
+	Subject string `json:"subject"`
+	Reason  string `json:"reason"`
+}
+
+// Options configures NewPlan.
+type Options struct {
+	// Prune plans the deletion of subjects that are not in the manifest.
+	// Otherwise they are reported as drift.
+	Prune bool
+}
+
+// Plan lists the changes that reconcile the registry with a manifest, in the
+// order Apply makes them.
+type Plan struct {
+	Changes []Change `json:"changes"`
+	Drift   []Drift  `json:"drift"`
+}
+
+// planner holds the state of the registry the plan leads to, for the
+// compatibility checks of the registrations that depend on it.
+type planner struct {
+	c *schemaregistry.SchemaClient
+	p *Plan
+
+	// global and planGlobal are the current and planned global levels.
+	global, planGlobal schemaregistry.CompatibilityLevel
+	// levels and planLevels are the current and planned subject levels,
+	// empty for subjects using the global level.
+	levels, planLevels map[string]schemaregistry.CompatibilityLevel
+	// versions are the subject versions the plan registers.
+	versions map[string]plannedVersion
+}
+
+type plannedVersion struct {
+	version int
+	spec    *SubjectSpec
+}
+
+// NewPlan compares the registry with the manifest. Compatibility is checked
+// at the levels the plan sets.
+func NewPlan(c *schemaregistry.SchemaClient, m *Manifest, opts Options) (*Plan, error) {
+	p := &Plan{Changes: []Change{}, Drift: []Drift{}}
+	pl := &planner{
+		c:          c,
+		p:          p,
+		levels:     make(map[string]schemaregistry.CompatibilityLevel),
+		planLevels: make(map[string]schemaregistry.CompatibilityLevel),
+		versions:   make(map[string]plannedVersion),
+	}
+
+	var err error
+	if pl.global, err = c.GetConfig(""); err != nil {
+		return nil, fmt.Errorf("error obtaining global config err:%w", err)
+	}
+	pl.planGlobal = pl.global
+	if m.Compatibility != "" && m.Compatibility != pl.global {
+		p.Changes = append(p.Changes, Change{Type: ChangeConfig, From: pl.global, To: m.Compatibility})
+		pl.planGlobal = m.Compatibility
+	}
+
+	specs, err := dependencyOrder(m.Subjects)
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range specs {
+		level, err := c.GetConfig(spec.Subject)
+		if err != nil && !errors.Is(err, schemaregistry.ErrSubjectCompatibilityNotFound) {
+			return nil, fmt.Errorf("error obtaining config for subject:%s err:%w", spec.Subject, err)
+		}
+		pl.levels[spec.Subject] = level
+		pl.planLevels[spec.Subject] = level
+		if spec.Compatibility != "" && level != spec.Compatibility {
+			p.Changes = append(p.Changes, Change{Type: ChangeConfig, Subject: spec.Subject, From: level, To: spec.Compatibility})
+			pl.planLevels[spec.Subject] = spec.Compatibility
+		}
+	}
+
+	for _, spec := range specs {
+		if err := pl.planSubject(spec); err != nil {
+			return nil, err
+		}
+	}
+
+	subjects, err := c.GetSubjects()
+	if err != nil {
+		return nil, fmt.Errorf("error obtaining subjects err:%w", err)
+	}
+	managed := make(map[string]bool, len(m.Subjects))
+	for _, spec := range m.Subjects {
+		managed[spec.Subject] = true
+	}
+	sort.Strings(subjects)
+	for _, subject := range subjects {
+		if managed[subject] {
+			continue
+		}
+		if opts.Prune {
+			p.Changes = append(p.Changes, Change{Type: ChangeDelete, Subject: subject})
+		} else {
+			p.Drift = append(p.Drift, Drift{Subject: subject, Reason: "subject is not in the manifest"})
+		}
+	}
+
+	return p, nil
+}
+
+func (pl *planner) planSubject(spec *SubjectSpec) error {
+	c, p := pl.c, pl.p
+	found, err := c.LookupSchema(spec.Subject, spec.Schema, spec.SchemaType, spec.References...)
+	switch {
+	case err == nil:
+		latest, err := c.GetLatestSchema(spec.Subject)
+		if err != nil {
+			return fmt.Errorf("error obtaining latest schema for subject:%s err:%w", spec.Subject, err)
+		}
+		if latest.Version != found.Version {
+			p.Drift = append(p.Drift, Drift{
+				Subject: spec.Subject,
+				Reason:  "manifest schema is version " + strconv.Itoa(found.Version) + " but the latest version is " + strconv.Itoa(latest.Version),
+			})
+		}
+		return nil
+	case errors.Is(err, schemaregistry.ErrSubjectNotFound):
+		// The subject may be soft deleted, in which case its versions keep
+		// counting from the deleted ones.
+		if err := pl.planVersion(spec); err != nil {
+			return err
+		}
+		change := Change{Type: ChangeRegister, Subject: spec.Subject, Spec: spec, Compatible: true}
+		if pl.inPlan(spec.References) {
+			change.Compatible, change.Messages, err = pl.checkLocal(spec, nil)
+			if err != nil {
+				return err
+			}
+		}
+		p.Changes = append(p.Changes, change)
+		return nil
+	case !errors.Is(err, schemaregistry.ErrSchemaNotFound):
+		return fmt.Errorf("error looking up schema for subject:%s err:%w", spec.Subject, err)
+	}
+
+	if err := pl.planVersion(spec); err != nil {
+		return err
+	}
+
+	change := Change{Type: ChangeRegister, Subject: spec.Subject, Spec: spec}
+	if pl.inPlan(spec.References) || pl.level(spec.Subject, pl.planLevels, pl.planGlobal) != pl.level(spec.Subject, pl.levels, pl.global) {
+		versions, err := c.GetVersions(spec.Subject)
+		if err != nil {
+			return fmt.Errorf("error obtaining versions for subject:%s err:%w", spec.Subject, err)
+		}
+		change.Compatible, change.Messages, err = pl.checkLocal(spec, versions)
+		if err != nil {
+			return err
+		}
+		p.Changes = append(p.Changes, change)
+		return nil
+	}
+
+	resp, err := c.CheckCompatibility(spec.Subject, spec.Schema, spec.SchemaType, int(schemaregistry.AllVersions), spec.References...)
+	var regErr schemaregistry.Error
+	switch {
+	case err == nil:
+		change.Compatible = resp.IsCompatible
+		change.Messages = resp.Messages
+	case errors.As(err, &regErr) && regErr.Code/100 == 422:
+		change.Messages = []string{regErr.Message}
+	default:
+		return fmt.Errorf("error checking compatibility for subject:%s err:%w", spec.Subject, err)
+	}
+	p.Changes = append(p.Changes, change)
+
+	return nil
+}
+
+// planVersion records the version the registry will give the manifest schema,
+// the one after the highest version of the subject, soft deleted ones included.
+func (pl *planner) planVersion(spec *SubjectSpec) error {
+	versions, err := pl.c.GetVersionsWithDeleted(spec.Subject)
+	if err != nil && !errors.Is(err, schemaregistry.ErrSubjectNotFound) {
+		return fmt.Errorf("error obtaining versions for subject:%s err:%w", spec.Subject, err)
+	}
+	next := 1
+	for _, v := range versions {
+		if v >= next {
+			next = v + 1
+		}
+	}
+	pl.versions[spec.Subject] = plannedVersion{version: next, spec: spec}
+
+	return nil
+}
+
+// level returns the level of the subject given the subject and global levels.
+func (pl *planner) level(subject string, levels map[string]schemaregistry.CompatibilityLevel, global schemaregistry.CompatibilityLevel) schemaregistry.CompatibilityLevel {
+	if level := levels[subject]; level != "" {
+		return level
+	}
+
+	return global
+}
+
+// inPlan reports whether any of the references is a version the plan registers.
+func (pl *planner) inPlan(references []schemaregistry.Reference) bool {
+	for _, ref := range references {
+		if v, ok := pl.versions[ref.Subject]; ok && v.version == ref.Version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// plannedSchema is a schema of the registry once the plan is applied.
+type plannedSchema struct {
+	name       string
+	schemaType schemaregistry.SchemaType
+	schema     string
+	references []schemaregistry.Reference
+}
+
+// schema returns the schema registered under the subject version, or about to
+// be registered by the plan.
+func (pl *planner) schema(subject string, version int) (plannedSchema, error) {
+	if v, ok := pl.versions[subject]; ok && v.version == version {
+		return plannedSchema{schemaType: v.spec.SchemaType, schema: v.spec.Schema, references: v.spec.References}, nil
+	}
+
+	resp, err := pl.c.GetSchemaByVersion(subject, version)
+	if err != nil {
+		return plannedSchema{}, fmt.Errorf("error obtaining subject:%s version:%d err:%w", subject, version, err)
+	}
+	schemaType := schemaregistry.AVRO
+	if resp.SchemaType != nil && *resp.SchemaType != "" {
+		schemaType = *resp.SchemaType
+	}
+
+	return plannedSchema{schemaType: schemaType, schema: resp.Schema, references: resp.References}, nil
+}
+
+// resolve returns the schemas the references point to, dependencies first.
+func (pl *planner) resolve(references []schemaregistry.Reference, seen map[string]bool) ([]plannedSchema, error) {
+	var out []plannedSchema
+	for _, ref := range references {
+		key := refs.VersionKey(ref.Subject, ref.Version)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		s, err := pl.schema(ref.Subject, ref.Version)
+		if err != nil {
+			return nil, err
+		}
+		s.name = ref.Name
+		deps, err := pl.resolve(s.references, seen)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, deps...)
+		out = append(out, s)
+	}
+
+	return out, nil
+}
+
+// checkLocal checks the manifest schema against the given versions of its
+// subject with the compatibility package, at the planned subject level.
+func (pl *planner) checkLocal(spec *SubjectSpec, versions []int) (bool, []string, error) {
+	level := pl.level(spec.Subject, pl.planLevels, pl.planGlobal)
+	candidate := plannedSchema{schemaType: spec.SchemaType, schema: spec.Schema, references: spec.References}
+
+	var messages []string
+	var previous []plannedSchema
+	for _, version := range versions {
+		s, err := pl.schema(spec.Subject, version)
+		if err != nil {
+			return false, nil, err
+		}
+		if s.schemaType != spec.SchemaType {
+			messages = append(messages, "Incompatible schema type "+string(s.schemaType)+" at version "+strconv.Itoa(version))
+			continue
+		}
+		previous = append(previous, s)
+	}
+
+	var res compatibility.Result
+	var err error
+	switch spec.SchemaType {
+	case schemaregistry.PROTOBUF:
+		res, err = pl.checkProtobuf(level, candidate, previous)
+	case schemaregistry.JSONSCHEMA:
+		texts := make([]string, len(previous))
+		for i, s := range previous {
+			texts[i] = s.schema
+		}
+		res, err = compatibility.CheckJSONSchema(level, candidate.schema, texts...)
+	default:
+		res, err = pl.checkAvro(level, candidate, previous)
+	}
+	if err != nil {
+		return false, []string{err.Error()}, nil
+	}
+	messages = append(messages, res.Messages()...)
+
+	return len(messages) == 0, messages, nil
+}
+
+func (pl *planner) checkAvro(level schemaregistry.CompatibilityLevel, candidate plannedSchema, previous []plannedSchema) (compatibility.Result, error) {
+	parse := func(s plannedSchema) (*compatibility.AvroSchema, error) {
+		resolved, err := pl.resolve(s.references, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		texts := make([]string, len(resolved))
+		for i, ref := range resolved {
+			texts[i] = ref.schema
+		}
+		return compatibility.ParseAvro(s.schema, texts...)
+	}
+
+	parsed, err := parse(candidate)
+	if err != nil {
+		return compatibility.Result{}, err
+	}
+	existing := make([]*compatibility.AvroSchema, len(previous))
+	for i, s := range previous {
+		if existing[i], err = parse(s); err != nil {
+			return compatibility.Result{}, err
+		}
+	}
+
+	return compatibility.CheckAvroSchemas(level, parsed, existing...)
+}
+
+func (pl *planner) checkProtobuf(level schemaregistry.CompatibilityLevel, candidate plannedSchema, previous []plannedSchema) (compatibility.Result, error) {
+	proto := func(s plannedSchema) (compatibility.ProtobufSchema, error) {
+		resolved, err := pl.resolve(s.references, map[string]bool{})
+		if err != nil {
+			return compatibility.ProtobufSchema{}, err
+		}
+		out := compatibility.ProtobufSchema{Schema: s.schema, References: make(map[string]string, len(resolved))}
+		for _, ref := range resolved {
+			out.References[ref.name] = ref.schema
+		}
+		return out, nil
+	}
+
+	parsed, err := proto(candidate)
+	if err != nil {
+		return compatibility.Result{}, err
+	}
+	existing := make([]compatibility.ProtobufSchema, len(previous))
+	for i, s := range previous {
+		if existing[i], err = proto(s); err != nil {
+			return compatibility.Result{}, err
+		}
+	}
+
+	return compatibility.CheckProtobuf(level, parsed, existing...)
+}
+
+// dependencyOrder orders the subjects so that subjects come after the manifest
+// subjects they reference, keeping the manifest order otherwise.
+func dependencyOrder(subjects []SubjectSpec) ([]*SubjectSpec, error) {
+	index := make(map[string]int, len(subjects))
+	for i, spec := range subjects {
+		index[spec.Subject] = i
+	}
+
+	order, err := refs.Sort(len(subjects), func(i int) []int {
+		var deps []int
+		for _, ref := range subjects[i].References {
+			if j, ok := index[ref.Subject]; ok {
+				deps = append(deps, j)
+			}
+		}
+		return deps
+	}, func(i int) error {
+		return fmt.Errorf("reference cycle at subject %s", subjects[i].Subject)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*SubjectSpec, len(order))
+	for i, j := range order {
+		out[i] = &subjects[j]
+	}
+
+	return out, nil
+}
+
+// HasChanges reports whether applying the plan would change the registry.
+func (p *Plan) HasChanges() bool {
+	return len(p.Changes) > 0
+}
+
+// Incompatible returns the register changes that failed the compatibility check.
+func (p *Plan) Incompatible() []Change {
+	var out []Change
+	for _, change := range p.Changes {
+		if change.Type == ChangeRegister && !change.Compatible {
+			out = append(out, change)
+		}
+	}
+
+	return out
+}
+
+// String formats the plan for review.
+func (p *Plan) String() string {
+	var b strings.Builder
+	if !p.HasChanges() {
+		b.WriteString("No changes.\n")
+	}
+	for _, change := range p.Changes {
+		switch change.Type {
+		case ChangeRegister:
+			fmt.Fprintf(&b, "+ register %s (%s)\n", change.Subject, change.Spec.File)
+			if !change.Compatible {
+				b.WriteString("    incompatible:\n")
+				for _, msg := range change.Messages {
+					fmt.Fprintf(&b, "      %s\n", msg)
+				}
+			}
+		case ChangeConfig:
+			subject := change.Subject
+			if subject == "" {
+				subject = "global"
+			}
+			from := string(change.From)
+			if from == "" {
+				from = "(unset)"
+			}
+			fmt.Fprintf(&b, "~ config %s: %s -> %s\n", subject, from, change.To)
+		case ChangeDelete:
+			fmt.Fprintf(&b, "- delete %s\n", change.Subject)
+		}
+	}
+	for _, drift := range p.Drift {
+		fmt.Fprintf(&b, "! drift %s: %s\n", drift.Subject, drift.Reason)
+	}
+
+	return b.String()
+}
+
+// Apply makes the planned changes: configs first, then schema registrations in
+// dependency order, then deletions. Nothing is changed if any registration
+// failed the compatibility check.
+func Apply(c *schemaregistry.SchemaClient, p *Plan) error {
+	if incompatible := p.Incompatible(); len(incompatible) > 0 {
+		subjects := make([]string, 0, len(incompatible))
+		for _, change := range incompatible {
+			subjects = append(subjects, change.Subject)
+		}
+		return fmt.Errorf("%w: %s", ErrIncompatible, strings.Join(subjects, ", "))
+	}
+
+	for _, typ := range []ChangeType{ChangeConfig, ChangeRegister, ChangeDelete} {
+		for _, change := range p.Changes {
+			if change.Type != typ {
+				continue
+			}
+			if err := apply(c, change); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func apply(c *schemaregistry.SchemaClient, change Change) error {
+	var err error
+	switch change.Type {
+	case ChangeConfig:
+		_, err = c.SetConfig(change.Subject, change.To)
+	case ChangeRegister:
+		_, err = c.CreateSchema(change.Subject, change.Spec.Schema, change.Spec.SchemaType, change.Spec.References...)
+	case ChangeDelete:
+		_, err = c.DeleteSubject(change.Subject, false)
+	}
+	if err != nil {
+		return fmt.Errorf("error applying %s to subject:%s err:%w", strings.ToLower(string(change.Type)), change.Subject, err)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ module github.com/anjulapaulus/schema_registry
 go 1.16
 
 require (
+	github.com/emicklei/proto v1.13.2
 	github.com/hamba/avro v1.6.5
 	github.com/json-iterator/go v1.1.12
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
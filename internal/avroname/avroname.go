@@ -0,0 +1,46 @@
+// Package avroname resolves AVRO type names against namespaces, shared by
+// normalization, schema diffs and the AVRO compatibility checks.
+package avroname
+
+import "strings"
+
+var primitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+// Primitive reports whether name is an AVRO primitive type.
+func Primitive(name string) bool {
+	return primitives[name]
+}
+
+// Qualify returns the full name name refers to in the namespace. Full names
+// and primitive types are returned unchanged.
+func Qualify(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" || primitives[name] {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+// FullName returns the full name of the named type declared by m in the
+// enclosing namespace, honouring its namespace attribute.
+func FullName(m map[string]interface{}, namespace string) string {
+	name, _ := m["name"].(string)
+	if ns, ok := m["namespace"].(string); ok && !strings.Contains(name, ".") {
+		namespace = ns
+	}
+
+	return Qualify(name, namespace)
+}
+
+// Namespace returns the namespace of the full name, the one the names
+// declared inside the type resolve in.
+func Namespace(fullName string) string {
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		return fullName[:i]
+	}
+
+	return ""
+}
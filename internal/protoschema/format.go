@@ -0,0 +1,229 @@
+package protoschema
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format prints the file in a normalized form: comments and formatting are
+// dropped, imports, options, definitions and services are sorted by name,
+// fields and enum values by number, and resolved type names are fully
+// qualified. Files that differ only in these respects format identically.
+func Format(f *File) string {
+	p := &printer{}
+
+	p.line(0, "syntax = "+strconv.Quote(f.Syntax)+";")
+	if f.Package != "" {
+		p.line(0, "package "+f.Package+";")
+	}
+	imports := append([]string(nil), f.Imports...)
+	sort.Strings(imports)
+	for _, imp := range imports {
+		p.line(0, "import "+strconv.Quote(imp)+";")
+	}
+	p.options(0, f.Options)
+
+	messages := append([]*Message(nil), f.Messages...)
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Name < messages[j].Name })
+	for _, m := range messages {
+		p.message(0, m)
+	}
+	p.enums(0, f.Enums)
+	p.extensions(0, f.Extensions)
+
+	services := append([]*Service(nil), f.Services...)
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	for _, s := range services {
+		p.service(s)
+	}
+
+	return p.String()
+}
+
+type printer struct {
+	strings.Builder
+}
+
+func (p *printer) line(indent int, s string) {
+	p.WriteString(strings.Repeat("  ", indent))
+	p.WriteString(s)
+	p.WriteByte('\n')
+}
+
+func sortedOptions(opts []Option) []Option {
+	out := append([]Option(nil), opts...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+
+	return out
+}
+
+func (p *printer) options(indent int, opts []Option) {
+	for _, o := range sortedOptions(opts) {
+		p.line(indent, "option "+o.Name+" = "+o.Value+";")
+	}
+}
+
+func inlineOptions(opts []Option) string {
+	if len(opts) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(opts))
+	for _, o := range sortedOptions(opts) {
+		parts = append(parts, o.Name+" = "+o.Value)
+	}
+
+	return " [" + strings.Join(parts, ", ") + "]"
+}
+
+func typeName(written, resolved string, kind Kind) string {
+	switch kind {
+	case Scalar:
+		return resolved
+	case MessageKind, EnumKind:
+		return "." + resolved
+	}
+
+	return written
+}
+
+func fieldType(f *Field) string {
+	if f.Kind == MapKind {
+		return "map<" + f.KeyType + ", " + typeName(f.Type, f.ResolvedType, f.ValueKind) + ">"
+	}
+
+	return typeName(f.Type, f.ResolvedType, f.Kind)
+}
+
+func (p *printer) field(indent int, f *Field) {
+	label := ""
+	switch {
+	case f.Repeated:
+		label = "repeated "
+	case f.Required:
+		label = "required "
+	case f.Optional:
+		label = "optional "
+	}
+	p.line(indent, label+fieldType(f)+" "+f.Name+" = "+strconv.Itoa(f.Number)+inlineOptions(f.Options)+";")
+}
+
+func sortedFields(fields []*Field) []*Field {
+	out := append([]*Field(nil), fields...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Number < out[j].Number })
+
+	return out
+}
+
+func (p *printer) reserved(indent int, ranges []Range, names []string) {
+	if len(ranges) > 0 {
+		sorted := append([]Range(nil), ranges...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].From < sorted[j].From })
+		parts := make([]string, 0, len(sorted))
+		for _, r := range sorted {
+			switch {
+			case r.From == r.To:
+				parts = append(parts, strconv.Itoa(r.From))
+			case r.To == maxFieldNumber:
+				parts = append(parts, strconv.Itoa(r.From)+" to max")
+			default:
+				parts = append(parts, strconv.Itoa(r.From)+" to "+strconv.Itoa(r.To))
+			}
+		}
+		p.line(indent, "reserved "+strings.Join(parts, ", ")+";")
+	}
+	if len(names) > 0 {
+		sorted := append([]string(nil), names...)
+		sort.Strings(sorted)
+		quoted := make([]string, 0, len(sorted))
+		for _, n := range sorted {
+			quoted = append(quoted, strconv.Quote(n))
+		}
+		p.line(indent, "reserved "+strings.Join(quoted, ", ")+";")
+	}
+}
+
+func (p *printer) message(indent int, m *Message) {
+	p.line(indent, "message "+m.Name+" {")
+	p.options(indent+1, m.Options)
+	p.reserved(indent+1, m.ReservedRanges, m.ReservedNames)
+	for _, f := range sortedFields(m.Fields) {
+		if f.Oneof == "" {
+			p.field(indent+1, f)
+		}
+	}
+
+	oneofs := append([]*Oneof(nil), m.Oneofs...)
+	sort.Slice(oneofs, func(i, j int) bool { return oneofs[i].Name < oneofs[j].Name })
+	for _, o := range oneofs {
+		p.line(indent+1, "oneof "+o.Name+" {")
+		p.options(indent+2, o.Options)
+		for _, f := range sortedFields(o.Fields) {
+			p.field(indent+2, f)
+		}
+		p.line(indent+1, "}")
+	}
+
+	nested := append([]*Message(nil), m.Messages...)
+	sort.Slice(nested, func(i, j int) bool { return nested[i].Name < nested[j].Name })
+	for _, n := range nested {
+		p.message(indent+1, n)
+	}
+	p.enums(indent+1, m.Enums)
+	p.extensions(indent+1, m.Extensions)
+	p.line(indent, "}")
+}
+
+func (p *printer) enums(indent int, enums []*Enum) {
+	sorted := append([]*Enum(nil), enums...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for _, e := range sorted {
+		p.line(indent, "enum "+e.Name+" {")
+		p.options(indent+1, e.Options)
+		p.reserved(indent+1, e.ReservedRanges, e.ReservedNames)
+		values := append([]*EnumValue(nil), e.Values...)
+		sort.SliceStable(values, func(i, j int) bool { return values[i].Number < values[j].Number })
+		for _, v := range values {
+			p.line(indent+1, v.Name+" = "+strconv.Itoa(v.Number)+inlineOptions(v.Options)+";")
+		}
+		p.line(indent, "}")
+	}
+}
+
+func (p *printer) extensions(indent int, extensions []*Extension) {
+	sorted := append([]*Extension(nil), extensions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ResolvedExtendee < sorted[j].ResolvedExtendee })
+	for _, ext := range sorted {
+		p.line(indent, "extend "+typeName(ext.Extendee, ext.ResolvedExtendee, ext.ExtendeeKind)+" {")
+		for _, f := range sortedFields(ext.Fields) {
+			p.field(indent+1, f)
+		}
+		p.line(indent, "}")
+	}
+}
+
+func (p *printer) service(s *Service) {
+	p.line(0, "service "+s.Name+" {")
+	p.options(1, s.Options)
+	rpcs := append([]*RPC(nil), s.RPCs...)
+	sort.Slice(rpcs, func(i, j int) bool { return rpcs[i].Name < rpcs[j].Name })
+	for _, r := range rpcs {
+		req := typeName(r.RequestType, r.ResolvedRequestType, r.RequestKind)
+		resp := typeName(r.ReturnsType, r.ResolvedReturnsType, r.ReturnsKind)
+		if r.StreamsRequest {
+			req = "stream " + req
+		}
+		if r.StreamsReturns {
+			resp = "stream " + resp
+		}
+		signature := "rpc " + r.Name + "(" + req + ") returns (" + resp + ")"
+		if len(r.Options) == 0 {
+			p.line(1, signature+";")
+			continue
+		}
+		p.line(1, signature+" {")
+		p.options(2, r.Options)
+		p.line(1, "}")
+	}
+	p.line(0, "}")
+}
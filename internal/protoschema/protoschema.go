@@ -0,0 +1,560 @@
+// Package protoschema parses .proto schema text into a simplified model with
+// resolved type names, shared by the protobuf compatibility checks and
+// normalization.
+package protoschema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/emicklei/proto"
+)
+
+// Kind is the kind of a field type.
+type Kind int
+
+const (
+	Scalar Kind = iota
+	MessageKind
+	EnumKind
+	MapKind
+	Unresolved
+)
+
+var scalars = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true,
+	"uint64": true, "sint32": true, "sint64": true, "fixed32": true, "fixed64": true,
+	"sfixed32": true, "sfixed64": true, "bool": true, "string": true, "bytes": true,
+}
+
+// IsScalar reports whether the type name is a protobuf scalar type.
+func IsScalar(typ string) bool {
+	return scalars[typ]
+}
+
+// File is a parsed .proto schema.
+type File struct {
+	Syntax     string
+	Package    string
+	Imports    []string
+	Options    []Option
+	Messages   []*Message
+	Enums      []*Enum
+	Services   []*Service
+	Extensions []*Extension
+
+	messages map[string]*Message
+	enums    map[string]*Enum
+}
+
+// Option is a name = value option, with the value as written in the source.
+type Option struct {
+	Name  string
+	Value string
+}
+
+// Message is a message definition.
+type Message struct {
+	Name       string
+	FullName   string
+	Doc        string
+	Fields     []*Field
+	Oneofs     []*Oneof
+	Messages   []*Message
+	Enums      []*Enum
+	Extensions []*Extension
+	Options    []Option
+
+	ReservedRanges []Range
+	ReservedNames  []string
+}
+
+// Extension is an extend block adding fields to another message.
+type Extension struct {
+	Extendee         string
+	ResolvedExtendee string
+	ExtendeeKind     Kind
+	Fields           []*Field
+	scope            string
+}
+
+// Range is an inclusive range of field or enum numbers.
+type Range struct {
+	From, To int
+}
+
+// Contains reports whether the number is in the range.
+func (r Range) Contains(n int) bool {
+	return n >= r.From && n <= r.To
+}
+
+// maxFieldNumber is the largest valid field number, used for "max" in reserved ranges.
+const maxFieldNumber = 536870911
+
+// Field is a message field.
+type Field struct {
+	Name   string
+	Number int
+	// Type is the type as written in the schema; for maps it is the value type.
+	Type string
+	// ResolvedType is the scalar name or the fully qualified name of a message
+	// or enum type, without a leading dot. For maps it is the value type.
+	ResolvedType string
+	Kind         Kind
+	// ValueKind is the kind of a map value type.
+	ValueKind Kind
+	KeyType   string
+	Repeated  bool
+	Optional  bool
+	Required  bool
+	Oneof     string
+	Options   []Option
+	Doc       string
+}
+
+// Oneof is a oneof definition within a message.
+type Oneof struct {
+	Name    string
+	Fields  []*Field
+	Options []Option
+}
+
+// Enum is an enum definition.
+type Enum struct {
+	Name     string
+	FullName string
+	Doc      string
+	Values   []*EnumValue
+	Options  []Option
+
+	ReservedRanges []Range
+	ReservedNames  []string
+}
+
+// EnumValue is an enum constant.
+type EnumValue struct {
+	Name    string
+	Number  int
+	Options []Option
+	Doc     string
+}
+
+// Service is a service definition.
+type Service struct {
+	Name    string
+	RPCs    []*RPC
+	Options []Option
+	Doc     string
+}
+
+// RPC is a service method.
+type RPC struct {
+	Name                string
+	RequestType         string
+	ResolvedRequestType string
+	RequestKind         Kind
+	StreamsRequest      bool
+	ReturnsType         string
+	ResolvedReturnsType string
+	ReturnsKind         Kind
+	StreamsReturns      bool
+	Options             []Option
+	Doc                 string
+}
+
+// Message returns the message with the given fully qualified name.
+func (f *File) Message(fullName string) (*Message, bool) {
+	m, ok := f.messages[fullName]
+	return m, ok
+}
+
+// Enum returns the enum with the given fully qualified name.
+func (f *File) Enum(fullName string) (*Enum, bool) {
+	e, ok := f.enums[fullName]
+	return e, ok
+}
+
+// MessageNames returns the fully qualified names of all messages defined in
+// the file, nested ones included, in sorted order.
+func (f *File) MessageNames() []string {
+	names := make([]string, 0, len(f.messages))
+	for name := range f.messages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// EnumNames returns the fully qualified names of all enums defined in the
+// file, nested ones included, in sorted order.
+func (f *File) EnumNames() []string {
+	names := make([]string, 0, len(f.enums))
+	for name := range f.enums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Parse parses the schema. References maps import paths to the text of the
+// imported schemas; their types are used to resolve field type names.
+func Parse(schema string, references map[string]string) (*File, error) {
+	f, err := parse(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := map[string]Kind{}
+	f.addSymbols(symbols)
+
+	seen := map[string]bool{}
+	imports := append([]string(nil), f.Imports...)
+	for len(imports) > 0 {
+		path := imports[0]
+		imports = imports[1:]
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		text, ok := references[path]
+		if !ok {
+			// Well known and other unresolvable imports keep their type names as written.
+			continue
+		}
+		dep, err := parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("reference %s: %w", path, err)
+		}
+		dep.addSymbols(symbols)
+		imports = append(imports, dep.Imports...)
+	}
+
+	for _, m := range f.messages {
+		for _, field := range m.Fields {
+			resolveField(field, m.FullName, symbols)
+		}
+	}
+	for _, ext := range f.allExtensions() {
+		ext.ResolvedExtendee, ext.ExtendeeKind = resolveType(ext.Extendee, ext.scope, symbols)
+		for _, field := range ext.Fields {
+			resolveField(field, ext.scope, symbols)
+		}
+	}
+	for _, svc := range f.Services {
+		for _, rpc := range svc.RPCs {
+			rpc.ResolvedRequestType, rpc.RequestKind = resolveType(rpc.RequestType, f.Package, symbols)
+			rpc.ResolvedReturnsType, rpc.ReturnsKind = resolveType(rpc.ReturnsType, f.Package, symbols)
+		}
+	}
+
+	return f, nil
+}
+
+func (f *File) allExtensions() []*Extension {
+	out := append([]*Extension(nil), f.Extensions...)
+	for _, m := range f.messages {
+		out = append(out, m.Extensions...)
+	}
+
+	return out
+}
+
+func (f *File) addSymbols(symbols map[string]Kind) {
+	for name := range f.messages {
+		symbols[name] = MessageKind
+	}
+	for name := range f.enums {
+		symbols[name] = EnumKind
+	}
+}
+
+func resolveField(field *Field, scope string, symbols map[string]Kind) {
+	if field.Kind == MapKind {
+		field.ResolvedType, field.ValueKind = resolveType(field.Type, scope, symbols)
+		return
+	}
+	field.ResolvedType, field.Kind = resolveType(field.Type, scope, symbols)
+}
+
+// resolveType resolves a type name following the protobuf scoping rules:
+// the innermost enclosing scope is searched first.
+func resolveType(typ, scope string, symbols map[string]Kind) (string, Kind) {
+	if IsScalar(typ) {
+		return typ, Scalar
+	}
+	if strings.HasPrefix(typ, ".") {
+		name := strings.TrimPrefix(typ, ".")
+		if kind, ok := symbols[name]; ok {
+			return name, kind
+		}
+		return name, Unresolved
+	}
+
+	for {
+		candidate := typ
+		if scope != "" {
+			candidate = scope + "." + typ
+		}
+		if kind, ok := symbols[candidate]; ok {
+			return candidate, kind
+		}
+		if scope == "" {
+			return typ, Unresolved
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+func parse(schema string) (*File, error) {
+	parsed, err := proto.NewParser(strings.NewReader(schema)).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		Syntax:   "proto2",
+		messages: make(map[string]*Message),
+		enums:    make(map[string]*Enum),
+	}
+	for _, e := range parsed.Elements {
+		switch v := e.(type) {
+		case *proto.Syntax:
+			f.Syntax = v.Value
+		case *proto.Package:
+			f.Package = v.Name
+		case *proto.Import:
+			f.Imports = append(f.Imports, v.Filename)
+		case *proto.Option:
+			f.Options = append(f.Options, option(v))
+		}
+	}
+	for _, e := range parsed.Elements {
+		switch v := e.(type) {
+		case *proto.Message:
+			if v.IsExtend {
+				f.Extensions = append(f.Extensions, extension(v, f.Package))
+				continue
+			}
+			f.Messages = append(f.Messages, f.message(v, f.Package))
+		case *proto.Enum:
+			f.Enums = append(f.Enums, f.enum(v, f.Package))
+		case *proto.Service:
+			f.Services = append(f.Services, service(v))
+		}
+	}
+
+	return f, nil
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+
+	return scope + "." + name
+}
+
+func option(o *proto.Option) Option {
+	return Option{Name: o.Name, Value: literal(&o.Constant, o.AggregatedConstants)}
+}
+
+func literal(l *proto.Literal, aggregated []*proto.NamedLiteral) string {
+	if len(aggregated) > 0 {
+		parts := make([]string, 0, len(aggregated))
+		for _, a := range aggregated {
+			parts = append(parts, a.Name+": "+literal(a.Literal, nil))
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	}
+
+	return l.SourceRepresentation()
+}
+
+func options(opts []*proto.Option) []Option {
+	var out []Option
+	for _, o := range opts {
+		out = append(out, option(o))
+	}
+
+	return out
+}
+
+func (f *File) message(m *proto.Message, scope string) *Message {
+	msg := &Message{Name: m.Name, FullName: qualify(scope, m.Name), Doc: doc(m.Comment, nil)}
+	f.messages[msg.FullName] = msg
+
+	for _, e := range m.Elements {
+		switch v := e.(type) {
+		case *proto.NormalField:
+			msg.Fields = append(msg.Fields, &Field{
+				Name:     v.Name,
+				Number:   v.Sequence,
+				Type:     v.Type,
+				Repeated: v.Repeated,
+				Optional: v.Optional,
+				Required: v.Required,
+				Options:  options(v.Options),
+				Doc:      doc(v.Comment, v.InlineComment),
+			})
+		case *proto.MapField:
+			msg.Fields = append(msg.Fields, &Field{
+				Name:    v.Name,
+				Number:  v.Sequence,
+				Type:    v.Type,
+				Kind:    MapKind,
+				KeyType: v.KeyType,
+				Options: options(v.Options),
+				Doc:     doc(v.Comment, v.InlineComment),
+			})
+		case *proto.Oneof:
+			oneof := &Oneof{Name: v.Name}
+			for _, oe := range v.Elements {
+				switch ov := oe.(type) {
+				case *proto.OneOfField:
+					field := &Field{
+						Name:    ov.Name,
+						Number:  ov.Sequence,
+						Type:    ov.Type,
+						Oneof:   v.Name,
+						Options: options(ov.Options),
+						Doc:     doc(ov.Comment, ov.InlineComment),
+					}
+					oneof.Fields = append(oneof.Fields, field)
+					msg.Fields = append(msg.Fields, field)
+				case *proto.Option:
+					oneof.Options = append(oneof.Options, option(ov))
+				}
+			}
+			msg.Oneofs = append(msg.Oneofs, oneof)
+		case *proto.Reserved:
+			msg.ReservedRanges = append(msg.ReservedRanges, ranges(v.Ranges)...)
+			msg.ReservedNames = append(msg.ReservedNames, v.FieldNames...)
+		case *proto.Option:
+			msg.Options = append(msg.Options, option(v))
+		case *proto.Message:
+			if v.IsExtend {
+				msg.Extensions = append(msg.Extensions, extension(v, msg.FullName))
+				continue
+			}
+			msg.Messages = append(msg.Messages, f.message(v, msg.FullName))
+		case *proto.Enum:
+			msg.Enums = append(msg.Enums, f.enum(v, msg.FullName))
+		}
+	}
+
+	return msg
+}
+
+func extension(m *proto.Message, scope string) *Extension {
+	ext := &Extension{Extendee: m.Name, scope: scope}
+	for _, e := range m.Elements {
+		if v, ok := e.(*proto.NormalField); ok {
+			ext.Fields = append(ext.Fields, &Field{
+				Name:     v.Name,
+				Number:   v.Sequence,
+				Type:     v.Type,
+				Repeated: v.Repeated,
+				Optional: v.Optional,
+				Required: v.Required,
+				Options:  options(v.Options),
+			})
+		}
+	}
+
+	return ext
+}
+
+// doc returns the text of the leading comment, or of the inline comment if
+// there is no leading comment.
+func doc(comment, inline *proto.Comment) string {
+	if comment == nil {
+		comment = inline
+	}
+	if comment == nil {
+		return ""
+	}
+	lines := make([]string, 0, len(comment.Lines))
+	for _, line := range comment.Lines {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func ranges(in []proto.Range) []Range {
+	out := make([]Range, 0, len(in))
+	for _, r := range in {
+		to := r.To
+		if r.Max {
+			to = maxFieldNumber
+		} else if to == 0 {
+			to = r.From
+		}
+		out = append(out, Range{From: r.From, To: to})
+	}
+
+	return out
+}
+
+func (f *File) enum(e *proto.Enum, scope string) *Enum {
+	enum := &Enum{Name: e.Name, FullName: qualify(scope, e.Name), Doc: doc(e.Comment, nil)}
+	f.enums[enum.FullName] = enum
+
+	for _, el := range e.Elements {
+		switch v := el.(type) {
+		case *proto.EnumField:
+			value := &EnumValue{Name: v.Name, Number: v.Integer, Doc: doc(v.Comment, v.InlineComment)}
+			for _, ve := range v.Elements {
+				if o, ok := ve.(*proto.Option); ok {
+					value.Options = append(value.Options, option(o))
+				}
+			}
+			if v.ValueOption != nil && len(value.Options) == 0 {
+				value.Options = append(value.Options, option(v.ValueOption))
+			}
+			enum.Values = append(enum.Values, value)
+		case *proto.Reserved:
+			enum.ReservedRanges = append(enum.ReservedRanges, ranges(v.Ranges)...)
+			enum.ReservedNames = append(enum.ReservedNames, v.FieldNames...)
+		case *proto.Option:
+			enum.Options = append(enum.Options, option(v))
+		}
+	}
+
+	return enum
+}
+
+func service(s *proto.Service) *Service {
+	svc := &Service{Name: s.Name, Doc: doc(s.Comment, nil)}
+	for _, e := range s.Elements {
+		switch v := e.(type) {
+		case *proto.RPC:
+			rpc := &RPC{
+				Name:           v.Name,
+				RequestType:    v.RequestType,
+				StreamsRequest: v.StreamsRequest,
+				ReturnsType:    v.ReturnsType,
+				StreamsReturns: v.StreamsReturns,
+				Doc:            doc(v.Comment, v.InlineComment),
+			}
+			for _, re := range v.Elements {
+				if o, ok := re.(*proto.Option); ok {
+					rpc.Options = append(rpc.Options, option(o))
+				}
+			}
+			svc.RPCs = append(svc.RPCs, rpc)
+		case *proto.Option:
+			svc.Options = append(svc.Options, option(v))
+		}
+	}
+
+	return svc
+}
@@ -0,0 +1,51 @@
+// Package refs orders schemas so that referenced schemas come before the
+// schemas referencing them, shared by backup restores, migrations and gitops
+// plans.
+package refs
+
+import "strconv"
+
+// VersionKey returns the key of a subject version.
+func VersionKey(subject string, version int) string {
+	return subject + "/" + strconv.Itoa(version)
+}
+
+// Sort returns the indices 0 to n-1 ordered so that every index comes after
+// the indices deps returns for it, otherwise keeping index order. When the
+// dependencies form a cycle, Sort returns the error cycle returns for the
+// index the cycle was found at.
+func Sort(n int, deps func(i int) []int, cycle func(i int) error) ([]int, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, n)
+	out := make([]int, 0, n)
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			return cycle(i)
+		}
+		state[i] = visiting
+		for _, j := range deps(i) {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		state[i] = done
+		out = append(out, i)
+
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,245 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointClass groups the registry endpoints sharing request limits.
+type EndpointClass string
+
+const (
+	// EndpointSchemas are the /schemas endpoints, used to look schemas up by id.
+	EndpointSchemas EndpointClass = "schemas"
+	// EndpointSubjects are the /subjects endpoints, used to register and look
+	// schemas up by subject.
+	EndpointSubjects EndpointClass = "subjects"
+	// EndpointConfig are the /config and /mode endpoints.
+	EndpointConfig EndpointClass = "config"
+	// EndpointCompatibility are the /compatibility endpoints.
+	EndpointCompatibility EndpointClass = "compatibility"
+	// EndpointOther are the remaining endpoints.
+	EndpointOther EndpointClass = "other"
+)
+
+// endpointClass returns the class of the request path.
+func endpointClass(path string) EndpointClass {
+	segment := strings.TrimPrefix(path, "/")
+	if i := strings.IndexAny(segment, "/?"); i >= 0 {
+		segment = segment[:i]
+	}
+
+	switch segment {
+	case "schemas":
+		return EndpointSchemas
+	case "subjects":
+		return EndpointSubjects
+	case "config", "mode":
+		return EndpointConfig
+	case "compatibility":
+		return EndpointCompatibility
+	default:
+		return EndpointOther
+	}
+}
+
+// RequestLimits limits the requests sent to the registry. Zero values are
+// unlimited.
+type RequestLimits struct {
+	// RequestsPerSecond is the rate at which requests are allowed.
+	RequestsPerSecond float64
+	// Burst is the number of requests allowed at once above the rate, 1 if
+	// not set.
+	Burst int
+	// MaxInFlight is the number of requests waiting for a response at once.
+	MaxInFlight int
+	// NoWait makes requests over the limits fail with a *RateLimitError
+	// instead of waiting for the limits to allow them.
+	NoWait bool
+}
+
+// ErrRateLimited matches the *RateLimitError of a request over the client limits.
+var ErrRateLimited = errors.New("schema registry request rate limited")
+
+// RateLimitError is returned for a request over the client limits when the
+// limits do not wait.
+type RateLimitError struct {
+	// Class is the endpoint class of the request.
+	Class EndpointClass
+	// InFlight is true if too many requests were in flight, and false if the
+	// request rate was exceeded.
+	InFlight bool
+}
+
+func (e *RateLimitError) Error() string {
+	if e.InFlight {
+		return "schema registry " + string(e.Class) + " request rate limited: too many requests in flight"
+	}
+
+	return "schema registry " + string(e.Class) + " request rate limited: request rate exceeded"
+}
+
+// Is reports whether the target is ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// limiter enforces one RequestLimits.
+type limiter struct {
+	bucket *tokenBucket
+	slots  chan struct{}
+	noWait bool
+}
+
+func newLimiter(limits RequestLimits) *limiter {
+	l := &limiter{noWait: limits.NoWait}
+	if limits.RequestsPerSecond > 0 {
+		l.bucket = newTokenBucket(limits.RequestsPerSecond, limits.Burst, time.Now)
+	}
+	if limits.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, limits.MaxInFlight)
+	}
+
+	return l
+}
+
+// acquire waits until the request is allowed and returns the function
+// releasing its in flight slot.
+func (l *limiter) acquire(ctx context.Context, class EndpointClass) (func(), error) {
+	if l.bucket != nil {
+		if err := l.bucket.take(ctx, l.noWait); err != nil {
+			if err == ErrRateLimited {
+				return nil, &RateLimitError{Class: class}
+			}
+			return nil, err
+		}
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	if l.noWait {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			return nil, &RateLimitError{Class: class, InFlight: true}
+		}
+	} else {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() { <-l.slots }, nil
+}
+
+// requestLimits holds the limits for every request and per endpoint class.
+type requestLimits struct {
+	all       *limiter
+	endpoints map[EndpointClass]*limiter
+}
+
+// acquire waits until the request to the path is allowed by the limits and
+// returns the function to call once it is done.
+func (r *requestLimits) acquire(ctx context.Context, path string) (func(), error) {
+	if r == nil || (r.all == nil && len(r.endpoints) == 0) {
+		return func() {}, nil
+	}
+
+	class := endpointClass(path)
+	var releases []func()
+	release := func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+	for _, l := range []*limiter{r.all, r.endpoints[class]} {
+		if l == nil {
+			continue
+		}
+		done, err := l.acquire(ctx, class)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, done)
+	}
+
+	return release, nil
+}
+
+// tokenBucket allows requests at a rate with bursts.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now func() time.Time) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now(), now: now}
+}
+
+// reserve takes a token and returns how long to wait before it is available.
+// Without waiting, it only takes an available token.
+func (b *tokenBucket) reserve(wait bool) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	if !wait {
+		return 0, false
+	}
+	b.tokens--
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second)), true
+}
+
+// cancel returns a reserved token that was not used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	b.tokens++
+	b.mu.Unlock()
+}
+
+// take takes a token, waiting for it unless noWait is set.
+func (b *tokenBucket) take(ctx context.Context, noWait bool) error {
+	delay, ok := b.reserve(!noWait)
+	if !ok {
+		return ErrRateLimited
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,177 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEndpointClass(t *testing.T) {
+	cases := map[string]EndpointClass{
+		"/schemas/ids/1": EndpointSchemas,
+		"/subjects/users/versions?normalize=true": EndpointSubjects,
+		"/subjects":     EndpointSubjects,
+		"/config/users": EndpointConfig,
+		"/mode":         EndpointConfig,
+		"/compatibility/subjects/users/versions/1": EndpointCompatibility,
+		"/contexts": EndpointOther,
+	}
+	for path, want := range cases {
+		if got := endpointClass(path); got != want {
+			t.Errorf("TestEndpointClass: %s got %s want %s", path, got, want)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(2, 2, func() time.Time { return now })
+
+	for i := 0; i < 2; i++ {
+		if delay, ok := b.reserve(false); !ok || delay != 0 {
+			t.Error("TestTokenBucket: burst not allowed", i, delay, ok)
+		}
+	}
+	if _, ok := b.reserve(false); ok {
+		t.Error("TestTokenBucket: request over the burst allowed")
+	}
+	if delay, ok := b.reserve(true); !ok || delay != 500*time.Millisecond {
+		t.Error("TestTokenBucket: unexpected delay", delay, ok)
+	}
+	b.cancel()
+
+	now = now.Add(time.Second)
+	if _, ok := b.reserve(false); !ok {
+		t.Error("TestTokenBucket: token not refilled")
+	}
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		b.reserve(false)
+	}
+	if _, ok := b.reserve(false); ok {
+		t.Error("TestTokenBucket: tokens above the burst")
+	}
+}
+
+func okClient(delay time.Duration) *HTTPClientMock {
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		time.Sleep(delay)
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(`["users"]`)), StatusCode: 200}, nil
+	}
+
+	return client
+}
+
+func TestRequestLimitsNoWait(t *testing.T) {
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(okClient(0)),
+		WithRequestEndpointLimits(EndpointSubjects, RequestLimits{RequestsPerSecond: 0.001, Burst: 2, NoWait: true}))
+	if err != nil {
+		t.Fatal("TestRequestLimitsNoWait: ", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetSubjects(); err != nil {
+			t.Fatal("TestRequestLimitsNoWait: ", err)
+		}
+	}
+	_, err = c.GetSubjects()
+	var rateErr *RateLimitError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &rateErr) || rateErr.Class != EndpointSubjects || rateErr.InFlight {
+		t.Error("TestRequestLimitsNoWait: unexpected error", err)
+	}
+	if _, err := c.GetSchemaTypes(); err != nil {
+		t.Error("TestRequestLimitsNoWait: other endpoint class limited", err)
+	}
+}
+
+func TestRequestLimitsWait(t *testing.T) {
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(okClient(0)), WithRequestLimits(RequestLimits{RequestsPerSecond: 50}))
+	if err != nil {
+		t.Fatal("TestRequestLimitsWait: ", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetSubjects(); err != nil {
+			t.Fatal("TestRequestLimitsWait: ", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Error("TestRequestLimitsWait: requests not rate limited", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c, _ = NewClient("localhost:8080", WithCustomHTTPClient(okClient(0)), WithRequestLimits(RequestLimits{RequestsPerSecond: 0.001}))
+	var out []string
+	if err := c.RequestContext(ctx, http.MethodGet, "/subjects", nil, &out); err != nil {
+		t.Fatal("TestRequestLimitsWait: ", err)
+	}
+	if err := c.RequestContext(ctx, http.MethodGet, "/subjects", nil, &out); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("TestRequestLimitsWait: wait not canceled", err)
+	}
+}
+
+func TestRequestLimitsMaxInFlight(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, max int
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > max {
+			max = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(`[]`)), StatusCode: 200}, nil
+	}
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(client), WithRequestLimits(RequestLimits{MaxInFlight: 2}))
+	if err != nil {
+		t.Fatal("TestRequestLimitsMaxInFlight: ", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetSubjects(); err != nil {
+				t.Error("TestRequestLimitsMaxInFlight: ", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if max != 2 {
+		t.Error("TestRequestLimitsMaxInFlight: unexpected requests in flight", max)
+	}
+
+	c, _ = NewClient("localhost:8080", WithCustomHTTPClient(okClient(20*time.Millisecond)),
+		WithRequestLimits(RequestLimits{MaxInFlight: 1, NoWait: true}))
+	go c.GetSubjects()
+	time.Sleep(5 * time.Millisecond)
+	var rateErr *RateLimitError
+	if _, err := c.GetSubjects(); !errors.As(err, &rateErr) || !rateErr.InFlight {
+		t.Error("TestRequestLimitsMaxInFlight: unexpected error", err)
+	}
+}
+
+func TestRegistryLimits(t *testing.T) {
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(okClient(0)),
+		WithLimits(RequestLimits{MaxInFlight: 1}), WithEndpointLimits(EndpointSchemas, RequestLimits{RequestsPerSecond: 1, NoWait: true}))
+	if err != nil {
+		t.Fatal("TestRegistryLimits: ", err)
+	}
+	if reg.registry.limits.all == nil || reg.registry.limits.endpoints[EndpointSchemas] == nil {
+		t.Error("TestRegistryLimits: limits not passed to the client")
+	}
+}
@@ -0,0 +1,53 @@
+package schemaregistry
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Logger receives log records with slog style alternating key and value
+// arguments, so a *slog.Logger can be used directly. Implementations must be
+// safe for concurrent use.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// NopLogger discards all records. It is used when no Logger is set.
+type NopLogger struct{}
+
+var _ Logger = NopLogger{}
+
+func (NopLogger) Debug(msg string, args ...interface{}) {}
+func (NopLogger) Info(msg string, args ...interface{})  {}
+func (NopLogger) Warn(msg string, args ...interface{})  {}
+func (NopLogger) Error(msg string, args ...interface{}) {}
+
+const redacted = "REDACTED"
+
+// secretHeaders are headers whose values are never logged.
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// redactHeaders returns the headers as a map for logging, with the values of
+// authentication headers replaced.
+func redactHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		if secretHeaders[http.CanonicalHeaderKey(name)] || strings.Contains(lower, "token") ||
+			strings.Contains(lower, "secret") || strings.Contains(lower, "api-key") {
+			out[name] = redacted
+			continue
+		}
+		out[name] = strings.Join(values, ",")
+	}
+
+	return out
+}
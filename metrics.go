@@ -0,0 +1,83 @@
+package schemaregistry
+
+import (
+	"strings"
+	"time"
+)
+
+// SerdeOperation names the direction of a serialization.
+type SerdeOperation string
+
+const (
+	SerdeSerialize   SerdeOperation = "serialize"
+	SerdeDeserialize SerdeOperation = "deserialize"
+)
+
+// Caches of SchemaRegistry reported to Metrics.
+const (
+	// CacheID holds schemas by id.
+	CacheID = "id"
+	// CacheSubject holds schemas by subject and schema.
+	CacheSubject = "subject"
+	// CacheParsed holds parsed AVRO schemas.
+	CacheParsed = "parsed"
+	// CacheDisk holds schemas persisted on disk.
+	CacheDisk = "disk"
+)
+
+// Metrics receives measurements of registry requests, cache use and
+// serialization. Implementations must be safe for concurrent use. The
+// prommetrics package implements it with Prometheus.
+type Metrics interface {
+	// ObserveRequest records a request to the registry endpoint, such as
+	// "/subjects/{subject}/versions", with the response status code or 0 if
+	// no response was received.
+	ObserveRequest(method, endpoint string, status int, latency time.Duration)
+	// ObserveCache records a lookup in the cache.
+	ObserveCache(cache string, hit bool)
+	// SetCacheSize records the number of entries in the cache.
+	SetCacheSize(cache string, size int)
+	// ObserveSerde records a serialization or deserialization of a value of
+	// the subject, failed if err is not nil.
+	ObserveSerde(op SerdeOperation, subject string, err error)
+}
+
+// NopMetrics discards all measurements. It is used when no Metrics are set.
+type NopMetrics struct{}
+
+var _ Metrics = NopMetrics{}
+
+func (NopMetrics) ObserveRequest(method, endpoint string, status int, latency time.Duration) {}
+func (NopMetrics) ObserveCache(cache string, hit bool)                                       {}
+func (NopMetrics) SetCacheSize(cache string, size int)                                       {}
+func (NopMetrics) ObserveSerde(op SerdeOperation, subject string, err error)                 {}
+
+// endpointParams maps path segments to the parameter held by the segment that
+// follows them.
+var endpointParams = map[string]string{
+	"subjects": "subject",
+	"versions": "version",
+	"ids":      "id",
+	"config":   "subject",
+	"mode":     "subject",
+}
+
+// endpoint returns the request uri with subjects, versions and ids replaced
+// by placeholders, so that it can be used as a metric label, and the values
+// of the replaced parameters.
+func endpoint(uri string) (string, map[string]string) {
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		uri = uri[:i]
+	}
+
+	params := make(map[string]string)
+	segments := strings.Split(uri, "/")
+	for i := 1; i < len(segments); i++ {
+		if param, ok := endpointParams[segments[i-1]]; ok && segments[i] != "" {
+			params[param] = segments[i]
+			segments[i] = "{" + param + "}"
+		}
+	}
+
+	return strings.Join(segments, "/"), params
+}
@@ -0,0 +1,130 @@
+package schemaregistry
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	requests []string
+	caches   map[string]int
+	sizes    map[string]int
+	serde    []string
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{caches: make(map[string]int), sizes: make(map[string]int)}
+}
+
+func (m *recordingMetrics) ObserveRequest(method, endpoint string, status int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, method+" "+endpoint+" "+http.StatusText(status))
+}
+
+func (m *recordingMetrics) ObserveCache(cache string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.caches[cache+" hit"]++
+	} else {
+		m.caches[cache+" miss"]++
+	}
+}
+
+func (m *recordingMetrics) SetCacheSize(cache string, size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sizes[cache] = size
+}
+
+func (m *recordingMetrics) ObserveSerde(op SerdeOperation, subject string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.serde = append(m.serde, string(op)+" "+subject)
+}
+
+func TestEndpoint(t *testing.T) {
+	for uri, want := range map[string]string{
+		"/subjects":                                  "/subjects",
+		"/subjects?deleted=true":                     "/subjects",
+		"/subjects/users/versions":                   "/subjects/{subject}/versions",
+		"/subjects/users/versions/3/referencedby":    "/subjects/{subject}/versions/{version}/referencedby",
+		"/subjects/versions/versions/latest":         "/subjects/{subject}/versions/{version}",
+		"/schemas/ids/7/versions":                    "/schemas/ids/{id}/versions",
+		"/schemas/types":                             "/schemas/types",
+		"/compatibility/subjects/users/versions/all": "/compatibility/subjects/{subject}/versions/{version}",
+		"/config":                "/config",
+		"/mode/users?force=true": "/mode/{subject}",
+	} {
+		if got, _ := endpoint(uri); got != want {
+			t.Errorf("TestEndpoint: endpoint(%q) got %q want %q", uri, got, want)
+		}
+	}
+}
+
+func TestClientRequestMetrics(t *testing.T) {
+	client := &HTTPClientMock{}
+	metrics := newRecordingMetrics()
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(client), WithRequestMetrics(metrics))
+	if err != nil {
+		t.Fatal("TestClientRequestMetrics: ", err)
+	}
+
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(`[1,2]`)), StatusCode: 200}, nil
+	}
+	if _, err := c.GetVersions("users"); err != nil {
+		t.Fatal("TestClientRequestMetrics: ", err)
+	}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}
+	if _, err := c.GetSubjects(); err == nil {
+		t.Fatal("TestClientRequestMetrics: expected an error")
+	}
+
+	want := []string{"GET /subjects/{subject}/versions OK", "GET /subjects "}
+	if strings.Join(metrics.requests, "|") != strings.Join(want, "|") {
+		t.Errorf("TestClientRequestMetrics: got %q want %q", metrics.requests, want)
+	}
+}
+
+func TestRegistryCacheMetrics(t *testing.T) {
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		body := `{"schema":"{\"type\":\"record\",\"name\":\"User\",\"fields\":[]}"}`
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(body)), StatusCode: 200}, nil
+	}
+	metrics := newRecordingMetrics()
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal("TestRegistryCacheMetrics: ", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		schema, err := reg.GetSchemaByID(1)
+		if err != nil {
+			t.Fatal("TestRegistryCacheMetrics: ", err)
+		}
+		if _, err := reg.ParseAvro(schema); err != nil {
+			t.Fatal("TestRegistryCacheMetrics: ", err)
+		}
+	}
+
+	if metrics.caches["id miss"] != 1 || metrics.caches["id hit"] != 1 || metrics.caches["parsed miss"] != 1 || metrics.caches["parsed hit"] != 1 {
+		t.Error("TestRegistryCacheMetrics: unexpected cache lookups", metrics.caches)
+	}
+	if metrics.sizes[CacheID] != 1 || metrics.sizes[CacheParsed] != 1 {
+		t.Error("TestRegistryCacheMetrics: unexpected cache sizes", metrics.sizes)
+	}
+	if len(metrics.requests) != 1 {
+		t.Error("TestRegistryCacheMetrics: requests not reported through the client", metrics.requests)
+	}
+}
@@ -0,0 +1,409 @@
+// Package migrate replicates subjects from one schema registry to another.
+//
+// A Migrator copies the schema versions of the matching source subjects into
+// the destination registry, optionally renaming subjects and keeping the
+// source schema ids and versions. Sync makes a single pass and Run keeps
+// polling the source for new versions. Every version considered is reported as
+// an Event, so progress and conflicts can be logged or inspected.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/internal/refs"
+)
+
+// Action is the outcome of migrating a schema version.
+type Action string
+
+const (
+	// ActionRegister means the version was, or in a dry run would be,
+	// registered in the destination.
+	ActionRegister Action = "REGISTER"
+	// ActionSkip means the version is already registered in the destination.
+	ActionSkip Action = "SKIP"
+	// ActionConflict means the version cannot be registered in the
+	// destination, for example because the id or version is taken by another
+	// schema or the schema is incompatible.
+	ActionConflict Action = "CONFLICT"
+)
+
+// Event reports what happened to a source schema version.
+type Event struct {
+	Action             Action `json:"action"`
+	Subject            string `json:"subject"`
+	DestinationSubject string `json:"destinationSubject"`
+	Version            int    `json:"version"`
+	ID                 int    `json:"id"`
+	DestinationID      int    `json:"destinationId,omitempty"`
+	DryRun             bool   `json:"dryRun,omitempty"`
+	Message            string `json:"message,omitempty"`
+}
+
+// Report lists the events of a Sync in the order they happened.
+type Report struct {
+	Events []Event `json:"events"`
+}
+
+// Count returns the number of events with the action.
+func (r Report) Count(action Action) int {
+	n := 0
+	for _, e := range r.Events {
+		if e.Action == action {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Conflicts returns the conflict events.
+func (r Report) Conflicts() []Event {
+	var out []Event
+	for _, e := range r.Events {
+		if e.Action == ActionConflict {
+			out = append(out, e)
+		}
+	}
+
+	return out
+}
+
+// Options configures a Migrator.
+type Options struct {
+	// Include lists path.Match patterns of the subjects to migrate. All
+	// subjects are migrated when it is empty.
+	Include []string
+	// Exclude lists path.Match patterns of subjects not to migrate.
+	Exclude []string
+	// SourcePrefix is replaced by DestinationPrefix in the names of migrated
+	// subjects and of the subjects they reference.
+	SourcePrefix      string
+	DestinationPrefix string
+	// PreserveIDs registers schemas with their source ids and versions, using
+	// IMPORT mode on the destination subjects while registering.
+	PreserveIDs bool
+	// DryRun reports what would be migrated without changing the destination.
+	DryRun bool
+	// Reporter, if set, is called with every event as it happens.
+	Reporter func(Event)
+	// Logger, if set, logs the failed syncs Run retries.
+	Logger schemaregistry.Logger
+}
+
+// Migrator replicates subjects from a source to a destination registry. Schema
+// versions referenced by migrated versions are migrated as well, even if their
+// subjects are not included.
+type Migrator struct {
+	source      *schemaregistry.SchemaClient
+	destination *schemaregistry.SchemaClient
+	opts        Options
+
+	mu        sync.Mutex
+	processed map[string]bool
+}
+
+// New creates a Migrator from source to destination.
+func New(source, destination *schemaregistry.SchemaClient, opts Options) (*Migrator, error) {
+	for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid subject pattern %q err:%w", pattern, err)
+		}
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = schemaregistry.NopLogger{}
+	}
+
+	return &Migrator{
+		source:      source,
+		destination: destination,
+		opts:        opts,
+		processed:   make(map[string]bool),
+	}, nil
+}
+
+// Sync migrates the source versions not migrated by an earlier Sync. Versions
+// reported as conflicts are retried, and reported again, by every Sync until
+// they are migrated. In a dry run nothing is recorded, so every Sync reports
+// all versions.
+func (m *Migrator) Sync() (Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := &pass{m: m, done: make(map[string]bool), importModes: make(map[string]schemaregistry.Mode)}
+	err := p.run()
+	if rerr := p.restoreModes(); err == nil {
+		err = rerr
+	}
+
+	return p.report, err
+}
+
+// Run calls Sync every interval until the context is done. A failed Sync is
+// logged and retried at the next interval, unless retrying cannot fix it, in
+// which case Run returns the error.
+func (m *Migrator) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Sync(); err != nil {
+			if unrecoverable(err) {
+				return err
+			}
+			m.opts.Logger.Warn("schema migration sync failed, retrying", "interval", interval, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// unrecoverable reports whether retrying a Sync cannot fix the error, as when
+// a registry refuses the credentials or the permissions of the migrator.
+func unrecoverable(err error) bool {
+	var regErr schemaregistry.Error
+	if !errors.As(err, &regErr) {
+		return false
+	}
+
+	return regErr.StatusCode == http.StatusUnauthorized || regErr.StatusCode == http.StatusForbidden
+}
+
+func (m *Migrator) included(subject string) bool {
+	for _, pattern := range m.opts.Exclude {
+		if ok, _ := path.Match(pattern, subject); ok {
+			return false
+		}
+	}
+	if len(m.opts.Include) == 0 {
+		return true
+	}
+	for _, pattern := range m.opts.Include {
+		if ok, _ := path.Match(pattern, subject); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Migrator) rename(subject string) string {
+	if (m.opts.SourcePrefix != "" || m.opts.DestinationPrefix != "") && strings.HasPrefix(subject, m.opts.SourcePrefix) {
+		return m.opts.DestinationPrefix + subject[len(m.opts.SourcePrefix):]
+	}
+
+	return subject
+}
+
+// pass is a single Sync.
+type pass struct {
+	m           *Migrator
+	report      Report
+	done        map[string]bool
+	importModes map[string]schemaregistry.Mode
+}
+
+func (p *pass) run() error {
+	subjects, err := p.m.source.GetSubjects()
+	if err != nil {
+		return fmt.Errorf("error obtaining source subjects err:%w", err)
+	}
+	sort.Strings(subjects)
+
+	for _, subject := range subjects {
+		if !p.m.included(subject) {
+			continue
+		}
+		versions, err := p.m.source.GetVersions(subject)
+		if err != nil {
+			return fmt.Errorf("error obtaining source versions for subject:%s err:%w", subject, err)
+		}
+		sort.Ints(versions)
+		for _, version := range versions {
+			if err := p.migrate(subject, version); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *pass) emit(e Event) {
+	e.DryRun = p.m.opts.DryRun
+	p.report.Events = append(p.report.Events, e)
+	if !p.m.opts.DryRun && e.Action != ActionConflict {
+		p.m.processed[refs.VersionKey(e.Subject, e.Version)] = true
+	}
+	if p.m.opts.Reporter != nil {
+		p.m.opts.Reporter(e)
+	}
+}
+
+func (p *pass) migrate(subject string, version int) error {
+	key := refs.VersionKey(subject, version)
+	if p.done[key] || p.m.processed[key] {
+		return nil
+	}
+	p.done[key] = true
+
+	resp, err := p.m.source.GetSchemaByVersion(subject, version)
+	if err != nil {
+		return fmt.Errorf("error obtaining source subject:%s version:%d err:%w", subject, version, err)
+	}
+	references := make([]schemaregistry.Reference, 0, len(resp.References))
+	for _, ref := range resp.References {
+		if err := p.migrate(ref.Subject, ref.Version); err != nil {
+			return err
+		}
+		ref.Subject = p.m.rename(ref.Subject)
+		references = append(references, ref)
+	}
+
+	schemaType := schemaregistry.AVRO
+	if resp.SchemaType != nil && *resp.SchemaType != "" {
+		schemaType = *resp.SchemaType
+	}
+	e := Event{
+		Subject:            subject,
+		DestinationSubject: p.m.rename(subject),
+		Version:            resp.Version,
+		ID:                 resp.ID,
+	}
+	dst := p.m.destination
+
+	found, err := dst.LookupSchemaWithMetadata(e.DestinationSubject, resp.Schema, schemaType, resp.Metadata, resp.RuleSet, references...)
+	switch {
+	case err == nil:
+		e.DestinationID = found.ID
+		if p.m.opts.PreserveIDs && found.ID != resp.ID {
+			return p.conflict(e, "schema registered with id "+strconv.Itoa(found.ID))
+		}
+		e.Action = ActionSkip
+		p.emit(e)
+		return nil
+	case !errors.Is(err, schemaregistry.ErrSubjectNotFound) && !errors.Is(err, schemaregistry.ErrSchemaNotFound):
+		return fmt.Errorf("error looking up destination subject:%s err:%w", e.DestinationSubject, err)
+	}
+
+	if p.m.opts.PreserveIDs {
+		if msg, err := p.checkTaken(e, resp.Schema); err != nil || msg != "" {
+			if err != nil {
+				return err
+			}
+			return p.conflict(e, msg)
+		}
+	}
+
+	e.Action = ActionRegister
+	if p.m.opts.DryRun {
+		p.emit(e)
+		return nil
+	}
+
+	if p.m.opts.PreserveIDs {
+		if err := p.importMode(e.DestinationSubject); err != nil {
+			return err
+		}
+		e.DestinationID, err = dst.ImportSchemaWithMetadata(e.DestinationSubject, resp.ID, resp.Version, resp.Schema, schemaType, resp.Metadata, resp.RuleSet, references...)
+	} else {
+		e.DestinationID, err = dst.CreateSchemaWithMetadata(e.DestinationSubject, resp.Schema, schemaType, resp.Metadata, resp.RuleSet, references...)
+	}
+	if err != nil {
+		var regErr schemaregistry.Error
+		if errors.As(err, &regErr) && (regErr.Code == 409 || regErr.Code/100 == 422) {
+			return p.conflict(e, regErr.Message)
+		}
+		return fmt.Errorf("error registering destination subject:%s err:%w", e.DestinationSubject, err)
+	}
+	p.emit(e)
+
+	return nil
+}
+
+// checkTaken returns a conflict message if the source id or version is used
+// by another schema in the destination.
+func (p *pass) checkTaken(e Event, schema string) (string, error) {
+	dst := p.m.destination
+
+	existing, err := dst.GetSchemaByID(e.ID)
+	switch {
+	case err == nil && existing != schema:
+		return "id " + strconv.Itoa(e.ID) + " is used by another schema", nil
+	case err != nil && !errors.Is(err, schemaregistry.ErrSchemaNotFound):
+		return "", fmt.Errorf("error obtaining destination schema id:%d err:%w", e.ID, err)
+	}
+
+	_, err = dst.GetSchemaByVersion(e.DestinationSubject, e.Version)
+	switch {
+	case err == nil:
+		return "version " + strconv.Itoa(e.Version) + " is used by another schema", nil
+	case !errors.Is(err, schemaregistry.ErrSubjectNotFound) && !errors.Is(err, schemaregistry.ErrVersionNotFound):
+		return "", fmt.Errorf("error obtaining destination subject:%s version:%d err:%w", e.DestinationSubject, e.Version, err)
+	}
+
+	return "", nil
+}
+
+func (p *pass) conflict(e Event, message string) error {
+	e.Action = ActionConflict
+	e.Message = message
+	p.emit(e)
+
+	return nil
+}
+
+// importMode switches the destination subject to IMPORT mode, remembering its
+// previous subject mode so it can be restored after the pass.
+func (p *pass) importMode(subject string) error {
+	if _, ok := p.importModes[subject]; ok {
+		return nil
+	}
+
+	previous, err := p.m.destination.GetMode(subject)
+	if err != nil && !errors.Is(err, schemaregistry.ErrSubjectModeNotFound) && !errors.Is(err, schemaregistry.ErrSubjectNotFound) {
+		return fmt.Errorf("error obtaining destination mode for subject:%s err:%w", subject, err)
+	}
+	if _, err := p.m.destination.SetModeForce(subject, schemaregistry.ModeImport); err != nil {
+		return fmt.Errorf("error setting import mode for subject:%s err:%w", subject, err)
+	}
+	p.importModes[subject] = previous
+
+	return nil
+}
+
+func (p *pass) restoreModes() error {
+	subjects := make([]string, 0, len(p.importModes))
+	for subject := range p.importModes {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+
+	for _, subject := range subjects {
+		var err error
+		if previous := p.importModes[subject]; previous != "" {
+			_, err = p.m.destination.SetMode(subject, previous)
+		} else {
+			_, err = p.m.destination.DeleteMode(subject)
+		}
+		if err != nil {
+			return fmt.Errorf("error restoring mode for subject:%s err:%w", subject, err)
+		}
+	}
+
+	return nil
+}
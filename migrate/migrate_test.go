@@ -0,0 +1,359 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/registrytest"
+)
+
+const address = `{"type":"record","name":"Address","namespace":"com.test","fields":[{"name":"city","type":"string"}]}`
+
+const user = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"address","type":"com.test.Address"}]}`
+
+const userV2 = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"address","type":"com.test.Address"},{"name":"age","type":"int","default":0}]}`
+
+const order = `{"type":"record","name":"Order","namespace":"com.test","fields":[{"name":"id","type":"long"}]}`
+
+func newClient(t *testing.T) *schemaregistry.SchemaClient {
+	t.Helper()
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+
+	c, err := schemaregistry.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal("newClient: ", err)
+	}
+
+	return c
+}
+
+func mustCreate(t *testing.T, c *schemaregistry.SchemaClient, subject, schema string, refs ...schemaregistry.Reference) int {
+	t.Helper()
+	id, err := c.CreateSchema(subject, schema, schemaregistry.AVRO, refs...)
+	if err != nil {
+		t.Fatalf("mustCreate: %s: %v", subject, err)
+	}
+
+	return id
+}
+
+var addressRef = schemaregistry.Reference{Name: "com.test.Address", Subject: "prod.address", Version: 1}
+
+func seed(t *testing.T, c *schemaregistry.SchemaClient) {
+	t.Helper()
+	mustCreate(t, c, "other", order)
+	mustCreate(t, c, "prod.address", address)
+	mustCreate(t, c, "prod.users", user, addressRef)
+}
+
+func TestSyncRenamesAndFilters(t *testing.T) {
+	src, dst := newClient(t), newClient(t)
+	seed(t, src)
+	mustCreate(t, dst, "unrelated", order)
+
+	var reported []Event
+	m, err := New(src, dst, Options{
+		Include:           []string{"prod.*"},
+		Exclude:           []string{"prod.address"},
+		SourcePrefix:      "prod.",
+		DestinationPrefix: "stage.",
+		Reporter:          func(e Event) { reported = append(reported, e) },
+	})
+	if err != nil {
+		t.Fatal("TestSyncRenamesAndFilters: ", err)
+	}
+	report, err := m.Sync()
+	if err != nil {
+		t.Fatal("TestSyncRenamesAndFilters: ", err)
+	}
+
+	// prod.address is excluded but migrated as a reference of prod.users.
+	if report.Count(ActionRegister) != 2 || len(reported) != 2 {
+		t.Fatalf("TestSyncRenamesAndFilters: unexpected report %+v", report)
+	}
+	if report.Events[0].DestinationSubject != "stage.address" || report.Events[1].DestinationSubject != "stage.users" {
+		t.Error("TestSyncRenamesAndFilters: unexpected destination subjects", report.Events)
+	}
+
+	resp, err := dst.GetLatestSchema("stage.users")
+	if err != nil {
+		t.Fatal("TestSyncRenamesAndFilters: ", err)
+	}
+	if len(resp.References) != 1 || resp.References[0].Subject != "stage.address" {
+		t.Error("TestSyncRenamesAndFilters: reference not renamed", resp.References)
+	}
+	if _, err := dst.GetLatestSchema("other"); err == nil {
+		t.Error("TestSyncRenamesAndFilters: subject not included was migrated")
+	}
+}
+
+func TestSyncPreservesIDs(t *testing.T) {
+	src, dst := newClient(t), newClient(t)
+	if _, err := src.SetMode("", schemaregistry.ModeImport); err != nil {
+		t.Fatal("TestSyncPreservesIDs: ", err)
+	}
+	if _, err := src.ImportSchema("prod.address", 40, 3, address, schemaregistry.AVRO); err != nil {
+		t.Fatal("TestSyncPreservesIDs: ", err)
+	}
+	ref := schemaregistry.Reference{Name: "com.test.Address", Subject: "prod.address", Version: 3}
+	if _, err := src.ImportSchema("prod.users", 20, 1, user, schemaregistry.AVRO, ref); err != nil {
+		t.Fatal("TestSyncPreservesIDs: ", err)
+	}
+	if _, err := dst.SetMode("", schemaregistry.ModeReadOnly); err != nil {
+		t.Fatal("TestSyncPreservesIDs: ", err)
+	}
+
+	m, err := New(src, dst, Options{PreserveIDs: true})
+	if err != nil {
+		t.Fatal("TestSyncPreservesIDs: ", err)
+	}
+	report, err := m.Sync()
+	if err != nil || report.Count(ActionRegister) != 2 {
+		t.Fatal("TestSyncPreservesIDs: ", report, err)
+	}
+
+	resp, err := dst.GetSchemaByVersion("prod.address", 3)
+	if err != nil || resp.ID != 40 {
+		t.Error("TestSyncPreservesIDs: address not imported with its id and version", resp, err)
+	}
+	resp, err = dst.GetSchemaByVersion("prod.users", 1)
+	if err != nil || resp.ID != 20 {
+		t.Error("TestSyncPreservesIDs: users not imported with its id and version", resp, err)
+	}
+	if _, err := dst.GetMode("prod.users"); err == nil {
+		t.Error("TestSyncPreservesIDs: import mode not removed")
+	}
+}
+
+func TestSyncMetadata(t *testing.T) {
+	metadata := &schemaregistry.Metadata{Properties: map[string]string{"owner": "team-a"}}
+	ruleSet := &schemaregistry.RuleSet{DomainRules: []schemaregistry.Rule{{Name: "checkCity", Kind: schemaregistry.RuleKindCondition, Mode: schemaregistry.RuleModeWrite, Type: "CEL", Expr: "size(message.city) > 0"}}}
+	for _, preserve := range []bool{false, true} {
+		src, dst := newClient(t), newClient(t)
+		if _, err := src.CreateSchemaWithMetadata("prod.address", address, schemaregistry.AVRO, metadata, ruleSet); err != nil {
+			t.Fatal("TestSyncMetadata: ", err)
+		}
+
+		m, err := New(src, dst, Options{PreserveIDs: preserve})
+		if err != nil {
+			t.Fatal("TestSyncMetadata: ", err)
+		}
+		if report, err := m.Sync(); err != nil || report.Count(ActionRegister) != 1 {
+			t.Fatal("TestSyncMetadata: ", preserve, report, err)
+		}
+		resp, err := dst.GetLatestSchema("prod.address")
+		if err != nil || resp.Metadata == nil || resp.Metadata.Properties["owner"] != "team-a" ||
+			resp.RuleSet == nil || resp.RuleSet.DomainRules[0].Name != "checkCity" {
+			t.Error("TestSyncMetadata: metadata and rule set not migrated", preserve, resp, err)
+		}
+		again, err := New(src, dst, Options{PreserveIDs: preserve})
+		if err != nil {
+			t.Fatal("TestSyncMetadata: ", err)
+		}
+		if report, err := again.Sync(); err != nil || report.Count(ActionSkip) != 1 {
+			t.Error("TestSyncMetadata: migrated schema not skipped", preserve, report, err)
+		}
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	src, dst := newClient(t), newClient(t)
+	seed(t, src)
+
+	m, err := New(src, dst, Options{DryRun: true})
+	if err != nil {
+		t.Fatal("TestSyncDryRun: ", err)
+	}
+	for i := 0; i < 2; i++ {
+		report, err := m.Sync()
+		if err != nil || report.Count(ActionRegister) != 3 || !report.Events[0].DryRun {
+			t.Fatal("TestSyncDryRun: ", report, err)
+		}
+	}
+
+	subjects, err := dst.GetSubjects()
+	if err != nil || len(subjects) != 0 {
+		t.Error("TestSyncDryRun: destination changed", subjects, err)
+	}
+}
+
+func TestSyncIncremental(t *testing.T) {
+	src, dst := newClient(t), newClient(t)
+	seed(t, src)
+	mustCreate(t, dst, "prod.address", address)
+
+	m, err := New(src, dst, Options{})
+	if err != nil {
+		t.Fatal("TestSyncIncremental: ", err)
+	}
+	report, err := m.Sync()
+	if err != nil || report.Count(ActionRegister) != 2 || report.Count(ActionSkip) != 1 {
+		t.Fatal("TestSyncIncremental: ", report, err)
+	}
+
+	mustCreate(t, src, "prod.users", userV2, addressRef)
+	report, err = m.Sync()
+	if err != nil || len(report.Events) != 1 || report.Events[0].Version != 2 {
+		t.Fatal("TestSyncIncremental: ", report, err)
+	}
+
+	report, err = m.Sync()
+	if err != nil || len(report.Events) != 0 {
+		t.Error("TestSyncIncremental: ", report, err)
+	}
+}
+
+func TestSyncConflicts(t *testing.T) {
+	src, dst := newClient(t), newClient(t)
+	mustCreate(t, src, "orders", order)
+	mustCreate(t, dst, "taken", address)
+
+	m, err := New(src, dst, Options{PreserveIDs: true})
+	if err != nil {
+		t.Fatal("TestSyncConflicts: ", err)
+	}
+	report, err := m.Sync()
+	if err != nil {
+		t.Fatal("TestSyncConflicts: ", err)
+	}
+	conflicts := report.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Subject != "orders" || conflicts[0].Message == "" {
+		t.Error("TestSyncConflicts: id conflict not reported", report)
+	}
+
+	// Conflicts are retried and reported by every Sync.
+	report, err = m.Sync()
+	if err != nil || len(report.Conflicts()) != 1 {
+		t.Error("TestSyncConflicts: id conflict not retried", report, err)
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	src, dst := newClient(t), newClient(t)
+	seed(t, src)
+
+	m, err := New(src, dst, Options{})
+	if err != nil {
+		t.Fatal("TestRunStopsWithContext: ", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx, 10*time.Millisecond) }()
+
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Error("TestRunStopsWithContext: unexpected error", err)
+	}
+	if subjects, err := dst.GetSubjects(); err != nil || len(subjects) != 3 {
+		t.Error("TestRunStopsWithContext: subjects not migrated", subjects, err)
+	}
+}
+
+// warnings records the messages of the warnings logged.
+type warnings struct {
+	schemaregistry.NopLogger
+	mu       sync.Mutex
+	messages []string
+}
+
+func (w *warnings) Warn(msg string, args ...interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.messages = append(w.messages, msg)
+}
+
+func (w *warnings) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.messages)
+}
+
+// flakySource returns a client of a seeded registry failing its first
+// requests with the status, or with a network error if the status is 0.
+func flakySource(t *testing.T, status, failures int) *schemaregistry.SchemaClient {
+	t.Helper()
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := schemaregistry.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal("flakySource: ", err)
+	}
+	seed(t, c)
+
+	var mu sync.Mutex
+	httpClient := &schemaregistry.HTTPClientMock{DoFunc: func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		failures--
+		fail := failures >= 0
+		mu.Unlock()
+		switch {
+		case !fail:
+			return http.DefaultClient.Do(r)
+		case status == 0:
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(`{"error_code":` + strconv.Itoa(status) + `}`))}, nil
+	}}
+	c, err = schemaregistry.NewClient(srv.URL, schemaregistry.WithCustomHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal("flakySource: ", err)
+	}
+
+	return c
+}
+
+func TestRunRetriesFailedSync(t *testing.T) {
+	dst := newClient(t)
+	logger := &warnings{}
+	m, err := New(flakySource(t, 0, 2), dst, Options{Logger: logger})
+	if err != nil {
+		t.Fatal("TestRunRetriesFailedSync: ", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx, 10*time.Millisecond) }()
+
+	var subjects []string
+	for deadline := time.Now().Add(2 * time.Second); len(subjects) != 3 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		subjects, _ = dst.GetSubjects()
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Error("TestRunRetriesFailedSync: unexpected error", err)
+	}
+	if len(subjects) != 3 {
+		t.Error("TestRunRetriesFailedSync: subjects not migrated", subjects)
+	}
+	if logger.count() != 2 {
+		t.Error("TestRunRetriesFailedSync: failed syncs not logged", logger.messages)
+	}
+}
+
+func TestRunStopsWhenUnauthorized(t *testing.T) {
+	m, err := New(flakySource(t, http.StatusUnauthorized, 1), newClient(t), Options{})
+	if err != nil {
+		t.Fatal("TestRunStopsWhenUnauthorized: ", err)
+	}
+
+	var regErr schemaregistry.Error
+	if err := m.Run(context.Background(), time.Millisecond); !errors.As(err, &regErr) || regErr.StatusCode != http.StatusUnauthorized {
+		t.Error("TestRunStopsWhenUnauthorized: unexpected error", err)
+	}
+}
+
+func TestNewRejectsInvalidPattern(t *testing.T) {
+	if _, err := New(nil, nil, Options{Include: []string{"["}}); err == nil {
+		t.Error("TestNewRejectsInvalidPattern: invalid pattern not handled")
+	}
+}
@@ -0,0 +1,147 @@
+package schemaregistry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var _ Registry = (*MockRegistry)(nil)
+
+// MockRegistry is an in-memory Registry for tests. Schema ids are assigned
+// sequentially starting at 1 in registration order, and an identical schema
+// registered under several subjects shares one id, as it does in the registry.
+// No compatibility checks are performed.
+type MockRegistry struct {
+	mu       sync.RWMutex
+	nextID   int
+	idSchema map[int]*Schema
+	subjects map[string][]*Schema
+}
+
+// NewMockRegistry creates an empty MockRegistry.
+func NewMockRegistry() *MockRegistry {
+	return &MockRegistry{
+		nextID:   1,
+		idSchema: make(map[int]*Schema),
+		subjects: make(map[string][]*Schema),
+	}
+}
+
+// Register checks that the subject version has been registered with RegisterSchema.
+func (m *MockRegistry) Register(subject string, version int) error {
+	if subject == "" || version == 0 {
+		return errors.New("subject and version can be empty")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, err := m.subjectVersion(subject, version); err != nil {
+		return fmt.Errorf(`error registering schema err:%w`, err)
+	}
+
+	return nil
+}
+
+// GetSchemaByID gets the schema with the given id.
+func (m *MockRegistry) GetSchemaByID(schemaId int) (*Schema, error) {
+	if schemaId == 0 {
+		return nil, errors.New("schema id cannot be zero")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	schema, ok := m.idSchema[schemaId]
+	if !ok {
+		return nil, fmt.Errorf("error obtaining schema id:%d error:%w", schemaId, ErrSchemaNotFound)
+	}
+
+	return schema, nil
+}
+
+// GetLatestSchema gets the latest schema registered under the subject.
+func (m *MockRegistry) GetLatestSchema(subject string) (*Schema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	schema, err := m.subjectVersion(subject, int(LatestVersion))
+	if err != nil {
+		return nil, fmt.Errorf(`error obtaining latest schema for subject:%s err:%w`, subject, err)
+	}
+
+	return schema, nil
+}
+
+// RegisterSchema registers the schema under the subject as its next version,
+// or returns the existing version if the schema is already registered.
+func (m *MockRegistry) RegisterSchema(subject, schema string, schemaType SchemaType, references ...Reference) (*Schema, error) {
+	if subject == "" || schema == "" {
+		return nil, errors.New("subject and schema cannot be empty")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.subjects[subject] {
+		if sameSchema(s, schema, schemaType, references) {
+			return s, nil
+		}
+	}
+
+	id := m.nextID
+	for _, s := range m.idSchema {
+		if sameSchema(s, schema, schemaType, references) {
+			id = s.ID
+			break
+		}
+	}
+	if id == m.nextID {
+		m.nextID++
+	}
+
+	typ := schemaType
+	s := &Schema{
+		ID:         id,
+		Schema:     schema,
+		SchemaType: &typ,
+		Subject:    subject,
+		Version:    len(m.subjects[subject]) + 1,
+		References: references,
+	}
+	m.subjects[subject] = append(m.subjects[subject], s)
+	if _, ok := m.idSchema[id]; !ok {
+		m.idSchema[id] = s
+	}
+
+	return s, nil
+}
+
+func (m *MockRegistry) subjectVersion(subject string, version int) (*Schema, error) {
+	versions, ok := m.subjects[subject]
+	if !ok {
+		return nil, ErrSubjectNotFound
+	}
+	if Version(version) == LatestVersion {
+		return versions[len(versions)-1], nil
+	}
+	if version < 1 || version > len(versions) {
+		return nil, ErrVersionNotFound
+	}
+
+	return versions[version-1], nil
+}
+
+func sameSchema(s *Schema, schema string, schemaType SchemaType, references []Reference) bool {
+	if s.Schema != schema || s.SchemaType == nil || *s.SchemaType != schemaType || len(s.References) != len(references) {
+		return false
+	}
+	for i := range references {
+		if s.References[i] != references[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,49 @@
+package schemaregistry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMockRegistry(t *testing.T) {
+	reg := NewMockRegistry()
+
+	first, err := reg.RegisterSchema("com.a", `"string"`, AVRO)
+	if err != nil || first.ID != 1 || first.Version != 1 {
+		t.Error("TestMockRegistry: unexpected first registration", first, err)
+	}
+	second, err := reg.RegisterSchema("com.a", `"int"`, AVRO)
+	if err != nil || second.ID != 2 || second.Version != 2 {
+		t.Error("TestMockRegistry: unexpected second registration", second, err)
+	}
+	again, err := reg.RegisterSchema("com.a", `"string"`, AVRO)
+	if err != nil || again != first {
+		t.Error("TestMockRegistry: re-registration should return existing schema", again, err)
+	}
+	shared, err := reg.RegisterSchema("com.b", `"string"`, AVRO)
+	if err != nil || shared.ID != 1 || shared.Version != 1 {
+		t.Error("TestMockRegistry: identical schema should share id", shared, err)
+	}
+
+	latest, err := reg.GetLatestSchema("com.a")
+	if err != nil || latest != second {
+		t.Error("TestMockRegistry: unexpected latest schema", latest, err)
+	}
+	byID, err := reg.GetSchemaByID(2)
+	if err != nil || byID != second {
+		t.Error("TestMockRegistry: unexpected schema by id", byID, err)
+	}
+	if err := reg.Register("com.a", 2); err != nil {
+		t.Error("TestMockRegistry: Register failed", err)
+	}
+
+	if _, err := reg.GetSchemaByID(9); !errors.Is(err, ErrSchemaNotFound) {
+		t.Error("TestMockRegistry: expected ErrSchemaNotFound, got", err)
+	}
+	if _, err := reg.GetLatestSchema("com.c"); !errors.Is(err, ErrSubjectNotFound) {
+		t.Error("TestMockRegistry: expected ErrSubjectNotFound, got", err)
+	}
+	if err := reg.Register("com.a", 3); !errors.Is(err, ErrVersionNotFound) {
+		t.Error("TestMockRegistry: expected ErrVersionNotFound, got", err)
+	}
+}
@@ -0,0 +1,283 @@
+package schemaregistry
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+
+	"github.com/hamba/avro/pkg/crc64"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/anjulapaulus/schema_registry/internal/avroname"
+	"github.com/anjulapaulus/schema_registry/internal/protoschema"
+)
+
+type FingerprintType string
+
+const (
+	FingerprintCRC64Avro FingerprintType = "CRC-64-AVRO"
+	FingerprintSHA256    FingerprintType = "SHA-256"
+)
+
+// canonicalJSON encodes with sorted object keys and without HTML escaping.
+var canonicalJSON = jsoniter.Config{SortMapKeys: true, UseNumber: true}.Froze()
+
+// Normalize returns the normalized form of the schema. Schemas that differ
+// only in formatting, attribute order or the way names are qualified have the
+// same normalized form, and it describes the same data as the original.
+//
+// AVRO schemas keep all their attributes with names fully qualified and keys
+// ordered as in the Parsing Canonical Form, PROTOBUF schemas are printed
+// without comments with definitions sorted and type names fully qualified,
+// and JSONSCHEMA schemas are compact JSON with sorted keys.
+func Normalize(schemaType SchemaType, schema string) (string, error) {
+	return normalize(schemaType, schema, false)
+}
+
+// CanonicalForm returns the canonical form of the schema used for
+// fingerprinting. For AVRO schemas it is the Parsing Canonical Form, which
+// drops attributes such as docs, defaults and logical types; for other schema
+// types it is the normalized form.
+func CanonicalForm(schemaType SchemaType, schema string) (string, error) {
+	return normalize(schemaType, schema, true)
+}
+
+// Fingerprint returns the fingerprint of the canonical form of the schema.
+// CRC-64-AVRO fingerprints are laid out in big endian byte order.
+func Fingerprint(schemaType SchemaType, schema string, typ FingerprintType) ([]byte, error) {
+	canonical, err := CanonicalForm(schemaType, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var h hash.Hash
+	switch typ {
+	case FingerprintCRC64Avro:
+		h = crc64.New()
+	case FingerprintSHA256:
+		h = sha256.New()
+	default:
+		return nil, fmt.Errorf("unknown fingerprint type [%s]", typ)
+	}
+	_, _ = h.Write([]byte(canonical))
+
+	return h.Sum(nil), nil
+}
+
+func normalize(schemaType SchemaType, schema string, strict bool) (string, error) {
+	switch schemaType {
+	case AVRO, "":
+		return normalizeAvro(schema, strict)
+	case PROTOBUF:
+		f, err := protoschema.Parse(schema, nil)
+		if err != nil {
+			return "", err
+		}
+		return protoschema.Format(f), nil
+	case JSONSCHEMA, "JSON":
+		var v interface{}
+		if err := canonicalJSON.UnmarshalFromString(schema, &v); err != nil {
+			return "", err
+		}
+		return canonicalJSON.MarshalToString(v)
+	}
+
+	return "", fmt.Errorf("unknown schema type [%s]", schemaType)
+}
+
+var avroComplexTypes = map[string]bool{
+	"record": true, "error": true, "enum": true, "fixed": true, "array": true, "map": true,
+}
+
+// avroKeyOrder is the attribute order of the Parsing Canonical Form. Other
+// attributes follow in sorted order when they are kept.
+var avroKeyOrder = []string{"name", "type", "fields", "symbols", "items", "values", "size"}
+
+func normalizeAvro(schema string, strict bool) (string, error) {
+	var v interface{}
+	if err := canonicalJSON.UnmarshalFromString(schema, &v); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := writeAvro(&b, v, "", strict); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func writeJSON(b *strings.Builder, v interface{}) error {
+	s, err := canonicalJSON.MarshalToString(v)
+	if err != nil {
+		return err
+	}
+	b.WriteString(s)
+
+	return nil
+}
+
+// writeAvro writes the schema in normalized form, following the Parsing
+// Canonical Form transformations and, unless strict, keeping the attributes
+// the canonical form strips.
+func writeAvro(b *strings.Builder, v interface{}, namespace string, strict bool) error {
+	switch t := v.(type) {
+	case string:
+		return writeJSON(b, avroname.Qualify(t, namespace))
+	case []interface{}:
+		b.WriteByte('[')
+		for i, branch := range t {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeAvro(b, branch, namespace, strict); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+		return nil
+	case map[string]interface{}:
+		return writeAvroObject(b, t, namespace, strict)
+	}
+
+	return errors.New("invalid avro schema")
+}
+
+func writeAvroObject(b *strings.Builder, m map[string]interface{}, namespace string, strict bool) error {
+	typ, ok := m["type"]
+	if !ok {
+		return errors.New("invalid avro schema: missing type")
+	}
+	typName, _ := typ.(string)
+	if avroname.Primitive(typName) && (strict || len(m) == 1) {
+		return writeJSON(b, typName)
+	}
+
+	keys := map[string]interface{}{}
+	for k, val := range m {
+		keys[k] = val
+	}
+	delete(keys, "namespace")
+
+	switch typName {
+	case "record", "error", "enum", "fixed":
+		fullName := avroname.FullName(m, namespace)
+		namespace = avroname.Namespace(fullName)
+		keys["name"] = fullName
+		if aliases, ok := keys["aliases"].([]interface{}); ok {
+			qualified := make([]interface{}, 0, len(aliases))
+			for _, a := range aliases {
+				if s, ok := a.(string); ok {
+					qualified = append(qualified, avroname.Qualify(s, namespace))
+				}
+			}
+			keys["aliases"] = qualified
+		}
+	}
+
+	b.WriteByte('{')
+	first := true
+	writeKey := func(k string) {
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		_ = writeJSON(b, k)
+		b.WriteByte(':')
+	}
+
+	for _, k := range avroKeyOrder {
+		val, ok := keys[k]
+		if !ok {
+			continue
+		}
+		delete(keys, k)
+		writeKey(k)
+		var err error
+		switch k {
+		case "type":
+			if avroComplexTypes[typName] {
+				err = writeJSON(b, typName)
+			} else {
+				err = writeAvro(b, val, namespace, strict)
+			}
+		case "items", "values":
+			err = writeAvro(b, val, namespace, strict)
+		case "fields":
+			err = writeAvroFields(b, val, namespace, strict)
+		default:
+			err = writeJSON(b, val)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if !strict {
+		rest := make([]string, 0, len(keys))
+		for k := range keys {
+			rest = append(rest, k)
+		}
+		sort.Strings(rest)
+		for _, k := range rest {
+			writeKey(k)
+			if err := writeJSON(b, keys[k]); err != nil {
+				return err
+			}
+		}
+	}
+	b.WriteByte('}')
+
+	return nil
+}
+
+func writeAvroFields(b *strings.Builder, v interface{}, namespace string, strict bool) error {
+	fields, ok := v.([]interface{})
+	if !ok {
+		return errors.New("invalid avro schema: fields must be an array")
+	}
+
+	b.WriteByte('[')
+	for i, f := range fields {
+		field, ok := f.(map[string]interface{})
+		if !ok {
+			return errors.New("invalid avro schema: field must be an object")
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteString(`{"name":`)
+		if err := writeJSON(b, field["name"]); err != nil {
+			return err
+		}
+		b.WriteString(`,"type":`)
+		if err := writeAvro(b, field["type"], namespace, strict); err != nil {
+			return err
+		}
+		if !strict {
+			rest := make([]string, 0, len(field))
+			for k := range field {
+				if k != "name" && k != "type" {
+					rest = append(rest, k)
+				}
+			}
+			sort.Strings(rest)
+			for _, k := range rest {
+				b.WriteByte(',')
+				_ = writeJSON(b, k)
+				b.WriteByte(':')
+				if err := writeJSON(b, field[k]); err != nil {
+					return err
+				}
+			}
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(']')
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hamba/avro"
+)
+
+const testRecord = `{
+	"type": "record",
+	"name": "User",
+	"namespace": "com.example",
+	"doc": "A user.",
+	"fields": [
+		{"name": "id", "type": {"type": "long"}, "doc": "The id."},
+		{"name": "email", "type": ["null", "string"], "default": null},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "DISABLED"]}}
+	]
+}`
+
+func TestCanonicalFormAvro(t *testing.T) {
+	got, err := CanonicalForm(AVRO, testRecord)
+	if err != nil {
+		t.Fatal("TestCanonicalFormAvro: ", err)
+	}
+	want := `{"name":"com.example.User","type":"record","fields":[{"name":"id","type":"long"},{"name":"email","type":["null","string"]},{"name":"status","type":{"name":"com.example.Status","type":"enum","symbols":["ACTIVE","DISABLED"]}}]}`
+	if got != want {
+		t.Errorf("TestCanonicalFormAvro: got %s want %s", got, want)
+	}
+
+	parsed := avro.MustParse(testRecord)
+	if got != parsed.String() {
+		t.Errorf("TestCanonicalFormAvro: got %s, avro parser canonical form %s", got, parsed.String())
+	}
+}
+
+func TestNormalizeAvroKeepsAttributes(t *testing.T) {
+	got, err := Normalize(AVRO, testRecord)
+	if err != nil {
+		t.Fatal("TestNormalizeAvroKeepsAttributes: ", err)
+	}
+	want := `{"name":"com.example.User","type":"record","fields":[{"name":"id","type":"long","doc":"The id."},{"name":"email","type":["null","string"],"default":null},{"name":"status","type":{"name":"com.example.Status","type":"enum","symbols":["ACTIVE","DISABLED"]}}],"doc":"A user."}`
+	if got != want {
+		t.Errorf("TestNormalizeAvroKeepsAttributes: got %s want %s", got, want)
+	}
+
+	reordered := `{"namespace":"com.example","fields":[{"type":"long","name":"id","doc":"The id."},{"default":null,"name":"email","type":["null","string"]},{"name":"status","type":{"symbols":["ACTIVE","DISABLED"],"type":"enum","name":"Status"}}],"name":"User","type":"record","doc":"A user."}`
+	other, err := Normalize(AVRO, reordered)
+	if err != nil {
+		t.Fatal("TestNormalizeAvroKeepsAttributes: ", err)
+	}
+	if other != got {
+		t.Errorf("TestNormalizeAvroKeepsAttributes: equivalent schemas normalized to %s and %s", got, other)
+	}
+}
+
+func TestNormalizeProtobuf(t *testing.T) {
+	a := `syntax = "proto3";
+package acme;
+
+// An order.
+message Order {
+  string id = 1;
+  Item item = 2;
+}
+
+message Item { int32 qty = 1; }
+`
+	b := `syntax = "proto3"; package acme;
+message Item {
+  int32 qty = 1;
+}
+message Order { Item item = 2; string id = 1; }`
+
+	na, err := Normalize(PROTOBUF, a)
+	if err != nil {
+		t.Fatal("TestNormalizeProtobuf: ", err)
+	}
+	nb, err := Normalize(PROTOBUF, b)
+	if err != nil {
+		t.Fatal("TestNormalizeProtobuf: ", err)
+	}
+	if na != nb {
+		t.Errorf("TestNormalizeProtobuf: equivalent schemas normalized to\n%s\nand\n%s", na, nb)
+	}
+	if !strings.Contains(na, ".acme.Item item = 2;") {
+		t.Errorf("TestNormalizeProtobuf: type name not fully qualified in\n%s", na)
+	}
+}
+
+func TestNormalizeJSONSchema(t *testing.T) {
+	got, err := Normalize(JSONSCHEMA, `{"type": "object", "properties": {"b": {"type": "string", "pattern": "<a>"}, "a": {"type": "integer"}}}`)
+	if err != nil {
+		t.Fatal("TestNormalizeJSONSchema: ", err)
+	}
+	want := `{"properties":{"a":{"type":"integer"},"b":{"pattern":"<a>","type":"string"}},"type":"object"}`
+	if got != want {
+		t.Errorf("TestNormalizeJSONSchema: got %s want %s", got, want)
+	}
+}
+
+func TestNormalizeInvalidSchema(t *testing.T) {
+	if _, err := Normalize(AVRO, `{"type":`); err == nil {
+		t.Error("TestNormalizeInvalidSchema: invalid avro schema not handled")
+	}
+	if _, err := Normalize("XML", `<a/>`); err == nil {
+		t.Error("TestNormalizeInvalidSchema: unknown schema type not handled")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	parsed := avro.MustParse(testRecord)
+	for typ, avroTyp := range map[FingerprintType]avro.FingerprintType{
+		FingerprintCRC64Avro: avro.CRC64Avro,
+		FingerprintSHA256:    avro.SHA256,
+	} {
+		got, err := Fingerprint(AVRO, testRecord, typ)
+		if err != nil {
+			t.Fatal("TestFingerprint: ", err)
+		}
+		want, err := parsed.FingerprintUsing(avroTyp)
+		if err != nil {
+			t.Fatal("TestFingerprint: ", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("TestFingerprint: %s got %x want %x", typ, got, want)
+		}
+	}
+
+	if _, err := Fingerprint(AVRO, testRecord, "MD5"); err == nil {
+		t.Error("TestFingerprint: unknown fingerprint type not handled")
+	}
+}
+
+func TestNormalizedSchemasQuery(t *testing.T) {
+	var uris []string
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		uris = append(uris, r.URL.String())
+		return &http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(`{"id":1,"subject":"users","version":1,"schema":"\"int\""}`)),
+			StatusCode: 200,
+		}, nil
+	}
+	c, err := NewClient("http://localhost:8080", WithCustomHTTPClient(client), WithNormalizedSchemas())
+	if err != nil {
+		t.Fatal("TestNormalizedSchemasQuery: ", err)
+	}
+	if _, err := c.CreateSchema("users", `"int"`, AVRO); err != nil {
+		t.Fatal("TestNormalizedSchemasQuery: ", err)
+	}
+	if _, err := c.LookupSchema("users", `"int"`, AVRO); err != nil {
+		t.Fatal("TestNormalizedSchemasQuery: ", err)
+	}
+
+	want := []string{
+		"http://localhost:8080/subjects/users/versions?normalize=true",
+		"http://localhost:8080/subjects/users?normalize=true",
+	}
+	if len(uris) != len(want) || uris[0] != want[0] || uris[1] != want[1] {
+		t.Errorf("TestNormalizedSchemasQuery: got %v want %v", uris, want)
+	}
+}
+
+func TestParseAvroSharesEquivalentSchemas(t *testing.T) {
+	reg, err := NewRegistry("localhost:8080")
+	if err != nil {
+		t.Fatal("TestParseAvroSharesEquivalentSchemas: ", err)
+	}
+
+	a, err := reg.ParseAvro(&Schema{ID: 1, Schema: `{"type":"record","name":"A","fields":[{"name":"f","type":"int"}]}`})
+	if err != nil {
+		t.Fatal("TestParseAvroSharesEquivalentSchemas: ", err)
+	}
+	b, err := reg.ParseAvro(&Schema{ID: 2, Schema: `{ "fields": [ {"type": {"type": "int"}, "name": "f"} ], "name": "A", "type": "record" }`})
+	if err != nil {
+		t.Fatal("TestParseAvroSharesEquivalentSchemas: ", err)
+	}
+	if a != b {
+		t.Error("TestParseAvroSharesEquivalentSchemas: equivalent schemas parsed separately")
+	}
+}
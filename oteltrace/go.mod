@@ -0,0 +1,14 @@
+module github.com/anjulapaulus/schema_registry/oteltrace
+
+go 1.16
+
+require (
+	github.com/anjulapaulus/schema_registry v0.0.0-20261018233138-2d6611a35f5d
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+)
+
+// Builds against the root module of the checkout during local development.
+// Modules requiring this one ignore the replace and use the version above.
+replace github.com/anjulapaulus/schema_registry => ../
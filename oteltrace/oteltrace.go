@@ -0,0 +1,88 @@
+// Package oteltrace traces registry requests and serialization with
+// OpenTelemetry.
+//
+// It is a module of its own, kept out of the schemaregistry module so that
+// programs that do not trace do not depend on OpenTelemetry:
+//
+//	tracer := oteltrace.New(provider)
+//	registry, err := schemaregistry.NewRegistry(url, schemaregistry.WithTracer(tracer))
+//	serializer := serde.NewAvroSerializer(registry, serde.WithTracer(tracer))
+//
+// The trace context is propagated to the registry in W3C traceparent headers.
+package oteltrace
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+// instrumentationName names the tracer of the spans.
+const instrumentationName = "github.com/anjulapaulus/schema_registry"
+
+// Tracer implements schemaregistry.Tracer with an OpenTelemetry tracer.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+var _ schemaregistry.Tracer = (*Tracer)(nil)
+
+// New creates a Tracer starting spans from the provider, or from the global
+// provider if it is nil.
+func New(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return &Tracer{
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: propagation.TraceContext{},
+	}
+}
+
+// Start starts a span as a child of the span in ctx.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, schemaregistry.Span) {
+	ctx, span := t.tracer.Start(ctx, name)
+
+	return ctx, otelSpan{span}
+}
+
+// Inject writes the W3C trace context of ctx to the headers.
+func (t *Tracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, v))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, v))
+	case bool:
+		s.span.SetAttributes(attribute.Bool(key, v))
+	default:
+		s.span.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+func (s otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
@@ -0,0 +1,146 @@
+package oteltrace
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/registrytest"
+	"github.com/anjulapaulus/schema_registry/serde"
+)
+
+type user struct {
+	Name string `avro:"name"`
+}
+
+// headerClient records the traceparent header of every request.
+type headerClient struct {
+	mu           sync.Mutex
+	traceparents []string
+}
+
+func (c *headerClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.traceparents = append(c.traceparents, req.Header.Get("traceparent"))
+	c.mu.Unlock()
+
+	return http.DefaultClient.Do(req)
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+
+	return attrs
+}
+
+func spanNamed(spans tracetest.SpanStubs, name string) (sdktrace.ReadOnlySpan, bool) {
+	for _, span := range spans.Snapshots() {
+		if span.Name() == name {
+			return span, true
+		}
+	}
+
+	return nil, false
+}
+
+func TestTracer(t *testing.T) {
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := New(provider)
+	client := &headerClient{}
+
+	reg, err := schemaregistry.NewRegistry(srv.URL, schemaregistry.WithHTTPClient(client), schemaregistry.WithTracer(tracer))
+	if err != nil {
+		t.Fatal("TestTracer: ", err)
+	}
+	ser := serde.NewAvroSerializer(reg, serde.WithTracer(tracer))
+	des := serde.NewAvroDeserializer(reg, serde.WithTracer(tracer))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "produce")
+	data, err := ser.SerializeWithSchemaContext(ctx, "users", `{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}`, user{Name: "jane"})
+	if err != nil {
+		t.Fatal("TestTracer: ", err)
+	}
+	parent.End()
+	var out user
+	if err := des.Deserialize(data, &out); err != nil {
+		t.Fatal("TestTracer: ", err)
+	}
+	if err := des.Deserialize([]byte{0, 0, 0, 0, 99}, &out); err == nil {
+		t.Fatal("TestTracer: unknown schema id deserialized")
+	}
+
+	spans := exporter.GetSpans()
+	serialize, ok := spanNamed(spans, "schema_registry.serialize")
+	if !ok {
+		t.Fatal("TestTracer: no serialize span", spans)
+	}
+	if serialize.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("TestTracer: serialize span is not a child of the context span")
+	}
+	attrs := attributes(serialize)
+	if attrs[schemaregistry.AttrSubject].AsString() != "users" || attrs[schemaregistry.AttrSchemaID].AsInt64() != 1 || attrs[schemaregistry.AttrCacheHit].AsBool() {
+		t.Error("TestTracer: unexpected serialize attributes", attrs)
+	}
+
+	register, ok := spanNamed(spans, "POST /subjects/{subject}/versions")
+	if !ok {
+		t.Fatal("TestTracer: no register request span")
+	}
+	attrs = attributes(register)
+	if attrs[schemaregistry.AttrSubject].AsString() != "users" || attrs[schemaregistry.AttrStatusCode].AsInt64() != 200 ||
+		attrs[schemaregistry.AttrEndpoint].AsString() != "/subjects/{subject}/versions" {
+		t.Error("TestTracer: unexpected request attributes", attrs)
+	}
+
+	lookup, ok := spanNamed(spans, "GET /schemas/ids/{id}")
+	if !ok {
+		t.Fatal("TestTracer: no schema id request span")
+	}
+	if attributes(lookup)[schemaregistry.AttrSchemaID].AsInt64() != 99 || lookup.Status().Code != codes.Error {
+		t.Error("TestTracer: unexpected failed request span", attributes(lookup), lookup.Status())
+	}
+
+	var failed int
+	for _, span := range spans.Snapshots() {
+		if span.Name() == "schema_registry.deserialize" && span.Status().Code == codes.Error {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Error("TestTracer: unexpected failed deserialize spans", failed)
+	}
+
+	if len(client.traceparents) == 0 {
+		t.Fatal("TestTracer: no requests sent")
+	}
+	for _, traceparent := range client.traceparents {
+		if len(traceparent) != 55 {
+			t.Errorf("TestTracer: invalid traceparent header %q", traceparent)
+		}
+	}
+}
+
+func TestNopTracer(t *testing.T) {
+	ctx, span := schemaregistry.NopTracer{}.Start(context.Background(), "noop")
+	span.SetAttribute("key", 1)
+	span.End()
+	header := http.Header{}
+	schemaregistry.NopTracer{}.Inject(ctx, header)
+	if len(header) != 0 {
+		t.Error("TestNopTracer: headers injected", header)
+	}
+}
@@ -0,0 +1,254 @@
+package schemaregistry
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const defaultPreloadConcurrency = 4
+
+type preloadOptions struct {
+	subjects    []string
+	prefixes    []string
+	ids         []int
+	concurrency int
+	failFast    bool
+}
+
+func (p preloadOptions) empty() bool {
+	return len(p.subjects) == 0 && len(p.prefixes) == 0 && len(p.ids) == 0
+}
+
+// WithPreloadSubjects loads every version of the subjects when the registry
+// is created.
+func WithPreloadSubjects(subjects ...string) regOps {
+	return func(opts *registryOptions) {
+		opts.preload.subjects = append(opts.preload.subjects, subjects...)
+	}
+}
+
+// WithPreloadSubjectPrefix loads every version of the subjects starting with
+// the prefix when the registry is created.
+func WithPreloadSubjectPrefix(prefix string) regOps {
+	return func(opts *registryOptions) {
+		opts.preload.prefixes = append(opts.preload.prefixes, prefix)
+	}
+}
+
+// WithPreloadIDs loads the schemas with the ids when the registry is created.
+func WithPreloadIDs(ids ...int) regOps {
+	return func(opts *registryOptions) {
+		opts.preload.ids = append(opts.preload.ids, ids...)
+	}
+}
+
+// WithPreloadConcurrency sets the number of schemas loaded in parallel, 4 by
+// default.
+func WithPreloadConcurrency(n int) regOps {
+	return func(opts *registryOptions) {
+		opts.preload.concurrency = n
+	}
+}
+
+// WithPreloadFailFast makes NewRegistry wait for the preloading, stop it at the
+// first error and fail with a *PreloadError. By default schemas are preloaded in the background, errors
+// are logged and Ready is signalled once every schema was tried.
+func WithPreloadFailFast() regOps {
+	return func(opts *registryOptions) {
+		opts.preload.failFast = true
+	}
+}
+
+// PreloadError lists the schemas that could not be preloaded.
+type PreloadError struct {
+	Errors []error
+}
+
+func (e *PreloadError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("%d schemas failed to preload: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Ready returns a channel closed once the preloaded schemas are loaded. It is
+// closed immediately if nothing is preloaded.
+func (sr *SchemaRegistry) Ready() <-chan struct{} {
+	return sr.ready
+}
+
+// PreloadErr returns the *PreloadError of the preloading once Ready is
+// closed, and nil before or if every schema was loaded.
+func (sr *SchemaRegistry) PreloadErr() error {
+	select {
+	case <-sr.ready:
+		return sr.preloadErr
+	default:
+		return nil
+	}
+}
+
+// preload loads the schemas and closes the ready channel.
+func (sr *SchemaRegistry) preload(opts preloadOptions) {
+	defer close(sr.ready)
+	if opts.empty() {
+		return
+	}
+
+	p := newPool(opts.concurrency, opts.failFast)
+	subjects := sr.preloadSubjects(opts, p)
+
+	var mu sync.Mutex
+	var versions []SubjectVersion
+	for _, subject := range subjects {
+		subject := subject
+		p.run(func() error {
+			vs, err := sr.registry.GetVersions(subject)
+			if err != nil {
+				return fmt.Errorf("error preloading subject:%s err:%w", subject, err)
+			}
+			mu.Lock()
+			for _, v := range vs {
+				versions = append(versions, SubjectVersion{Subject: subject, Version: v})
+			}
+			mu.Unlock()
+			return nil
+		})
+	}
+	p.wait()
+
+	for _, sv := range versions {
+		sv := sv
+		p.run(func() error {
+			if err := sr.preloadVersion(sv.Subject, sv.Version); err != nil {
+				return fmt.Errorf("error preloading subject:%s version:%d err:%w", sv.Subject, sv.Version, err)
+			}
+			return nil
+		})
+	}
+	for _, id := range opts.ids {
+		id := id
+		p.run(func() error {
+			if _, err := sr.GetSchemaByID(id); err != nil {
+				return fmt.Errorf("error preloading schema id:%d err:%w", id, err)
+			}
+			return nil
+		})
+	}
+	p.wait()
+
+	if len(p.errs) > 0 {
+		sr.preloadErr = &PreloadError{Errors: p.errs}
+		for _, err := range p.errs {
+			sr.logger.Warn("schema registry preload failed", "error", err)
+		}
+	}
+	sr.logger.Info("schema registry preload finished", "versions", len(versions), "ids", len(opts.ids), "errors", len(p.errs))
+}
+
+// preloadSubjects returns the listed subjects and the registry subjects
+// matching the prefixes, without duplicates.
+func (sr *SchemaRegistry) preloadSubjects(opts preloadOptions, p *pool) []string {
+	seen := make(map[string]bool)
+	var subjects []string
+	add := func(subject string) {
+		if !seen[subject] {
+			seen[subject] = true
+			subjects = append(subjects, subject)
+		}
+	}
+
+	for _, subject := range opts.subjects {
+		add(subject)
+	}
+	if len(opts.prefixes) > 0 {
+		p.run(func() error {
+			all, err := sr.registry.GetSubjects()
+			if err != nil {
+				return fmt.Errorf("error preloading subjects err:%w", err)
+			}
+			for _, subject := range all {
+				_, name := SplitSubject(subject)
+				for _, prefix := range opts.prefixes {
+					if strings.HasPrefix(name, prefix) {
+						add(subject)
+						break
+					}
+				}
+			}
+			return nil
+		})
+		p.wait()
+	}
+
+	return subjects
+}
+
+// preloadVersion loads the subject version unless it is cached already.
+func (sr *SchemaRegistry) preloadVersion(subject string, version int) error {
+	sr.ssMu.RLock()
+	_, ok := sr.subjectVersionSchema[sr.subject(subject)][version]
+	sr.ssMu.RUnlock()
+	if ok {
+		return nil
+	}
+
+	return sr.Register(subject, version)
+}
+
+// pool runs functions with bounded parallelism, collecting their errors. In
+// fail fast mode no function is started after one failed.
+type pool struct {
+	sem      chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	errs     []error
+	failFast bool
+}
+
+func newPool(concurrency int, failFast bool) *pool {
+	if concurrency <= 0 {
+		concurrency = defaultPreloadConcurrency
+	}
+
+	return &pool{sem: make(chan struct{}, concurrency), failFast: failFast}
+}
+
+func (p *pool) failed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.failFast && len(p.errs) > 0
+}
+
+func (p *pool) run(f func() error) {
+	p.sem <- struct{}{}
+	if p.failed() {
+		<-p.sem
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		if err := f(); err != nil {
+			p.mu.Lock()
+			p.errs = append(p.errs, err)
+			p.mu.Unlock()
+		}
+	}()
+}
+
+func (p *pool) wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,177 @@
+package schemaregistry
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// preloadClient serves subjects with two versions each. The schema id of a
+// version is 10 times its subject index plus the version, and the id 99 and
+// the subjects listed in fail do not exist.
+type preloadClient struct {
+	mu       sync.Mutex
+	subjects []string
+	fail     map[string]bool
+	inFlight int
+	max      int
+	requests []string
+}
+
+func (c *preloadClient) Do(r *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.inFlight++
+	if c.inFlight > c.max {
+		c.max = c.inFlight
+	}
+	path := r.URL.String()
+	if i := strings.Index(path, "/subjects"); i >= 0 {
+		path = path[i:]
+	} else if i := strings.Index(path, "/schemas"); i >= 0 {
+		path = path[i:]
+	}
+	c.requests = append(c.requests, path)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+	time.Sleep(time.Millisecond)
+
+	respond := func(status int, body string) (*http.Response, error) {
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(body)), StatusCode: status}, nil
+	}
+	notFound := `{"error_code":40401,"message":"Subject not found."}`
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "subjects":
+		return respond(200, `["`+strings.Join(c.subjects, `","`)+`"]`)
+	case len(parts) >= 3 && parts[0] == "subjects":
+		index := -1
+		for i, s := range c.subjects {
+			if s == parts[1] && !c.fail[s] {
+				index = i
+			}
+		}
+		if index < 0 {
+			return respond(404, notFound)
+		}
+		if len(parts) == 3 {
+			return respond(200, `[1,2]`)
+		}
+		return respond(200, fmt.Sprintf(`{"subject":%q,"version":%s,"id":%d,"schema":"\"string\""}`, parts[1], parts[3], 10*index+int(parts[3][0]-'0')))
+	case len(parts) == 3 && parts[0] == "schemas":
+		var id int
+		fmt.Sscan(parts[2], &id)
+		if id/10 >= len(c.subjects) {
+			return respond(404, `{"error_code":40403,"message":"Schema not found."}`)
+		}
+		return respond(200, `{"schema":"\"string\""}`)
+	case len(parts) == 4 && parts[0] == "schemas" && parts[3] == "versions":
+		var id int
+		fmt.Sscan(parts[2], &id)
+		if id/10 >= len(c.subjects) {
+			return respond(404, `{"error_code":40403,"message":"Schema not found."}`)
+		}
+		return respond(200, fmt.Sprintf(`[{"subject":%q,"version":%d}]`, c.subjects[id/10], id%10))
+	}
+
+	return respond(404, notFound)
+}
+
+func TestPreload(t *testing.T) {
+	client := &preloadClient{subjects: []string{"users-value", "users-key", "orders-value"}}
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client),
+		WithPreloadSubjects("orders-value", "users-value"), WithPreloadSubjectPrefix("users-"), WithPreloadIDs(22),
+		WithPreloadConcurrency(2))
+	if err != nil {
+		t.Fatal("TestPreload: ", err)
+	}
+
+	select {
+	case <-reg.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("TestPreload: registry not ready")
+	}
+	if err := reg.PreloadErr(); err != nil {
+		t.Fatal("TestPreload: ", err)
+	}
+
+	for _, id := range []int{1, 2, 11, 12, 21, 22} {
+		reg.idMu.RLock()
+		_, ok := reg.idSchema[schemaID{id: id}]
+		reg.idMu.RUnlock()
+		if !ok {
+			t.Error("TestPreload: schema not preloaded", id)
+		}
+	}
+	if client.max > 2 {
+		t.Error("TestPreload: concurrency limit exceeded", client.max)
+	}
+
+	requests := len(client.requests)
+	if _, err := reg.GetSchemaByID(11); err != nil || len(client.requests) != requests {
+		t.Error("TestPreload: preloaded schema fetched again", err)
+	}
+}
+
+func TestPreloadTolerateErrors(t *testing.T) {
+	client := &preloadClient{subjects: []string{"users-value", "orders-value"}, fail: map[string]bool{"orders-value": true}}
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithPreloadSubjects("users-value", "orders-value"), WithPreloadIDs(99))
+	if err != nil {
+		t.Fatal("TestPreloadTolerateErrors: ", err)
+	}
+	<-reg.Ready()
+
+	var preloadErr *PreloadError
+	if !errors.As(reg.PreloadErr(), &preloadErr) || len(preloadErr.Errors) != 2 {
+		t.Fatal("TestPreloadTolerateErrors: unexpected error", reg.PreloadErr())
+	}
+	if !errors.Is(preloadErr.Errors[0], ErrSubjectNotFound) && !errors.Is(preloadErr.Errors[1], ErrSubjectNotFound) {
+		t.Error("TestPreloadTolerateErrors: registry error not wrapped", preloadErr)
+	}
+	if _, err := reg.GetSchemaByID(2); err != nil {
+		t.Error("TestPreloadTolerateErrors: ", err)
+	}
+}
+
+func TestPreloadFailFast(t *testing.T) {
+	client := &preloadClient{subjects: []string{"users-value"}}
+	_, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithPreloadIDs(99), WithPreloadFailFast())
+	var preloadErr *PreloadError
+	if !errors.As(err, &preloadErr) {
+		t.Error("TestPreloadFailFast: unexpected error", err)
+	}
+
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithPreloadIDs(1), WithPreloadFailFast())
+	if err != nil {
+		t.Fatal("TestPreloadFailFast: ", err)
+	}
+	select {
+	case <-reg.Ready():
+	default:
+		t.Error("TestPreloadFailFast: registry not ready after NewRegistry")
+	}
+}
+
+func TestReadyWithoutPreload(t *testing.T) {
+	reg, err := NewRegistry("localhost:8080")
+	if err != nil {
+		t.Fatal("TestReadyWithoutPreload: ", err)
+	}
+	select {
+	case <-reg.Ready():
+	case <-time.After(time.Second):
+		t.Error("TestReadyWithoutPreload: registry not ready")
+	}
+	if reg.PreloadErr() != nil {
+		t.Error("TestReadyWithoutPreload: unexpected error", reg.PreloadErr())
+	}
+}
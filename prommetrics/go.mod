@@ -0,0 +1,12 @@
+module github.com/anjulapaulus/schema_registry/prommetrics
+
+go 1.16
+
+require (
+	github.com/anjulapaulus/schema_registry v0.0.0-20261018233138-2d6611a35f5d
+	github.com/prometheus/client_golang v1.12.2
+)
+
+// Builds against the root module of the checkout during local development.
+// Modules requiring this one ignore the replace and use the version above.
+replace github.com/anjulapaulus/schema_registry => ../
@@ -0,0 +1,112 @@
+// Package prommetrics reports registry metrics to Prometheus.
+//
+// It is a module of its own, kept out of the schemaregistry module so that
+// programs that do not collect metrics do not depend on the Prometheus client:
+//
+//	m, err := prommetrics.New(prometheus.DefaultRegisterer)
+//	...
+//	registry, err := schemaregistry.NewRegistry(url, schemaregistry.WithMetrics(m))
+//	serializer := serde.NewAvroSerializer(registry, serde.WithMetrics(m))
+package prommetrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+const namespace = "schema_registry"
+
+// Metrics implements schemaregistry.Metrics with Prometheus collectors.
+type Metrics struct {
+	requests     *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	cacheLookups *prometheus.CounterVec
+	cacheEntries *prometheus.GaugeVec
+	serde        *prometheus.CounterVec
+	serdeErrors  *prometheus.CounterVec
+}
+
+var _ schemaregistry.Metrics = (*Metrics)(nil)
+
+// New creates the metrics and registers their collectors with the registerer.
+func New(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "client",
+			Name:      "requests_total",
+			Help:      "Registry requests by method, endpoint and response status, 0 if no response was received.",
+		}, []string{"method", "endpoint", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "client",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of registry requests by method and endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "endpoint"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "lookups_total",
+			Help:      "Schema cache lookups by cache and result, hit or miss.",
+		}, []string{"cache", "result"}),
+		cacheEntries: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "entries",
+			Help:      "Number of entries in the schema caches.",
+		}, []string{"cache"}),
+		serde: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "serde",
+			Name:      "operations_total",
+			Help:      "Serializations and deserializations by operation and subject.",
+		}, []string{"operation", "subject"}),
+		serdeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "serde",
+			Name:      "failures_total",
+			Help:      "Failed serializations and deserializations by operation and subject.",
+		}, []string{"operation", "subject"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.requests, m.latency, m.cacheLookups, m.cacheEntries, m.serde, m.serdeErrors} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// ObserveRequest counts the request and observes its latency.
+func (m *Metrics) ObserveRequest(method, endpoint string, status int, latency time.Duration) {
+	m.requests.WithLabelValues(method, endpoint, strconv.Itoa(status)).Inc()
+	m.latency.WithLabelValues(method, endpoint).Observe(latency.Seconds())
+}
+
+// ObserveCache counts the cache lookup as a hit or a miss.
+func (m *Metrics) ObserveCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// SetCacheSize sets the number of entries in the cache.
+func (m *Metrics) SetCacheSize(cache string, size int) {
+	m.cacheEntries.WithLabelValues(cache).Set(float64(size))
+}
+
+// ObserveSerde counts the operation, and its failure if err is not nil.
+func (m *Metrics) ObserveSerde(op schemaregistry.SerdeOperation, subject string, err error) {
+	m.serde.WithLabelValues(string(op), subject).Inc()
+	if err != nil {
+		m.serdeErrors.WithLabelValues(string(op), subject).Inc()
+	}
+}
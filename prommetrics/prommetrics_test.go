@@ -0,0 +1,101 @@
+package prommetrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/registrytest"
+	"github.com/anjulapaulus/schema_registry/serde"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := New(reg)
+	if err != nil {
+		t.Fatal("TestMetrics: ", err)
+	}
+
+	m.ObserveRequest("GET", "/subjects", 200, 10*time.Millisecond)
+	m.ObserveRequest("GET", "/subjects", 200, 20*time.Millisecond)
+	m.ObserveCache(schemaregistry.CacheID, true)
+	m.ObserveCache(schemaregistry.CacheID, false)
+	m.SetCacheSize(schemaregistry.CacheID, 3)
+	m.ObserveSerde(schemaregistry.SerdeSerialize, "users", nil)
+	m.ObserveSerde(schemaregistry.SerdeSerialize, "users", errors.New("bad value"))
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("GET", "/subjects", "200")); got != 2 {
+		t.Error("TestMetrics: unexpected request count", got)
+	}
+	if got := testutil.CollectAndCount(m.latency); got != 1 {
+		t.Error("TestMetrics: unexpected latency series", got)
+	}
+	if got := testutil.ToFloat64(m.cacheLookups.WithLabelValues("id", "hit")); got != 1 {
+		t.Error("TestMetrics: unexpected cache hits", got)
+	}
+	if got := testutil.ToFloat64(m.cacheEntries.WithLabelValues("id")); got != 3 {
+		t.Error("TestMetrics: unexpected cache size", got)
+	}
+	if got := testutil.ToFloat64(m.serde.WithLabelValues("serialize", "users")); got != 2 {
+		t.Error("TestMetrics: unexpected serde count", got)
+	}
+	if got := testutil.ToFloat64(m.serdeErrors.WithLabelValues("serialize", "users")); got != 1 {
+		t.Error("TestMetrics: unexpected serde failures", got)
+	}
+
+	if _, err := New(reg); err == nil {
+		t.Error("TestMetrics: registering twice did not fail")
+	}
+}
+
+type user struct {
+	Name string `avro:"name"`
+}
+
+func TestMetricsWithRegistry(t *testing.T) {
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+
+	m, err := New(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal("TestMetricsWithRegistry: ", err)
+	}
+	reg, err := schemaregistry.NewRegistry(srv.URL, schemaregistry.WithMetrics(m))
+	if err != nil {
+		t.Fatal("TestMetricsWithRegistry: ", err)
+	}
+	ser := serde.NewAvroSerializer(reg, serde.WithMetrics(m))
+	des := serde.NewAvroDeserializer(reg, serde.WithMetrics(m))
+
+	data, err := ser.SerializeWithSchema("users", `{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}`, user{Name: "jane"})
+	if err != nil {
+		t.Fatal("TestMetricsWithRegistry: ", err)
+	}
+	var out user
+	if err := des.Deserialize(data, &out); err != nil {
+		t.Fatal("TestMetricsWithRegistry: ", err)
+	}
+	if err := des.Deserialize([]byte{1}, &out); err == nil {
+		t.Fatal("TestMetricsWithRegistry: invalid data deserialized")
+	}
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("POST", "/subjects/{subject}/versions", "200")); got != 1 {
+		t.Error("TestMetricsWithRegistry: unexpected register requests", got)
+	}
+	if got := testutil.ToFloat64(m.serde.WithLabelValues("serialize", "users")); got != 1 {
+		t.Error("TestMetricsWithRegistry: unexpected serializations", got)
+	}
+	if got := testutil.ToFloat64(m.serde.WithLabelValues("deserialize", "users")); got != 1 {
+		t.Error("TestMetricsWithRegistry: unexpected deserializations", got)
+	}
+	if got := testutil.ToFloat64(m.serdeErrors.WithLabelValues("deserialize", "")); got != 1 {
+		t.Error("TestMetricsWithRegistry: unexpected deserialization failures", got)
+	}
+	if got := testutil.ToFloat64(m.cacheLookups.WithLabelValues("id", "hit")); got != 1 {
+		t.Error("TestMetricsWithRegistry: unexpected id cache hits", got)
+	}
+}
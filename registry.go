@@ -1,14 +1,30 @@
 package schemaregistry
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"sync"
+
+	"github.com/hamba/avro"
 )
 
 type registryOptions struct {
-	client HTTPClient
+	client           HTTPClient
+	normalize        bool
+	metrics          Metrics
+	tracer           Tracer
+	logger           Logger
+	diskDir          string
+	stale            bool
+	preload          preloadOptions
+	fetchConcurrency int
+	limits           *RequestLimits
+	endpointLimits   map[EndpointClass]RequestLimits
+	breaker          *CircuitBreakerSettings
+	context          string
 }
 
 type regOps func(*registryOptions)
@@ -19,113 +35,680 @@ func WithHTTPClient(client HTTPClient) regOps {
 	}
 }
 
+// WithNormalize registers and looks schemas up in their normalized form.
+func WithNormalize() regOps {
+	return func(opts *registryOptions) {
+		opts.normalize = true
+	}
+}
+
+// WithMetrics reports registry requests and cache use to the metrics.
+func WithMetrics(metrics Metrics) regOps {
+	return func(opts *registryOptions) {
+		if metrics != nil {
+			opts.metrics = metrics
+		}
+	}
+}
+
+// WithTracer traces the registry requests.
+func WithTracer(tracer Tracer) regOps {
+	return func(opts *registryOptions) {
+		opts.tracer = tracer
+	}
+}
+
+// WithLogger logs registry requests, cache updates and failures.
+func WithLogger(logger Logger) regOps {
+	return func(opts *registryOptions) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	}
+}
+
+// WithDiskCache persists fetched schemas as JSON files in a subdirectory of the
+// directory named after the registry URL. Schemas by id missing from memory
+// are read from the directory before the registry is asked, so known ids can
+// be decoded after a restart without the registry. Schemas by subject version
+// are only read from it when serving stale schemas.
+func WithDiskCache(dir string) regOps {
+	return func(opts *registryOptions) {
+		opts.diskDir = dir
+	}
+}
+
+// WithServeStale serves the last known schemas from the disk cache when the
+// registry cannot be reached: the latest schema of a subject, a version of a
+// subject, and the registered version of a schema that is registered again.
+func WithServeStale() regOps {
+	return func(opts *registryOptions) {
+		opts.stale = true
+	}
+}
+
+// WithLimits limits the rate and the number of in flight registry requests, see
+// WithRequestLimits.
+func WithLimits(limits RequestLimits) regOps {
+	return func(opts *registryOptions) {
+		opts.limits = &limits
+	}
+}
+
+// WithEndpointLimits limits the registry requests to one endpoint class, see
+// WithRequestEndpointLimits.
+func WithEndpointLimits(class EndpointClass, limits RequestLimits) regOps {
+	return func(opts *registryOptions) {
+		if opts.endpointLimits == nil {
+			opts.endpointLimits = make(map[EndpointClass]RequestLimits)
+		}
+		opts.endpointLimits[class] = limits
+	}
+}
+
+// WithCircuitBreaker fails registry requests fast while the registry is
+// unhealthy, see WithRequestCircuitBreaker. While the circuit is open cached
+// schemas are still served, including the last known latest schema of a
+// subject.
+func WithCircuitBreaker(settings CircuitBreakerSettings) regOps {
+	return func(opts *registryOptions) {
+		opts.breaker = &settings
+	}
+}
+
+// WithSchemaContext scopes the registry to the schema context, see WithContext.
+// Subjects that are not qualified with a context and schema ids are looked up
+// in it.
+func WithSchemaContext(name string) regOps {
+	return func(opts *registryOptions) {
+		opts.context = contextName(name)
+	}
+}
+
+// Registry looks up and registers schemas. It is implemented by SchemaRegistry
+// and, for tests, by MockRegistry.
+type Registry interface {
+	// Register loads the schema of the subject version into the registry.
+	Register(subject string, version int) error
+	// GetSchemaByID gets the schema with the given id.
+	GetSchemaByID(schemaId int) (*Schema, error)
+	// GetLatestSchema gets the latest schema registered under the subject.
+	GetLatestSchema(subject string) (*Schema, error)
+	// RegisterSchema registers the schema under the subject, returning the
+	// existing schema if it is already registered.
+	RegisterSchema(subject, schema string, schemaType SchemaType, references ...Reference) (*Schema, error)
+}
+
+var _ Registry = (*SchemaRegistry)(nil)
+
 type SchemaRegistry struct {
 	subjectVersionSchema map[string]map[int]*Schema
-	idSchema             map[int]*Schema
+	subjectSchema        map[string]map[string]*Schema
+	idSchema             map[schemaID]*Schema
+	parsed               map[string]avro.Schema
 	ssMu                 *sync.RWMutex
 	idMu                 *sync.RWMutex
+	parsedMu             *sync.Mutex
 	registry             *SchemaClient
+	metrics              Metrics
+	logger               Logger
+	disk                 *diskCache
+	serveStale           bool
+	subjectSchemas       int
+	ready                chan struct{}
+	preloadErr           error
+	fetchConcurrency     int
+	context              string
+}
+
+// schemaID identifies a schema by its id within its context, as every context
+// numbers its schemas separately.
+type schemaID struct {
+	context string
+	id      int
 }
 
 func NewRegistry(url string, ops ...regOps) (*SchemaRegistry, error) {
 	if url == "" {
 		return nil, errors.New("schema-registry.NewRegistry Error: url empty")
 	}
-	opts := &registryOptions{}
+	opts := &registryOptions{metrics: NopMetrics{}, logger: NopLogger{}, fetchConcurrency: defaultFetchConcurrency}
 	for _, opt := range ops {
 		opt(opts)
 	}
-	var client *SchemaClient
-	client, err := NewClient(url)
+	var clientOps []clientOps
+	if opts.client != nil {
+		clientOps = append(clientOps, WithCustomHTTPClient(opts.client))
+	}
+	if opts.normalize {
+		clientOps = append(clientOps, WithNormalizedSchemas())
+	}
+	if opts.limits != nil {
+		clientOps = append(clientOps, WithRequestLimits(*opts.limits))
+	}
+	for class, limits := range opts.endpointLimits {
+		clientOps = append(clientOps, WithRequestEndpointLimits(class, limits))
+	}
+	if opts.breaker != nil {
+		clientOps = append(clientOps, WithRequestCircuitBreaker(*opts.breaker))
+	}
+	if opts.context != "" {
+		clientOps = append(clientOps, WithContext(opts.context))
+	}
+	clientOps = append(clientOps, WithRequestMetrics(opts.metrics), WithRequestTracer(opts.tracer), WithRequestLogger(opts.logger))
+	client, err := NewClient(url, clientOps...)
 	if err != nil {
 		return nil, err
-
 	}
-	if opts.client != nil {
-		client, err = NewClient(url, WithCustomHTTPClient(opts.client))
-		if err != nil {
-			return nil, err
+
+	var disk *diskCache
+	if opts.diskDir != "" {
+		if disk, err = newDiskCache(opts.diskDir, url); err != nil {
+			return nil, fmt.Errorf("error creating disk cache err:%w", err)
 		}
 	}
 
 	r := SchemaRegistry{
 		subjectVersionSchema: make(map[string]map[int]*Schema),
-		idSchema:             make(map[int]*Schema),
+		subjectSchema:        make(map[string]map[string]*Schema),
+		idSchema:             make(map[schemaID]*Schema),
+		parsed:               make(map[string]avro.Schema),
 		ssMu:                 new(sync.RWMutex),
 		idMu:                 new(sync.RWMutex),
+		parsedMu:             new(sync.Mutex),
 		registry:             client,
+		metrics:              opts.metrics,
+		logger:               opts.logger,
+		disk:                 disk,
+		serveStale:           opts.stale,
+		ready:                make(chan struct{}),
+		fetchConcurrency:     opts.fetchConcurrency,
+		context:              opts.context,
+	}
+	if r.fetchConcurrency <= 0 {
+		r.fetchConcurrency = defaultFetchConcurrency
+	}
+
+	if opts.preload.failFast {
+		r.preload(opts.preload)
+		if err := r.PreloadErr(); err != nil {
+			return nil, err
+		}
+	} else {
+		go r.preload(opts.preload)
 	}
 
 	return &r, nil
 }
 
 func (sr *SchemaRegistry) Register(subject string, version int) error {
+	return sr.register(context.Background(), subject, version)
+}
+
+func (sr *SchemaRegistry) register(ctx context.Context, subject string, version int) error {
 	if subject == "" || version == 0 {
 		return errors.New("subject and version can be empty")
 	}
+	subject = sr.subject(subject)
 	sr.ssMu.RLock()
-	if cSchema, ok := sr.subjectVersionSchema[subject][version]; ok {
+	cSchema, ok := sr.subjectVersionSchema[subject][version]
+	sr.ssMu.RUnlock()
+	if ok {
 		if cSchema == nil {
 			return fmt.Errorf(`subject:%s version:%d registered but schema is nil`, subject, version)
 		}
 		return fmt.Errorf(`subject:%s version:%d already registered`, subject, version)
 	}
+
+	resp, err := sr.registry.GetSchemaByVersionContext(ctx, subject, version)
+	if err != nil {
+		sr.logger.Warn("schema registry fetch failed", "subject", subject, "version", version, "error", err)
+		// A registered version is never fetched again, so a stale one is
+		// cached like a fetched one.
+		if schema, ok := sr.stale(err, func(d *diskCache) (*Schema, bool) { return d.get(d.versionPath(subject, version)) }); ok {
+			sr.store(subject, version, schema)
+			return nil
+		}
+		return fmt.Errorf(`error registering schema err:%w`, err)
+	}
+
+	sr.store(subject, version, newSchema(resp))
+
+	return nil
+}
+
+// GetLatestSchema gets the latest schema of the subject. The latest version can
+// change at any time so it is always fetched from the registry.
+func (sr *SchemaRegistry) GetLatestSchema(subject string) (*Schema, error) {
+	if subject == "" {
+		return nil, errors.New("subject cannot be empty")
+	}
+	subject = sr.subject(subject)
+
+	resp, err := sr.registry.GetLatestSchema(subject)
+	if err != nil {
+		sr.logger.Warn("schema registry latest schema refresh failed", "subject", subject, "error", err)
+		if schema, ok := sr.cachedLatest(subject); ok && errors.Is(err, ErrCircuitOpen) {
+			return schema, nil
+		}
+		if schema, ok := sr.stale(err, func(d *diskCache) (*Schema, bool) { return d.get(d.latestPath(subject)) }); ok {
+			return schema, nil
+		}
+		return nil, fmt.Errorf(`error obtaining latest schema for subject:%s err:%w`, subject, err)
+	}
+
+	schema := sr.store(subject, resp.Version, newSchema(resp))
+	if sr.disk != nil {
+		if err := sr.disk.putLatest(schema); err != nil {
+			sr.logger.Warn("schema registry disk cache write failed", "subject", subject, "error", err)
+		}
+	}
+
+	return schema, nil
+}
+
+// RegisterSchema registers the schema under the subject. Schemas registered
+// through the registry are cached by their normalized form, so repeated calls
+// with equivalent schemas do not reach the registry.
+func (sr *SchemaRegistry) RegisterSchema(subject, schema string, schemaType SchemaType, references ...Reference) (*Schema, error) {
+	return sr.RegisterSchemaWithMetadata(subject, schema, schemaType, nil, nil, references...)
+}
+
+// RegisterSchemaWithMetadata registers the schema with its metadata and rule
+// set under the subject. Schemas are cached by their normalized form together
+// with the requested metadata and rule set, the returned schema holds the ones
+// applied by the registry, including the config defaults and overrides.
+func (sr *SchemaRegistry) RegisterSchemaWithMetadata(subject, schema string, schemaType SchemaType, metadata *Metadata, ruleSet *RuleSet, references ...Reference) (*Schema, error) {
+	if subject == "" || schema == "" {
+		return nil, errors.New("subject and schema cannot be empty")
+	}
+	subject = sr.subject(subject)
+	reqKey := cacheKey(schemaType, schema, references, metadata, ruleSet)
+
+	sr.ssMu.RLock()
+	cSchema, ok := sr.subjectSchema[subject][reqKey]
 	sr.ssMu.RUnlock()
+	sr.metrics.ObserveCache(CacheSubject, ok)
+	if ok {
+		return cSchema, nil
+	}
 
-	resp, err := sr.registry.GetSchemaByVersion(subject, version)
+	if _, err := sr.registry.CreateSchemaWithMetadata(subject, schema, schemaType, metadata, ruleSet, references...); err != nil {
+		sr.logger.Warn("schema registry registration failed", "subject", subject, "error", err)
+		if cSchema, ok := sr.stale(err, func(d *diskCache) (*Schema, bool) { return d.find(subject, reqKey) }); ok {
+			return cSchema, nil
+		}
+		return nil, fmt.Errorf(`error registering schema for subject:%s err:%w`, subject, err)
+	}
+	resp, err := sr.registry.LookupSchemaWithMetadata(subject, schema, schemaType, metadata, ruleSet, references...)
 	if err != nil {
-		return fmt.Errorf(`error registering schema err:%s`, err.Error())
+		sr.logger.Warn("schema registry lookup of registered schema failed", "subject", subject, "error", err)
+		return nil, fmt.Errorf(`error obtaining registered schema for subject:%s err:%w`, subject, err)
 	}
+	cSchema = sr.store(subject, resp.Version, newSchema(resp))
 
-	schema := &Schema{
-		ID:         resp.ID,
-		Schema:     resp.Schema,
-		SchemaType: resp.SchemaType,
-		Subject:    resp.Subject,
-		Version:    resp.Version,
+	// The registry may have applied config defaults or overrides, cache the
+	// schema under the requested key too so the next call finds it.
+	sr.ssMu.Lock()
+	if _, ok := sr.subjectSchema[subject][reqKey]; !ok {
+		sr.subjectSchema[subject][reqKey] = cSchema
+		sr.subjectSchemas++
+	}
+	subjectSchemas := sr.subjectSchemas
+	sr.ssMu.Unlock()
+	sr.metrics.SetCacheSize(CacheSubject, subjectSchemas)
+
+	return cSchema, nil
+}
+
+// store caches the schema by subject version, normalized schema and id. If the
+// subject version is already cached the cached schema is kept and returned.
+func (sr *SchemaRegistry) store(subject string, version int, schema *Schema) *Schema {
+	if schema.Subject == "" {
+		schema.Subject = subject
 	}
+	schema.context, _ = SplitSubject(subject)
 
 	sr.ssMu.Lock()
+	if _, ok := sr.subjectVersionSchema[subject]; !ok {
+		sr.subjectVersionSchema[subject] = make(map[int]*Schema)
+		sr.subjectSchema[subject] = make(map[string]*Schema)
+	}
+	if cSchema, ok := sr.subjectVersionSchema[subject][schema.Version]; ok && cSchema != nil {
+		schema = cSchema
+	}
+	sr.subjectVersionSchema[subject][schema.Version] = schema
 	sr.subjectVersionSchema[subject][version] = schema
+	key := cacheKey(schema.Type(), schema.Schema, schema.References, schema.Metadata, schema.RuleSet)
+	if _, ok := sr.subjectSchema[subject][key]; !ok {
+		sr.subjectSchemas++
+	}
+	sr.subjectSchema[subject][key] = schema
+	subjectSchemas := sr.subjectSchemas
 	sr.ssMu.Unlock()
 
 	sr.idMu.Lock()
-	sr.idSchema[resp.ID] = schema
+	sr.idSchema[schemaID{schema.context, schema.ID}] = schema
+	ids := len(sr.idSchema)
 	sr.idMu.Unlock()
 
-	return nil
+	sr.metrics.SetCacheSize(CacheSubject, subjectSchemas)
+	sr.metrics.SetCacheSize(CacheID, ids)
+	sr.logger.Debug("schema registry cache updated", "subject", subject, "version", schema.Version, "id", schema.ID)
+
+	if sr.disk != nil {
+		if err := sr.disk.put(schema); err != nil {
+			sr.logger.Warn("schema registry disk cache write failed", "id", schema.ID, "error", err)
+		}
+	}
+
+	return schema
 }
 
+// cachedLatest returns the highest cached version of the subject.
+func (sr *SchemaRegistry) cachedLatest(subject string) (*Schema, bool) {
+	sr.ssMu.RLock()
+	defer sr.ssMu.RUnlock()
+
+	var latest *Schema
+	for version, schema := range sr.subjectVersionSchema[subject] {
+		if schema != nil && version > 0 && (latest == nil || version > latest.Version) {
+			latest = schema
+		}
+	}
+
+	return latest, latest != nil
+}
+
+// loadDisk reads a schema from the disk cache, if there is one.
+func (sr *SchemaRegistry) loadDisk(load func(d *diskCache) (*Schema, bool)) (*Schema, bool) {
+	if sr.disk == nil {
+		return nil, false
+	}
+
+	schema, ok := load(sr.disk)
+	sr.metrics.ObserveCache(CacheDisk, ok)
+
+	return schema, ok
+}
+
+// stale reads a schema from the disk cache if stale schemas are served and
+// the registry is unavailable, or if the circuit breaker is open. Stale schemas
+// are not cached in memory, so they are refreshed once the registry is back.
+func (sr *SchemaRegistry) stale(err error, load func(d *diskCache) (*Schema, bool)) (*Schema, bool) {
+	if !errors.Is(err, ErrCircuitOpen) && (!sr.serveStale || !unavailable(err)) {
+		return nil, false
+	}
+
+	schema, ok := sr.loadDisk(load)
+	if ok {
+		sr.logger.Warn("schema registry unavailable, serving stale schema", "subject", schema.Subject, "version", schema.Version, "id", schema.ID, "error", err)
+	}
+
+	return schema, ok
+}
+
+func newSchema(resp SchemaResponse) *Schema {
+	return &Schema{
+		ID:         resp.ID,
+		Schema:     resp.Schema,
+		SchemaType: resp.SchemaType,
+		Subject:    resp.Subject,
+		Version:    resp.Version,
+		References: resp.References,
+		Metadata:   resp.Metadata,
+		RuleSet:    resp.RuleSet,
+	}
+}
+
+// GetSchemaByID gets the schema with the given id in the registry context. Its
+// subject and version are empty unless the schema was cached by subject
+// already, see ResolveSubject.
 func (sr *SchemaRegistry) GetSchemaByID(schemaId int) (*Schema, error) {
+	return sr.GetSchemaByContextID(sr.context, schemaId)
+}
+
+// GetSchemaByContextID gets the schema with the given id in the schema context,
+// like GetSchemaByID.
+func (sr *SchemaRegistry) GetSchemaByContextID(schemaContext string, schemaId int) (*Schema, error) {
 	if schemaId == 0 {
 		return nil, errors.New("schema id cannot be zero")
 	}
+	schemaContext = contextName(schemaContext)
 
 	sr.idMu.RLock()
-	if cSchema, ok := sr.idSchema[schemaId]; ok {
+	cSchema, ok := sr.idSchema[schemaID{schemaContext, schemaId}]
+	sr.idMu.RUnlock()
+	sr.metrics.ObserveCache(CacheID, ok)
+	if ok {
 		return cSchema, nil
 	}
-	sr.idMu.RUnlock()
 
-	subVersion, err := sr.registry.GetSubjectVersionByID(schemaId)
+	return sr.fetchSchemaByID(context.Background(), schemaContext, schemaId)
+}
+
+// fetchSchemaByID loads the schema with the id from the disk cache or the
+// registry into the cache. The registry is asked for the schema alone, so its
+// subject and version are only known if it was cached by subject before.
+func (sr *SchemaRegistry) fetchSchemaByID(ctx context.Context, schemaContext string, schemaId int) (*Schema, error) {
+	if schema, ok := sr.loadDisk(func(d *diskCache) (*Schema, bool) { return d.get(d.idPath(schemaContext, schemaId)) }); ok {
+		return sr.storeID(schemaContext, schema), nil
+	}
+
+	resp, err := sr.client(schemaContext).GetSchemaResponseByID(ctx, schemaId)
 	if err != nil {
-		return nil, fmt.Errorf("error obtaining subject and version for schema id:%s error:%s", strconv.Itoa(schemaId), err.Error())
+		sr.logger.Warn("schema registry fetch failed", "context", schemaContext, "id", schemaId, "error", err)
+		return nil, fmt.Errorf("error obtaining schema id:%s error:%w", strconv.Itoa(schemaId), err)
 	}
 
-	if len(subVersion) == 0 {
-		return nil, fmt.Errorf("error obtaining subject and version for schema id:%s due to being empty", strconv.Itoa(schemaId))
+	return sr.storeID(schemaContext, newSchema(resp)), nil
+}
+
+// storeID caches the schema by id in the context, and by subject version too if
+// they are known.
+func (sr *SchemaRegistry) storeID(schemaContext string, schema *Schema) *Schema {
+	if schema.Subject != "" && schema.Version > 0 {
+		return sr.store(QualifiedSubject(schemaContext, schema.Subject), schema.Version, schema)
 	}
+	schema.context = schemaContext
 
-	err = sr.Register(subVersion[0].Subject, subVersion[0].Version)
-	if err != nil {
-		return nil, err
+	key := schemaID{schemaContext, schema.ID}
+	sr.idMu.Lock()
+	if cSchema, ok := sr.idSchema[key]; ok {
+		sr.idMu.Unlock()
+		return cSchema
+	}
+	sr.idSchema[key] = schema
+	ids := len(sr.idSchema)
+	sr.idMu.Unlock()
+
+	sr.metrics.SetCacheSize(CacheID, ids)
+	sr.logger.Debug("schema registry cache updated", "id", schema.ID)
+
+	if sr.disk != nil {
+		if err := sr.disk.put(schema); err != nil {
+			sr.logger.Warn("schema registry disk cache write failed", "id", schema.ID, "error", err)
+		}
+	}
+
+	return schema
+}
+
+// ResolveSubject returns the schema with its subject and version. Schemas
+// fetched by id do not have them until they are resolved, which costs a request
+// the first time. The schema passed in is left unchanged.
+func (sr *SchemaRegistry) ResolveSubject(schema *Schema) (*Schema, error) {
+	if schema == nil {
+		return nil, errors.New("schema cannot be nil")
+	}
+	if schema.Subject != "" && schema.Version > 0 {
+		return schema, nil
 	}
 
+	key := schemaID{schema.context, schema.ID}
 	sr.idMu.RLock()
-	if cSchema, ok := sr.idSchema[schemaId]; ok {
+	cSchema, ok := sr.idSchema[key]
+	sr.idMu.RUnlock()
+	if ok && cSchema.Subject != "" && cSchema.Version > 0 {
 		return cSchema, nil
 	}
-	sr.idMu.RUnlock()
 
-	return nil, nil
+	subVersion, err := sr.client(schema.context).GetSubjectVersionByID(schema.ID)
+	if err != nil {
+		sr.logger.Warn("schema registry fetch failed", "id", schema.ID, "error", err)
+		return nil, fmt.Errorf("error obtaining subject and version for schema id:%s error:%w", strconv.Itoa(schema.ID), err)
+	}
+	if len(subVersion) == 0 {
+		return nil, fmt.Errorf("error obtaining subject and version for schema id:%s due to being empty", strconv.Itoa(schema.ID))
+	}
+
+	resolved := *schema
+	resolved.Subject = QualifiedSubject(schema.context, subVersion[0].Subject)
+	resolved.Version = subVersion[0].Version
+	stored := sr.store(resolved.Subject, resolved.Version, &resolved)
+
+	sr.idMu.Lock()
+	sr.idSchema[key] = stored
+	sr.idMu.Unlock()
+
+	return stored, nil
+}
+
+// ParseAvro parses the AVRO schema together with the schemas it references.
+// Equivalent schemas referencing the same subject versions share one parsed
+// instance, however they are formatted.
+func (sr *SchemaRegistry) ParseAvro(schema *Schema) (avro.Schema, error) {
+	if schema == nil {
+		return nil, errors.New("schema cannot be nil")
+	}
+	if schema.Type() != AVRO {
+		return nil, fmt.Errorf("schema id:%d is not an avro schema", schema.ID)
+	}
+
+	refs := make([]Reference, len(schema.References))
+	for i, ref := range schema.References {
+		ref.Subject = QualifiedSubject(schema.context, ref.Subject)
+		refs[i] = ref
+	}
+	key := schemaKey(AVRO, schema.Schema) + referencesKey(refs)
+	sr.parsedMu.Lock()
+	parsed, ok := sr.parsed[key]
+	sr.parsedMu.Unlock()
+	sr.metrics.ObserveCache(CacheParsed, ok)
+	if ok {
+		return parsed, nil
+	}
+
+	cache := &avro.SchemaCache{}
+	if err := sr.parseReferences(schema.context, schema.References, cache, map[string]bool{}); err != nil {
+		return nil, err
+	}
+	parsed, err := avro.ParseWithCache(schema.Schema, "", cache)
+	if err != nil {
+		sr.logger.Error("schema registry schema parsing failed", "id", schema.ID, "error", err)
+		return nil, fmt.Errorf("error parsing schema id:%d err:%w", schema.ID, err)
+	}
+
+	sr.parsedMu.Lock()
+	if cParsed, ok := sr.parsed[key]; ok {
+		parsed = cParsed
+	} else {
+		sr.parsed[key] = parsed
+	}
+	size := len(sr.parsed)
+	sr.parsedMu.Unlock()
+	sr.metrics.SetCacheSize(CacheParsed, size)
+
+	return parsed, nil
+}
+
+// parseReferences parses the referenced schemas into the cache, dependencies
+// first. References are resolved in the context of the referencing schema.
+func (sr *SchemaRegistry) parseReferences(schemaContext string, refs []Reference, cache *avro.SchemaCache, seen map[string]bool) error {
+	for _, ref := range refs {
+		ref.Subject = QualifiedSubject(schemaContext, ref.Subject)
+		key := ref.Subject + "/" + strconv.Itoa(ref.Version)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		sr.ssMu.RLock()
+		refSchema, ok := sr.subjectVersionSchema[ref.Subject][ref.Version]
+		sr.ssMu.RUnlock()
+		if !ok || refSchema == nil {
+			resp, err := sr.registry.GetSchemaByVersion(ref.Subject, ref.Version)
+			if err != nil {
+				if refSchema, ok = sr.stale(err, func(d *diskCache) (*Schema, bool) { return d.get(d.versionPath(ref.Subject, ref.Version)) }); !ok {
+					return fmt.Errorf("error obtaining reference %s err:%w", ref.Name, err)
+				}
+			} else {
+				refSchema = sr.store(ref.Subject, ref.Version, newSchema(resp))
+			}
+		}
+
+		if err := sr.parseReferences(refSchema.context, refSchema.References, cache, seen); err != nil {
+			return err
+		}
+		if _, err := avro.ParseWithCache(refSchema.Schema, "", cache); err != nil {
+			return fmt.Errorf("error parsing reference %s err:%w", ref.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// subject qualifies the subject with the registry context.
+func (sr *SchemaRegistry) subject(subject string) string {
+	return QualifiedSubject(sr.context, subject)
+}
+
+// client returns the registry client scoped to the schema context.
+func (sr *SchemaRegistry) client(schemaContext string) *SchemaClient {
+	if schemaContext == sr.registry.Context() {
+		return sr.registry
+	}
+
+	return sr.registry.InContext(schemaContext)
+}
+
+// schemaKey returns the normalized form of the schema, or the schema itself
+// when it cannot be normalized.
+func schemaKey(schemaType SchemaType, schema string) string {
+	normalized, err := Normalize(schemaType, schema)
+	if err != nil {
+		return schema
+	}
+
+	return normalized
+}
+
+// referencesKey returns the subject versions of the references, so that the
+// same schema text referencing other versions gets another key.
+func referencesKey(references []Reference) string {
+	var key string
+	for _, ref := range references {
+		key += "\x00" + ref.Name + "=" + ref.Subject + "/" + strconv.Itoa(ref.Version)
+	}
+
+	return key
+}
+
+// cacheKey returns the schema key of the schema, followed by its references,
+// metadata and rule set when it has any.
+func cacheKey(schemaType SchemaType, schema string, references []Reference, metadata *Metadata, ruleSet *RuleSet) string {
+	key := schemaKey(schemaType, schema) + referencesKey(references)
+	if metadata == nil && ruleSet == nil {
+		return key
+	}
+
+	contract, err := json.Marshal(struct {
+		Metadata *Metadata `json:"metadata,omitempty"`
+		RuleSet  *RuleSet  `json:"ruleSet,omitempty"`
+	}{metadata, ruleSet})
+	if err != nil {
+		return key
+	}
+
+	return key + "\x00" + string(contract)
 }
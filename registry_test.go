@@ -1,8 +1,11 @@
 package schemaregistry
 
 import (
+	"errors"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 )
@@ -43,3 +46,61 @@ func TestNewSchemaRegistryWithCustomOptions_HTTPClient(t *testing.T) {
 		t.Error("TestNewSchemaRegistryWithCustomOptions_HTTPClient: registry nil", reg)
 	}
 }
+
+func TestRegisterWrapsRegistryError(t *testing.T) {
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error_code":40402,"message":"Version 3 not found."}`)),
+			StatusCode: 404,
+		}, nil
+	}
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client))
+	if err != nil {
+		t.Fatal("TestRegisterWrapsRegistryError: ", err)
+	}
+
+	err = reg.Register("com.test", 3)
+	if !errors.Is(err, ErrVersionNotFound) {
+		t.Error("TestRegisterWrapsRegistryError: expected ErrVersionNotFound, got", err)
+	}
+}
+
+func TestGetSchemaByIDSingleRequest(t *testing.T) {
+	var requests []string
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		requests = append(requests, r.URL.String())
+		body := `{"schemaType":"PROTOBUF","schema":"syntax = \"proto3\";","references":[{"name":"other.proto","subject":"other","version":2}]}`
+		if strings.HasSuffix(r.URL.String(), "/schemas/ids/5/versions") {
+			body = `[{"subject":"orders","version":3}]`
+		}
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(body)), StatusCode: 200}, nil
+	}
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client))
+	if err != nil {
+		t.Fatal("TestGetSchemaByIDSingleRequest: ", err)
+	}
+
+	schema, err := reg.GetSchemaByID(5)
+	if err != nil {
+		t.Fatal("TestGetSchemaByIDSingleRequest: ", err)
+	}
+	if schema.ID != 5 || schema.Type() != PROTOBUF || len(schema.References) != 1 || schema.References[0].Subject != "other" {
+		t.Error("TestGetSchemaByIDSingleRequest: unexpected schema", schema)
+	}
+	if len(requests) != 1 || !strings.HasSuffix(requests[0], "/schemas/ids/5") {
+		t.Error("TestGetSchemaByIDSingleRequest: unexpected requests", requests)
+	}
+
+	resolved, err := reg.ResolveSubject(schema)
+	if err != nil || resolved.Subject != "orders" || resolved.Version != 3 || resolved.Schema != schema.Schema {
+		t.Error("TestGetSchemaByIDSingleRequest: unexpected resolved schema", resolved, err)
+	}
+	if schema.Subject != "" {
+		t.Error("TestGetSchemaByIDSingleRequest: fetched schema modified", schema)
+	}
+	if again, err := reg.ResolveSubject(schema); err != nil || again != resolved || len(requests) != 2 {
+		t.Error("TestGetSchemaByIDSingleRequest: subject resolved again", again, err, requests)
+	}
+}
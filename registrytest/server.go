@@ -0,0 +1,1318 @@
+// Package registrytest provides an in-memory schema registry server for tests.
+//
+// The server speaks the registry REST API closely enough for SchemaClient and
+// SchemaRegistry to be exercised end to end without a network: subjects,
+// versions, schema ids, references, soft and permanent deletes, config, mode
+// and compatibility checks are supported and failures are reported with the
+// registry error codes. Schema metadata and rule sets are stored with the
+// schemas, and the config default ones apply to schemas registered without
+// them while the override ones are merged into every registered schema.
+// Subjects qualified with a schema context are stored like any other subject
+// and their contexts listed. Every context has a range of schema ids of its
+// own, looked up with the subject query parameter as the registry does.
+package registrytest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hamba/avro"
+	jsoniter "github.com/json-iterator/go"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/compatibility"
+)
+
+type schemaEntry struct {
+	id         int
+	schema     string
+	schemaType schemaregistry.SchemaType
+	references []schemaregistry.Reference
+	metadata   *schemaregistry.Metadata
+	ruleSet    *schemaregistry.RuleSet
+}
+
+// schemaID identifies a schema by its context and its id within it.
+type schemaID struct {
+	context string
+	id      int
+}
+
+type versionEntry struct {
+	version int
+	id      int
+	context string
+	deleted bool
+}
+
+func (v *versionEntry) schemaID() schemaID {
+	return schemaID{v.context, v.id}
+}
+
+// contextOf returns the context of a subject, or of a context prefix such as
+// :.staging:, as used by the subject query parameter.
+func contextOf(subject string) string {
+	context, _ := schemaregistry.SplitSubject(subject)
+	return context
+}
+
+type subjectEntry struct {
+	versions      []*versionEntry
+	compatibility schemaregistry.CompatibilityLevel
+	contract      contractConfig
+	mode          schemaregistry.Mode
+}
+
+// contractConfig holds the config default and override metadata and rule sets.
+type contractConfig struct {
+	defaultMetadata  *schemaregistry.Metadata
+	overrideMetadata *schemaregistry.Metadata
+	defaultRuleSet   *schemaregistry.RuleSet
+	overrideRuleSet  *schemaregistry.RuleSet
+}
+
+func (c contractConfig) empty() bool {
+	return c.defaultMetadata == nil && c.overrideMetadata == nil && c.defaultRuleSet == nil && c.overrideRuleSet == nil
+}
+
+// Server is an in-memory schema registry backed by an httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	lastIDs       map[string]int
+	schemas       map[schemaID]*schemaEntry
+	subjects      map[string]*subjectEntry
+	compatibility schemaregistry.CompatibilityLevel
+	contract      contractConfig
+	mode          schemaregistry.Mode
+}
+
+// NewServer starts an empty registry. The global compatibility level is
+// BACKWARD and the global mode READWRITE, matching the registry defaults.
+// The caller should call Close when finished.
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.Start()
+
+	return s
+}
+
+// NewUnstartedServer returns a registry that is not yet listening, so the
+// underlying httptest.Server can be configured before calling Start.
+func NewUnstartedServer() *Server {
+	s := &Server{
+		lastIDs:       make(map[string]int),
+		schemas:       make(map[schemaID]*schemaEntry),
+		subjects:      make(map[string]*subjectEntry),
+		compatibility: schemaregistry.CompatibilityBackward,
+		mode:          schemaregistry.ModeReadWrite,
+	}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+type httpError struct {
+	status  int
+	code    int
+	message string
+}
+
+func errorf(status, code int, message string) *httpError {
+	return &httpError{status: status, code: code, message: message}
+}
+
+func subjectNotFound(subject string) *httpError {
+	return errorf(http.StatusNotFound, 40401, "Subject '"+subject+"' not found.")
+}
+
+func versionNotFound(version int) *httpError {
+	return errorf(http.StatusNotFound, 40402, "Version "+strconv.Itoa(version)+" not found.")
+}
+
+func schemaNotFound() *httpError {
+	return errorf(http.StatusNotFound, 40403, "Schema not found")
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	out, herr := s.route(r)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if herr != nil {
+		w.WriteHeader(herr.status)
+		_ = jsoniter.NewEncoder(w).Encode(map[string]interface{}{
+			"error_code": herr.code,
+			"message":    herr.message,
+		})
+		return
+	}
+	_ = jsoniter.NewEncoder(w).Encode(out)
+}
+
+func (s *Server) route(r *http.Request) (interface{}, *httpError) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	q := r.URL.Query()
+
+	switch {
+	case match(parts, "schemas", "types"):
+		return []string{"JSON", "PROTOBUF", "AVRO"}, nil
+	case match(parts, "schemas", "ids", "*") && r.Method == http.MethodGet:
+		return s.getSchemaByID(parts[2], contextOf(q.Get("subject")))
+	case match(parts, "schemas", "ids", "*", "versions") && r.Method == http.MethodGet:
+		return s.getSubjectVersionsByID(parts[2], contextOf(q.Get("subject")))
+	case match(parts, "contexts") && r.Method == http.MethodGet:
+		return s.getContexts(), nil
+	case match(parts, "subjects") && r.Method == http.MethodGet:
+		return s.getSubjects(q.Get("deleted") == "true", q.Get("subjectPrefix")), nil
+	case match(parts, "subjects", "*") && r.Method == http.MethodPost:
+		return s.lookupSchema(r, parts[1])
+	case match(parts, "subjects", "*") && r.Method == http.MethodDelete:
+		return s.deleteSubject(parts[1], q.Get("permanent") == "true")
+	case match(parts, "subjects", "*", "versions") && r.Method == http.MethodGet:
+		return s.getVersions(parts[1], q.Get("deleted") == "true")
+	case match(parts, "subjects", "*", "versions") && r.Method == http.MethodPost:
+		return s.registerSchema(r, parts[1])
+	case match(parts, "subjects", "*", "versions", "*") && r.Method == http.MethodGet:
+		return s.getSchemaByVersion(parts[1], parts[3], q.Get("deleted") == "true")
+	case match(parts, "subjects", "*", "versions", "*") && r.Method == http.MethodDelete:
+		return s.deleteVersion(parts[1], parts[3], q.Get("permanent") == "true")
+	case match(parts, "subjects", "*", "versions", "*", "schema") && r.Method == http.MethodGet:
+		return s.getRawSchemaByVersion(parts[1], parts[3])
+	case match(parts, "subjects", "*", "versions", "*", "referencedby") && r.Method == http.MethodGet:
+		return s.getReferencedBy(parts[1], parts[3])
+	case match(parts, "compatibility", "subjects", "*", "versions") && r.Method == http.MethodPost:
+		return s.checkCompatibility(r, parts[2], "")
+	case match(parts, "compatibility", "subjects", "*", "versions", "*") && r.Method == http.MethodPost:
+		return s.checkCompatibility(r, parts[2], parts[4])
+	case match(parts, "config"):
+		return s.config(r, "")
+	case match(parts, "config", "*"):
+		return s.config(r, parts[1])
+	case match(parts, "mode"):
+		return s.modeHandler(r, "")
+	case match(parts, "mode", "*"):
+		return s.modeHandler(r, parts[1])
+	}
+
+	return nil, errorf(http.StatusNotFound, http.StatusNotFound, "HTTP 404 Not Found")
+}
+
+// match reports whether the path parts equal the pattern, where * matches any
+// single non-empty part.
+func match(parts []string, pattern ...string) bool {
+	if len(parts) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if parts[i] == "" || (p != "*" && p != parts[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Server) getSchemaByID(rawID, context string) (interface{}, *httpError) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		return nil, schemaNotFound()
+	}
+	entry, ok := s.schemas[schemaID{context, id}]
+	if !ok {
+		return nil, schemaNotFound()
+	}
+
+	out := map[string]interface{}{"schema": entry.schema}
+	if entry.schemaType != schemaregistry.AVRO {
+		out["schemaType"] = entry.schemaType
+	}
+	if len(entry.references) > 0 {
+		out["references"] = entry.references
+	}
+	if entry.metadata != nil {
+		out["metadata"] = entry.metadata
+	}
+	if entry.ruleSet != nil {
+		out["ruleSet"] = entry.ruleSet
+	}
+
+	return out, nil
+}
+
+func (s *Server) getSubjectVersionsByID(rawID, context string) (interface{}, *httpError) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		return nil, schemaNotFound()
+	}
+	key := schemaID{context, id}
+	if _, ok := s.schemas[key]; !ok {
+		return nil, schemaNotFound()
+	}
+
+	out := []schemaregistry.SubjectVersion{}
+	for _, name := range s.sortedSubjects() {
+		for _, v := range s.subjects[name].versions {
+			if v.schemaID() == key && !v.deleted {
+				out = append(out, schemaregistry.SubjectVersion{Subject: name, Version: v.version})
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func (s *Server) sortedSubjects() []string {
+	names := make([]string, 0, len(s.subjects))
+	for name := range s.subjects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (s *Server) getSubjects(deleted bool, prefix string) []string {
+	out := []string{}
+	for _, name := range s.sortedSubjects() {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if deleted || len(liveVersions(s.subjects[name])) > 0 {
+			out = append(out, name)
+		}
+	}
+
+	return out
+}
+
+func (s *Server) getContexts() []string {
+	seen := map[string]bool{schemaregistry.DefaultContext: true}
+	out := []string{schemaregistry.DefaultContext}
+	for _, name := range s.sortedSubjects() {
+		if context, _ := schemaregistry.SplitSubject(name); context != "" && !seen[context] {
+			seen[context] = true
+			out = append(out, context)
+		}
+	}
+	sort.Strings(out)
+
+	return out
+}
+
+func liveVersions(subject *subjectEntry) []*versionEntry {
+	var out []*versionEntry
+	for _, v := range subject.versions {
+		if !v.deleted {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// liveSubject returns the subject if it has at least one version that is not
+// soft deleted.
+func (s *Server) liveSubject(name string) (*subjectEntry, *httpError) {
+	subject, ok := s.subjects[name]
+	if !ok || len(liveVersions(subject)) == 0 {
+		return nil, subjectNotFound(name)
+	}
+
+	return subject, nil
+}
+
+func (s *Server) getVersions(name string, deleted bool) (interface{}, *httpError) {
+	subject, ok := s.subjects[name]
+	if !ok || (!deleted && len(liveVersions(subject)) == 0) {
+		return nil, subjectNotFound(name)
+	}
+
+	out := []int{}
+	for _, v := range subject.versions {
+		if deleted || !v.deleted {
+			out = append(out, v.version)
+		}
+	}
+
+	return out, nil
+}
+
+// findVersion resolves a version path segment, which is either a number or
+// "latest", within the subject.
+func (s *Server) findVersion(name, rawVersion string, deleted bool) (*versionEntry, *httpError) {
+	subject, ok := s.subjects[name]
+	if !ok || (!deleted && len(liveVersions(subject)) == 0) {
+		return nil, subjectNotFound(name)
+	}
+
+	if rawVersion == "latest" || rawVersion == "-1" {
+		live := liveVersions(subject)
+		if len(live) == 0 {
+			return nil, versionNotFound(-1)
+		}
+		return live[len(live)-1], nil
+	}
+
+	version, err := strconv.Atoi(rawVersion)
+	if err != nil || version <= 0 {
+		return nil, errorf(http.StatusUnprocessableEntity, 42202, "The specified version '"+rawVersion+"' is not a valid version id.")
+	}
+	for _, v := range subject.versions {
+		if v.version == version && (deleted || !v.deleted) {
+			return v, nil
+		}
+	}
+
+	return nil, versionNotFound(version)
+}
+
+func (s *Server) schemaResponse(name string, v *versionEntry) schemaregistry.SchemaResponse {
+	entry := s.schemas[v.schemaID()]
+	schemaType := entry.schemaType
+
+	return schemaregistry.SchemaResponse{
+		ID:         entry.id,
+		Subject:    name,
+		Version:    v.version,
+		SchemaType: &schemaType,
+		Schema:     entry.schema,
+		References: entry.references,
+		Metadata:   entry.metadata,
+		RuleSet:    entry.ruleSet,
+	}
+}
+
+func (s *Server) getSchemaByVersion(name, rawVersion string, deleted bool) (interface{}, *httpError) {
+	v, herr := s.findVersion(name, rawVersion, deleted)
+	if herr != nil {
+		return nil, herr
+	}
+
+	return s.schemaResponse(name, v), nil
+}
+
+func (s *Server) getRawSchemaByVersion(name, rawVersion string) (interface{}, *httpError) {
+	v, herr := s.findVersion(name, rawVersion, false)
+	if herr != nil {
+		return nil, herr
+	}
+
+	return jsoniter.RawMessage(s.schemas[v.schemaID()].schema), nil
+}
+
+func (s *Server) getReferencedBy(name, rawVersion string) (interface{}, *httpError) {
+	v, herr := s.findVersion(name, rawVersion, false)
+	if herr != nil {
+		return nil, herr
+	}
+
+	return s.referencedBy(name, v.version), nil
+}
+
+func (s *Server) referencedBy(name string, version int) []int {
+	ids := []int{}
+	for _, subjectName := range s.sortedSubjects() {
+		for _, v := range liveVersions(s.subjects[subjectName]) {
+			for _, ref := range s.schemas[v.schemaID()].references {
+				if ref.Subject == name && ref.Version == version {
+					ids = append(ids, v.id)
+				}
+			}
+		}
+	}
+	sort.Ints(ids)
+
+	return ids
+}
+
+type schemaRequest struct {
+	Schema     string                     `json:"schema"`
+	SchemaType schemaregistry.SchemaType  `json:"schemaType"`
+	References []schemaregistry.Reference `json:"references"`
+	ID         int                        `json:"id"`
+	Version    int                        `json:"version"`
+	Metadata   *schemaregistry.Metadata   `json:"metadata"`
+	RuleSet    *schemaregistry.RuleSet    `json:"ruleSet"`
+}
+
+func decodeSchemaRequest(r *http.Request) (schemaRequest, *httpError) {
+	var req schemaRequest
+	if err := jsoniter.NewDecoder(r.Body).Decode(&req); err != nil {
+		return schemaRequest{}, errorf(http.StatusBadRequest, http.StatusBadRequest, "Unrecognized request body")
+	}
+	switch req.SchemaType {
+	case "":
+		req.SchemaType = schemaregistry.AVRO
+	case "JSON":
+		req.SchemaType = schemaregistry.JSONSCHEMA
+	}
+	if req.Schema == "" {
+		return schemaRequest{}, errorf(http.StatusUnprocessableEntity, 42201, "Empty schema")
+	}
+	if r.URL.Query().Get("normalize") == "true" {
+		normalized, err := schemaregistry.Normalize(req.SchemaType, req.Schema)
+		if err != nil {
+			return schemaRequest{}, errorf(http.StatusUnprocessableEntity, 42201, "Invalid schema: "+err.Error())
+		}
+		req.Schema = normalized
+	}
+
+	return req, nil
+}
+
+// resolveReferences returns the referenced schemas, dependencies first.
+func (s *Server) resolveReferences(refs []schemaregistry.Reference, seen map[schemaID]bool) ([]*schemaEntry, *httpError) {
+	var out []*schemaEntry
+	for _, ref := range refs {
+		v, herr := s.findVersion(ref.Subject, strconv.Itoa(ref.Version), false)
+		if herr != nil {
+			return nil, errorf(http.StatusUnprocessableEntity, 42201, "Invalid schema reference "+ref.Name+": "+herr.message)
+		}
+		if seen[v.schemaID()] {
+			continue
+		}
+		seen[v.schemaID()] = true
+		entry := s.schemas[v.schemaID()]
+		deps, herr := s.resolveReferences(entry.references, seen)
+		if herr != nil {
+			return nil, herr
+		}
+		out = append(out, deps...)
+		out = append(out, entry)
+	}
+
+	return out, nil
+}
+
+// parseAvro parses an AVRO schema together with its references.
+func (s *Server) parseAvro(schema string, refs []schemaregistry.Reference) (avro.Schema, *httpError) {
+	deps, herr := s.resolveReferences(refs, map[schemaID]bool{})
+	if herr != nil {
+		return nil, herr
+	}
+
+	cache := &avro.SchemaCache{}
+	for _, dep := range deps {
+		if _, err := avro.ParseWithCache(dep.schema, "", cache); err != nil {
+			return nil, errorf(http.StatusUnprocessableEntity, 42201, "Invalid schema reference: "+err.Error())
+		}
+	}
+	parsed, err := avro.ParseWithCache(schema, "", cache)
+	if err != nil {
+		return nil, errorf(http.StatusUnprocessableEntity, 42201, "Invalid schema: "+err.Error())
+	}
+
+	return parsed, nil
+}
+
+func (s *Server) validate(req schemaRequest) *httpError {
+	switch req.SchemaType {
+	case schemaregistry.AVRO:
+		_, herr := s.parseAvro(req.Schema, req.References)
+		return herr
+	case schemaregistry.JSONSCHEMA:
+		if !jsoniter.Valid([]byte(req.Schema)) {
+			return errorf(http.StatusUnprocessableEntity, 42201, "Invalid schema: not valid JSON")
+		}
+	case schemaregistry.PROTOBUF:
+		if _, herr := s.resolveReferences(req.References, map[schemaID]bool{}); herr != nil {
+			return herr
+		}
+	default:
+		return errorf(http.StatusUnprocessableEntity, 42201, "Unsupported schema type "+string(req.SchemaType))
+	}
+
+	return nil
+}
+
+func sameReferences(a, b []schemaregistry.Reference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// findSchema returns the id of an identical schema registered under any
+// subject of the context, the lowest one if several are.
+func (s *Server) findSchema(context string, req schemaRequest) (int, bool) {
+	var ids []int
+	for key := range s.schemas {
+		if key.context == context {
+			ids = append(ids, key.id)
+		}
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		entry := s.schemas[schemaID{context, id}]
+		if entry.schema == req.Schema && entry.schemaType == req.SchemaType && sameReferences(entry.references, req.References) &&
+			reflect.DeepEqual(entry.metadata, req.Metadata) && reflect.DeepEqual(entry.ruleSet, req.RuleSet) {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+func (s *Server) effectiveMode(name string) schemaregistry.Mode {
+	if subject, ok := s.subjects[name]; ok && subject.mode != "" {
+		return subject.mode
+	}
+
+	return s.mode
+}
+
+// effectiveContract returns the config of the subject, each value falling
+// back to the global one when the subject does not set it.
+func (s *Server) effectiveContract(name string) contractConfig {
+	config := s.contract
+	subject, ok := s.subjects[name]
+	if !ok {
+		return config
+	}
+	if subject.contract.defaultMetadata != nil {
+		config.defaultMetadata = subject.contract.defaultMetadata
+	}
+	if subject.contract.overrideMetadata != nil {
+		config.overrideMetadata = subject.contract.overrideMetadata
+	}
+	if subject.contract.defaultRuleSet != nil {
+		config.defaultRuleSet = subject.contract.defaultRuleSet
+	}
+	if subject.contract.overrideRuleSet != nil {
+		config.overrideRuleSet = subject.contract.overrideRuleSet
+	}
+
+	return config
+}
+
+// applyContract returns the metadata and rule set of a schema registered
+// under the subject: the requested ones or the config defaults, with the
+// config overrides merged in.
+func (s *Server) applyContract(name string, metadata *schemaregistry.Metadata, ruleSet *schemaregistry.RuleSet) (*schemaregistry.Metadata, *schemaregistry.RuleSet) {
+	config := s.effectiveContract(name)
+	if metadata == nil {
+		metadata = config.defaultMetadata
+	}
+	if ruleSet == nil {
+		ruleSet = config.defaultRuleSet
+	}
+
+	return mergeMetadata(metadata, config.overrideMetadata), mergeRuleSet(ruleSet, config.overrideRuleSet)
+}
+
+// mergeMetadata returns the metadata with the tags, properties and sensitive
+// fields of the override added, the override winning on conflicts.
+func mergeMetadata(metadata, override *schemaregistry.Metadata) *schemaregistry.Metadata {
+	if override == nil {
+		return metadata
+	}
+	if metadata == nil {
+		return override
+	}
+
+	out := &schemaregistry.Metadata{}
+	for _, m := range []*schemaregistry.Metadata{metadata, override} {
+		for path, tags := range m.Tags {
+			if out.Tags == nil {
+				out.Tags = make(map[string][]string)
+			}
+			out.Tags[path] = tags
+		}
+		for key, value := range m.Properties {
+			if out.Properties == nil {
+				out.Properties = make(map[string]string)
+			}
+			out.Properties[key] = value
+		}
+		for _, field := range m.Sensitive {
+			if !containsString(out.Sensitive, field) {
+				out.Sensitive = append(out.Sensitive, field)
+			}
+		}
+	}
+
+	return out
+}
+
+// mergeRuleSet returns the rule set with the rules of the override added, an
+// override rule replacing the rule with the same name.
+func mergeRuleSet(ruleSet, override *schemaregistry.RuleSet) *schemaregistry.RuleSet {
+	if override == nil {
+		return ruleSet
+	}
+	if ruleSet == nil {
+		return override
+	}
+
+	return &schemaregistry.RuleSet{
+		MigrationRules: mergeRules(ruleSet.MigrationRules, override.MigrationRules),
+		DomainRules:    mergeRules(ruleSet.DomainRules, override.DomainRules),
+	}
+}
+
+func mergeRules(rules, override []schemaregistry.Rule) []schemaregistry.Rule {
+	var out []schemaregistry.Rule
+	for _, rule := range rules {
+		replaced := false
+		for _, o := range override {
+			replaced = replaced || o.Name == rule.Name
+		}
+		if !replaced {
+			out = append(out, rule)
+		}
+	}
+
+	return append(out, override...)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) effectiveCompatibility(name string) schemaregistry.CompatibilityLevel {
+	if subject, ok := s.subjects[name]; ok && subject.compatibility != "" {
+		return subject.compatibility
+	}
+
+	return s.compatibility
+}
+
+func (s *Server) registerSchema(r *http.Request, name string) (interface{}, *httpError) {
+	req, herr := decodeSchemaRequest(r)
+	if herr != nil {
+		return nil, herr
+	}
+	mode := s.effectiveMode(name)
+	if mode == schemaregistry.ModeReadOnly {
+		return nil, errorf(http.StatusUnprocessableEntity, 42205, "Subject "+name+" is in read-only mode")
+	}
+	if (req.ID != 0 || req.Version != 0) && mode != schemaregistry.ModeImport {
+		return nil, errorf(http.StatusUnprocessableEntity, 42205, "Subject "+name+" is not in import mode")
+	}
+	if herr := s.validate(req); herr != nil {
+		return nil, herr
+	}
+	if mode != schemaregistry.ModeImport {
+		req.Metadata, req.RuleSet = s.applyContract(name, req.Metadata, req.RuleSet)
+	}
+
+	subject, ok := s.subjects[name]
+	if !ok {
+		subject = &subjectEntry{}
+	}
+
+	// Registering an already registered schema returns the existing id.
+	context := contextOf(name)
+	if id, ok := s.findSchema(context, req); ok {
+		for _, v := range liveVersions(subject) {
+			if v.id == id {
+				return map[string]int{"id": id}, nil
+			}
+		}
+	}
+
+	if mode == schemaregistry.ModeImport {
+		return s.importSchema(name, subject, req)
+	}
+
+	messages, herr := s.compatibilityMessages(name, req, liveVersions(subject))
+	if herr != nil {
+		return nil, herr
+	}
+	if len(messages) > 0 {
+		return nil, errorf(http.StatusConflict, 409, "Schema being registered is incompatible with an earlier schema for subject \""+name+"\", details: "+strings.Join(messages, "; "))
+	}
+
+	id, ok := s.findSchema(context, req)
+	if !ok {
+		id = s.lastIDs[context] + 1
+		s.schemas[schemaID{context, id}] = newSchemaEntry(id, req)
+	}
+	s.bumpLastID(context, id)
+	subject.versions = append(subject.versions, &versionEntry{version: nextVersion(subject), id: id, context: context})
+	s.subjects[name] = subject
+
+	return map[string]int{"id": id}, nil
+}
+
+func (s *Server) importSchema(name string, subject *subjectEntry, req schemaRequest) (interface{}, *httpError) {
+	context := contextOf(name)
+	id := req.ID
+	if id == 0 {
+		if existing, ok := s.findSchema(context, req); ok {
+			id = existing
+		} else {
+			id = s.lastIDs[context] + 1
+		}
+	}
+	if entry, ok := s.schemas[schemaID{context, id}]; ok {
+		if entry.schema != req.Schema || entry.schemaType != req.SchemaType {
+			return nil, errorf(http.StatusUnprocessableEntity, 42205, "Overwrite new schema with id "+strconv.Itoa(id)+" is not permitted.")
+		}
+	} else {
+		s.schemas[schemaID{context, id}] = newSchemaEntry(id, req)
+	}
+	s.bumpLastID(context, id)
+
+	version := req.Version
+	if version == 0 {
+		version = nextVersion(subject)
+	}
+	for _, v := range subject.versions {
+		if v.version == version {
+			return nil, errorf(http.StatusUnprocessableEntity, 42205, "Version "+strconv.Itoa(version)+" already exists for subject "+name)
+		}
+	}
+	subject.versions = append(subject.versions, &versionEntry{version: version, id: id, context: context})
+	sort.Slice(subject.versions, func(i, j int) bool { return subject.versions[i].version < subject.versions[j].version })
+	s.subjects[name] = subject
+
+	return map[string]int{"id": id}, nil
+}
+
+func newSchemaEntry(id int, req schemaRequest) *schemaEntry {
+	return &schemaEntry{
+		id:         id,
+		schema:     req.Schema,
+		schemaType: req.SchemaType,
+		references: req.References,
+		metadata:   req.Metadata,
+		ruleSet:    req.RuleSet,
+	}
+}
+
+func (s *Server) bumpLastID(context string, id int) {
+	if id > s.lastIDs[context] {
+		s.lastIDs[context] = id
+	}
+}
+
+func nextVersion(subject *subjectEntry) int {
+	if len(subject.versions) == 0 {
+		return 1
+	}
+
+	return subject.versions[len(subject.versions)-1].version + 1
+}
+
+func (s *Server) lookupSchema(r *http.Request, name string) (interface{}, *httpError) {
+	req, herr := decodeSchemaRequest(r)
+	if herr != nil {
+		return nil, herr
+	}
+	subject, herr := s.liveSubject(name)
+	if herr != nil {
+		return nil, herr
+	}
+
+	// The metadata and rule set are only matched when requested, the latest
+	// matching version is returned otherwise.
+	metadata, ruleSet := s.applyContract(name, req.Metadata, req.RuleSet)
+	versions := liveVersions(subject)
+	for i := len(versions) - 1; i >= 0; i-- {
+		entry := s.schemas[versions[i].schemaID()]
+		if entry.schema != req.Schema || entry.schemaType != req.SchemaType || !sameReferences(entry.references, req.References) {
+			continue
+		}
+		if (req.Metadata != nil && !reflect.DeepEqual(entry.metadata, metadata)) || (req.RuleSet != nil && !reflect.DeepEqual(entry.ruleSet, ruleSet)) {
+			continue
+		}
+		return s.schemaResponse(name, versions[i]), nil
+	}
+
+	return nil, schemaNotFound()
+}
+
+func (s *Server) deleteSubject(name string, permanent bool) (interface{}, *httpError) {
+	subject, ok := s.subjects[name]
+	if !ok {
+		return nil, subjectNotFound(name)
+	}
+	live := liveVersions(subject)
+	if permanent && len(live) > 0 {
+		return nil, errorf(http.StatusNotFound, 40405, "Subject '"+name+"' was not deleted first before being permanently deleted")
+	}
+	if !permanent && len(live) == 0 {
+		return nil, errorf(http.StatusNotFound, 40404, "Subject '"+name+"' was soft deleted.Set permanent=true to delete permanently")
+	}
+
+	for _, v := range subject.versions {
+		if ids := s.referencedBy(name, v.version); len(ids) > 0 {
+			return nil, errorf(http.StatusUnprocessableEntity, 42206, "One or more references exist to the schema {subject="+name+",version="+strconv.Itoa(v.version)+"}")
+		}
+	}
+
+	deleted := []int{}
+	for _, v := range subject.versions {
+		deleted = append(deleted, v.version)
+		v.deleted = true
+	}
+	if permanent {
+		delete(s.subjects, name)
+		s.dropUnusedSchemas()
+	}
+
+	return deleted, nil
+}
+
+func (s *Server) deleteVersion(name, rawVersion string, permanent bool) (interface{}, *httpError) {
+	v, herr := s.findVersion(name, rawVersion, permanent)
+	if herr != nil {
+		return nil, herr
+	}
+	if permanent && !v.deleted {
+		return nil, errorf(http.StatusNotFound, 40407, "Subject '"+name+"' Version "+strconv.Itoa(v.version)+" was not deleted first before being permanently deleted")
+	}
+	if ids := s.referencedBy(name, v.version); len(ids) > 0 {
+		return nil, errorf(http.StatusUnprocessableEntity, 42206, "One or more references exist to the schema {subject="+name+",version="+strconv.Itoa(v.version)+"}")
+	}
+
+	v.deleted = true
+	if permanent {
+		subject := s.subjects[name]
+		for i, candidate := range subject.versions {
+			if candidate == v {
+				subject.versions = append(subject.versions[:i], subject.versions[i+1:]...)
+				break
+			}
+		}
+		if len(subject.versions) == 0 {
+			delete(s.subjects, name)
+		}
+		s.dropUnusedSchemas()
+	}
+
+	return v.version, nil
+}
+
+// dropUnusedSchemas forgets schema ids no longer registered under any subject
+// version, soft deleted versions included.
+func (s *Server) dropUnusedSchemas() {
+	used := map[schemaID]bool{}
+	for _, subject := range s.subjects {
+		for _, v := range subject.versions {
+			used[v.schemaID()] = true
+		}
+	}
+	for key := range s.schemas {
+		if !used[key] {
+			delete(s.schemas, key)
+		}
+	}
+}
+
+func (s *Server) checkCompatibility(r *http.Request, name, rawVersion string) (interface{}, *httpError) {
+	req, herr := decodeSchemaRequest(r)
+	if herr != nil {
+		return nil, herr
+	}
+	if herr := s.validate(req); herr != nil {
+		return nil, herr
+	}
+
+	var against []*versionEntry
+	if rawVersion == "" {
+		if subject, ok := s.subjects[name]; ok {
+			against = liveVersions(subject)
+		}
+	} else {
+		v, herr := s.findVersion(name, rawVersion, false)
+		if herr != nil {
+			return nil, herr
+		}
+		against = []*versionEntry{v}
+	}
+
+	var messages []string
+	if rawVersion == "" {
+		messages, herr = s.compatibilityMessages(name, req, against)
+	} else {
+		messages, herr = s.compareWith(s.effectiveCompatibility(name), req, against)
+	}
+	if herr != nil {
+		return nil, herr
+	}
+
+	return schemaregistry.CompatibilityResponse{IsCompatible: len(messages) == 0, Messages: messages}, nil
+}
+
+// compatibilityMessages checks the schema against the versions selected by the
+// subject compatibility level, returning a message per incompatibility.
+func (s *Server) compatibilityMessages(name string, req schemaRequest, versions []*versionEntry) ([]string, *httpError) {
+	level := s.effectiveCompatibility(name)
+	if len(versions) == 0 || level == schemaregistry.CompatibilityNone {
+		return nil, nil
+	}
+	switch level {
+	case schemaregistry.CompatibilityBackward, schemaregistry.CompatibilityForward, schemaregistry.CompatibilityFull:
+		versions = versions[len(versions)-1:]
+	}
+
+	return s.compareWith(level, req, versions)
+}
+
+func (s *Server) compareWith(level schemaregistry.CompatibilityLevel, req schemaRequest, versions []*versionEntry) ([]string, *httpError) {
+	if level == schemaregistry.CompatibilityNone {
+		return nil, nil
+	}
+	switch req.SchemaType {
+	case schemaregistry.PROTOBUF:
+		return s.compareProtobuf(level, req, versions)
+	case schemaregistry.JSONSCHEMA:
+		return s.compareJSONSchema(level, req, versions)
+	}
+
+	candidate, herr := s.parseAvro(req.Schema, req.References)
+	if herr != nil {
+		return nil, herr
+	}
+
+	var messages []string
+	compat := avro.NewSchemaCompatibility()
+	for _, v := range versions {
+		entry := s.schemas[v.schemaID()]
+		if entry.schemaType != schemaregistry.AVRO {
+			messages = append(messages, "Incompatible schema type "+string(entry.schemaType)+" at version "+strconv.Itoa(v.version))
+			continue
+		}
+		existing, herr := s.parseAvro(entry.schema, entry.references)
+		if herr != nil {
+			return nil, herr
+		}
+
+		switch level {
+		case schemaregistry.CompatibilityBackward, schemaregistry.CompatibilityBackwardTransitive:
+			if err := compat.Compatible(candidate, existing); err != nil {
+				messages = append(messages, "Version "+strconv.Itoa(v.version)+": "+err.Error())
+			}
+		case schemaregistry.CompatibilityForward, schemaregistry.CompatibilityForwardTransitive:
+			if err := compat.Compatible(existing, candidate); err != nil {
+				messages = append(messages, "Version "+strconv.Itoa(v.version)+": "+err.Error())
+			}
+		case schemaregistry.CompatibilityFull, schemaregistry.CompatibilityFullTransitive:
+			if err := compat.Compatible(candidate, existing); err != nil {
+				messages = append(messages, "Version "+strconv.Itoa(v.version)+": "+err.Error())
+			}
+			if err := compat.Compatible(existing, candidate); err != nil {
+				messages = append(messages, "Version "+strconv.Itoa(v.version)+": "+err.Error())
+			}
+		}
+	}
+
+	return messages, nil
+}
+
+// compareProtobuf checks a PROTOBUF schema with the local checker, which
+// follows the registry rules.
+func (s *Server) compareProtobuf(level schemaregistry.CompatibilityLevel, req schemaRequest, versions []*versionEntry) ([]string, *httpError) {
+	candidate, herr := s.protobufSchema(req.Schema, req.References)
+	if herr != nil {
+		return nil, herr
+	}
+
+	level = singleVersionLevel(level)
+
+	var messages []string
+	for _, v := range versions {
+		entry := s.schemas[v.schemaID()]
+		if entry.schemaType != schemaregistry.PROTOBUF {
+			messages = append(messages, "Incompatible schema type "+string(entry.schemaType)+" at version "+strconv.Itoa(v.version))
+			continue
+		}
+		existing, herr := s.protobufSchema(entry.schema, entry.references)
+		if herr != nil {
+			return nil, herr
+		}
+
+		res, err := compatibility.CheckProtobuf(level, candidate, existing)
+		if err != nil {
+			return nil, errorf(http.StatusUnprocessableEntity, 42201, "Invalid schema: "+err.Error())
+		}
+		for _, m := range res.Messages() {
+			messages = append(messages, "Version "+strconv.Itoa(v.version)+": "+m)
+		}
+	}
+
+	return messages, nil
+}
+
+// compareJSONSchema checks a JSONSCHEMA schema with the local checker.
+func (s *Server) compareJSONSchema(level schemaregistry.CompatibilityLevel, req schemaRequest, versions []*versionEntry) ([]string, *httpError) {
+	level = singleVersionLevel(level)
+
+	var messages []string
+	for _, v := range versions {
+		entry := s.schemas[v.schemaID()]
+		if entry.schemaType != schemaregistry.JSONSCHEMA {
+			messages = append(messages, "Incompatible schema type "+string(entry.schemaType)+" at version "+strconv.Itoa(v.version))
+			continue
+		}
+		res, err := compatibility.CheckJSONSchema(level, req.Schema, entry.schema)
+		if err != nil {
+			return nil, errorf(http.StatusUnprocessableEntity, 42201, "Invalid schema: "+err.Error())
+		}
+		for _, m := range res.Messages() {
+			messages = append(messages, "Version "+strconv.Itoa(v.version)+": "+m)
+		}
+	}
+
+	return messages, nil
+}
+
+// singleVersionLevel maps transitive levels to their single version
+// counterpart. The versions a level applies to are selected by the caller and
+// each is checked on its own.
+func singleVersionLevel(level schemaregistry.CompatibilityLevel) schemaregistry.CompatibilityLevel {
+	switch level {
+	case schemaregistry.CompatibilityBackwardTransitive:
+		return schemaregistry.CompatibilityBackward
+	case schemaregistry.CompatibilityForwardTransitive:
+		return schemaregistry.CompatibilityForward
+	case schemaregistry.CompatibilityFullTransitive:
+		return schemaregistry.CompatibilityFull
+	}
+
+	return level
+}
+
+// protobufSchema collects the text of a PROTOBUF schema and of its references
+// keyed by import path.
+func (s *Server) protobufSchema(schema string, refs []schemaregistry.Reference) (compatibility.ProtobufSchema, *httpError) {
+	out := compatibility.ProtobufSchema{Schema: schema, References: map[string]string{}}
+	pending := refs
+	for len(pending) > 0 {
+		ref := pending[0]
+		pending = pending[1:]
+		if _, ok := out.References[ref.Name]; ok {
+			continue
+		}
+		v, herr := s.findVersion(ref.Subject, strconv.Itoa(ref.Version), false)
+		if herr != nil {
+			return compatibility.ProtobufSchema{}, errorf(http.StatusUnprocessableEntity, 42201, "Invalid schema reference "+ref.Name+": "+herr.message)
+		}
+		entry := s.schemas[v.schemaID()]
+		out.References[ref.Name] = entry.schema
+		pending = append(pending, entry.references...)
+	}
+
+	return out, nil
+}
+
+func validLevel(level schemaregistry.CompatibilityLevel) bool {
+	switch level {
+	case schemaregistry.CompatibilityNone,
+		schemaregistry.CompatibilityBackward, schemaregistry.CompatibilityBackwardTransitive,
+		schemaregistry.CompatibilityForward, schemaregistry.CompatibilityForwardTransitive,
+		schemaregistry.CompatibilityFull, schemaregistry.CompatibilityFullTransitive:
+		return true
+	}
+
+	return false
+}
+
+func (s *Server) config(r *http.Request, name string) (interface{}, *httpError) {
+	switch r.Method {
+	case http.MethodGet:
+		if name == "" {
+			return configResponse("compatibilityLevel", s.compatibility, s.contract), nil
+		}
+		subject, ok := s.subjects[name]
+		if !ok || (subject.compatibility == "" && subject.contract.empty()) {
+			if r.URL.Query().Get("defaultToGlobal") == "true" {
+				return configResponse("compatibilityLevel", s.compatibility, s.contract), nil
+			}
+			return nil, errorf(http.StatusNotFound, 40408, "Subject '"+name+"' does not have subject-level compatibility configured")
+		}
+		if r.URL.Query().Get("defaultToGlobal") == "true" {
+			return configResponse("compatibilityLevel", s.effectiveCompatibility(name), s.effectiveContract(name)), nil
+		}
+		return configResponse("compatibilityLevel", subject.compatibility, subject.contract), nil
+	case http.MethodPut:
+		var req struct {
+			Compatibility    schemaregistry.CompatibilityLevel `json:"compatibility"`
+			DefaultMetadata  *schemaregistry.Metadata          `json:"defaultMetadata"`
+			OverrideMetadata *schemaregistry.Metadata          `json:"overrideMetadata"`
+			DefaultRuleSet   *schemaregistry.RuleSet           `json:"defaultRuleSet"`
+			OverrideRuleSet  *schemaregistry.RuleSet           `json:"overrideRuleSet"`
+		}
+		err := jsoniter.NewDecoder(r.Body).Decode(&req)
+		contract := contractConfig{
+			defaultMetadata:  req.DefaultMetadata,
+			overrideMetadata: req.OverrideMetadata,
+			defaultRuleSet:   req.DefaultRuleSet,
+			overrideRuleSet:  req.OverrideRuleSet,
+		}
+		if err != nil || (req.Compatibility == "" && contract.empty()) || (req.Compatibility != "" && !validLevel(req.Compatibility)) {
+			return nil, errorf(http.StatusUnprocessableEntity, 42203, "Invalid compatibility level. Valid values are none, backward, forward, full, backward_transitive, forward_transitive, and full_transitive")
+		}
+		if name == "" {
+			if req.Compatibility != "" {
+				s.compatibility = req.Compatibility
+			}
+			s.contract = setContract(s.contract, contract)
+		} else {
+			subject := s.subject(name)
+			if req.Compatibility != "" {
+				subject.compatibility = req.Compatibility
+			}
+			subject.contract = setContract(subject.contract, contract)
+		}
+		return configResponse("compatibility", req.Compatibility, contract), nil
+	case http.MethodDelete:
+		if name == "" {
+			previous := s.compatibility
+			s.compatibility = schemaregistry.CompatibilityBackward
+			s.contract = contractConfig{}
+			return map[string]interface{}{"compatibilityLevel": previous}, nil
+		}
+		subject, ok := s.subjects[name]
+		if !ok || (subject.compatibility == "" && subject.contract.empty()) {
+			return nil, subjectNotFound(name)
+		}
+		previous := subject.compatibility
+		subject.compatibility = ""
+		subject.contract = contractConfig{}
+		return map[string]interface{}{"compatibilityLevel": previous}, nil
+	}
+
+	return nil, errorf(http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "HTTP 405 Method Not Allowed")
+}
+
+// configResponse returns the config body, the compatibility level being named
+// differently in responses to reads and updates.
+func configResponse(levelKey string, level schemaregistry.CompatibilityLevel, contract contractConfig) map[string]interface{} {
+	out := map[string]interface{}{}
+	if level != "" {
+		out[levelKey] = level
+	}
+	if contract.defaultMetadata != nil {
+		out["defaultMetadata"] = contract.defaultMetadata
+	}
+	if contract.overrideMetadata != nil {
+		out["overrideMetadata"] = contract.overrideMetadata
+	}
+	if contract.defaultRuleSet != nil {
+		out["defaultRuleSet"] = contract.defaultRuleSet
+	}
+	if contract.overrideRuleSet != nil {
+		out["overrideRuleSet"] = contract.overrideRuleSet
+	}
+
+	return out
+}
+
+// setContract returns the config with the values set by the update replaced.
+func setContract(config, update contractConfig) contractConfig {
+	if update.defaultMetadata != nil {
+		config.defaultMetadata = update.defaultMetadata
+	}
+	if update.overrideMetadata != nil {
+		config.overrideMetadata = update.overrideMetadata
+	}
+	if update.defaultRuleSet != nil {
+		config.defaultRuleSet = update.defaultRuleSet
+	}
+	if update.overrideRuleSet != nil {
+		config.overrideRuleSet = update.overrideRuleSet
+	}
+
+	return config
+}
+
+func (s *Server) modeHandler(r *http.Request, name string) (interface{}, *httpError) {
+	switch r.Method {
+	case http.MethodGet:
+		if name == "" {
+			return map[string]interface{}{"mode": s.mode}, nil
+		}
+		subject, ok := s.subjects[name]
+		if !ok || subject.mode == "" {
+			if r.URL.Query().Get("defaultToGlobal") == "true" {
+				return map[string]interface{}{"mode": s.mode}, nil
+			}
+			return nil, errorf(http.StatusNotFound, 40409, "Subject '"+name+"' does not have subject-level mode configured")
+		}
+		return map[string]interface{}{"mode": subject.mode}, nil
+	case http.MethodPut:
+		var req struct {
+			Mode schemaregistry.Mode `json:"mode"`
+		}
+		err := jsoniter.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case err != nil:
+			return nil, errorf(http.StatusUnprocessableEntity, 42204, "Invalid mode")
+		case req.Mode != schemaregistry.ModeReadWrite && req.Mode != schemaregistry.ModeReadOnly && req.Mode != schemaregistry.ModeImport:
+			return nil, errorf(http.StatusUnprocessableEntity, 42204, "Invalid mode. Valid values are READWRITE, READONLY, IMPORT")
+		}
+		if req.Mode == schemaregistry.ModeImport && r.URL.Query().Get("force") != "true" && s.hasSchemas(name) {
+			return nil, errorf(http.StatusUnprocessableEntity, 42205, "Cannot import since found existing subjects")
+		}
+		if name == "" {
+			s.mode = req.Mode
+		} else {
+			s.subject(name).mode = req.Mode
+		}
+		return map[string]interface{}{"mode": req.Mode}, nil
+	case http.MethodDelete:
+		if name == "" {
+			return nil, errorf(http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "HTTP 405 Method Not Allowed")
+		}
+		subject, ok := s.subjects[name]
+		if !ok || subject.mode == "" {
+			return nil, subjectNotFound(name)
+		}
+		previous := subject.mode
+		subject.mode = ""
+		return map[string]interface{}{"mode": previous}, nil
+	}
+
+	return nil, errorf(http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "HTTP 405 Method Not Allowed")
+}
+
+// hasSchemas reports whether the subject, or any subject if name is empty,
+// has registered versions.
+func (s *Server) hasSchemas(name string) bool {
+	if name != "" {
+		subject, ok := s.subjects[name]
+		return ok && len(subject.versions) > 0
+	}
+	for _, subject := range s.subjects {
+		if len(subject.versions) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subject returns the named subject, creating an empty one if needed so that
+// config and mode can be set before the first schema is registered.
+func (s *Server) subject(name string) *subjectEntry {
+	subject, ok := s.subjects[name]
+	if !ok {
+		subject = &subjectEntry{}
+		s.subjects[name] = subject
+	}
+
+	return subject
+}
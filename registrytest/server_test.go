@@ -0,0 +1,511 @@
+package registrytest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hamba/avro"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+)
+
+const userV1 = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"}]}`
+
+const userV2 = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"age","type":"int","default":0}]}`
+
+const userIncompatible = `{"type":"record","name":"User","namespace":"com.test","fields":[{"name":"name","type":"string"},{"name":"email","type":"string"}]}`
+
+func newClient(t *testing.T) (*Server, *schemaregistry.SchemaClient) {
+	t.Helper()
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+
+	c, err := schemaregistry.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal("newClient: ", err)
+	}
+
+	return srv, c
+}
+
+func TestServerRegisterAndFetch(t *testing.T) {
+	_, c := newClient(t)
+
+	id, err := c.CreateSchema("com.test-value", userV1, schemaregistry.AVRO)
+	if err != nil {
+		t.Fatal("TestServerRegisterAndFetch: ", err)
+	}
+	if id != 1 {
+		t.Error("TestServerRegisterAndFetch: expected id 1, got", id)
+	}
+
+	again, err := c.CreateSchema("com.test-value", userV1, schemaregistry.AVRO)
+	if err != nil || again != id {
+		t.Error("TestServerRegisterAndFetch: re-registering returned", again, err)
+	}
+
+	schema, err := c.GetSchemaByID(id)
+	if err != nil || schema != userV1 {
+		t.Error("TestServerRegisterAndFetch: GetSchemaByID returned", schema, err)
+	}
+
+	subjects, err := c.GetSubjects()
+	if err != nil || len(subjects) != 1 || subjects[0] != "com.test-value" {
+		t.Error("TestServerRegisterAndFetch: GetSubjects returned", subjects, err)
+	}
+
+	latest, err := c.GetLatestSchema("com.test-value")
+	if err != nil || latest.ID != id || latest.Version != 1 {
+		t.Error("TestServerRegisterAndFetch: GetLatestSchema returned", latest, err)
+	}
+
+	found, err := c.LookupSchema("com.test-value", userV1, schemaregistry.AVRO)
+	if err != nil || found.Version != 1 {
+		t.Error("TestServerRegisterAndFetch: LookupSchema returned", found, err)
+	}
+
+	_, err = c.LookupSchema("com.test-value", userV2, schemaregistry.AVRO)
+	if !errors.Is(err, schemaregistry.ErrSchemaNotFound) {
+		t.Error("TestServerRegisterAndFetch: expected ErrSchemaNotFound, got", err)
+	}
+}
+
+func TestServerNotFound(t *testing.T) {
+	_, c := newClient(t)
+
+	if _, err := c.GetVersions("missing"); !errors.Is(err, schemaregistry.ErrSubjectNotFound) {
+		t.Error("TestServerNotFound: expected ErrSubjectNotFound, got", err)
+	}
+	if _, err := c.GetSchemaByID(42); !errors.Is(err, schemaregistry.ErrSchemaNotFound) {
+		t.Error("TestServerNotFound: expected ErrSchemaNotFound, got", err)
+	}
+
+	if _, err := c.CreateSchema("com.test-value", userV1, schemaregistry.AVRO); err != nil {
+		t.Fatal("TestServerNotFound: ", err)
+	}
+	if _, err := c.GetSchemaByVersion("com.test-value", 7); !errors.Is(err, schemaregistry.ErrVersionNotFound) {
+		t.Error("TestServerNotFound: expected ErrVersionNotFound, got", err)
+	}
+	if _, err := c.CreateSchema("com.test-value", `{"type":"nope"}`, schemaregistry.AVRO); !errors.Is(err, schemaregistry.ErrInvalidSchema) {
+		t.Error("TestServerNotFound: expected ErrInvalidSchema, got", err)
+	}
+}
+
+func TestServerCompatibility(t *testing.T) {
+	_, c := newClient(t)
+
+	if _, err := c.CreateSchema("com.test-value", userV1, schemaregistry.AVRO); err != nil {
+		t.Fatal("TestServerCompatibility: ", err)
+	}
+
+	res, err := c.CheckCompatibility("com.test-value", userV2, schemaregistry.AVRO, int(schemaregistry.LatestVersion))
+	if err != nil || !res.IsCompatible {
+		t.Error("TestServerCompatibility: expected compatible, got", res, err)
+	}
+	res, err = c.CheckCompatibility("com.test-value", userIncompatible, schemaregistry.AVRO, int(schemaregistry.AllVersions))
+	if err != nil || res.IsCompatible || len(res.Messages) == 0 {
+		t.Error("TestServerCompatibility: expected incompatible, got", res, err)
+	}
+
+	if _, err := c.CreateSchema("com.test-value", userIncompatible, schemaregistry.AVRO); !errors.Is(err, schemaregistry.ErrIncompatibleSchema) {
+		t.Error("TestServerCompatibility: expected ErrIncompatibleSchema, got", err)
+	}
+
+	if _, err := c.SetConfig("com.test-value", schemaregistry.CompatibilityNone); err != nil {
+		t.Fatal("TestServerCompatibility: ", err)
+	}
+	level, err := c.GetConfig("com.test-value")
+	if err != nil || level != schemaregistry.CompatibilityNone {
+		t.Error("TestServerCompatibility: GetConfig returned", level, err)
+	}
+	if _, err := c.CreateSchema("com.test-value", userIncompatible, schemaregistry.AVRO); err != nil {
+		t.Error("TestServerCompatibility: registering with NONE failed", err)
+	}
+
+	if _, err := c.SetConfig("", "SIDEWAYS"); !errors.Is(err, schemaregistry.ErrInvalidCompatibilityLevel) {
+		t.Error("TestServerCompatibility: expected ErrInvalidCompatibilityLevel, got", err)
+	}
+	if _, err := c.GetConfig("other"); !errors.Is(err, schemaregistry.ErrSubjectCompatibilityNotFound) {
+		t.Error("TestServerCompatibility: expected ErrSubjectCompatibilityNotFound, got", err)
+	}
+}
+
+func TestServerReferencesAndDeletes(t *testing.T) {
+	_, c := newClient(t)
+
+	addressID, err := c.CreateSchema("com.test.Address", `{"type":"record","name":"Address","namespace":"com.test","fields":[{"name":"street","type":"string"}]}`, schemaregistry.AVRO)
+	if err != nil {
+		t.Fatal("TestServerReferencesAndDeletes: ", err)
+	}
+	ref := schemaregistry.Reference{Name: "com.test.Address", Subject: "com.test.Address", Version: 1}
+	personID, err := c.CreateSchema("com.test-value", `{"type":"record","name":"Person","namespace":"com.test","fields":[{"name":"address","type":"com.test.Address"}]}`, schemaregistry.AVRO, ref)
+	if err != nil {
+		t.Fatal("TestServerReferencesAndDeletes: ", err)
+	}
+
+	ids, err := c.GetSchemaReferencedBy("com.test.Address", 1)
+	if err != nil || len(ids) != 1 || ids[0] != personID {
+		t.Error("TestServerReferencesAndDeletes: GetSchemaReferencedBy returned", ids, err)
+	}
+
+	if _, err := c.DeleteSubject("com.test.Address", false); !errors.Is(err, schemaregistry.ErrReferenceExists) {
+		t.Error("TestServerReferencesAndDeletes: expected ErrReferenceExists, got", err)
+	}
+	if _, err := c.DeleteSubject("com.test-value", true); !errors.Is(err, schemaregistry.ErrSubjectNotSoftDeleted) {
+		t.Error("TestServerReferencesAndDeletes: expected ErrSubjectNotSoftDeleted, got", err)
+	}
+	if versions, err := c.DeleteSubject("com.test-value", false); err != nil || len(versions) != 1 {
+		t.Error("TestServerReferencesAndDeletes: soft delete returned", versions, err)
+	}
+	if _, err := c.GetLatestSchema("com.test-value"); !errors.Is(err, schemaregistry.ErrSubjectNotFound) {
+		t.Error("TestServerReferencesAndDeletes: expected ErrSubjectNotFound, got", err)
+	}
+	if _, err := c.DeleteSubject("com.test-value", true); err != nil {
+		t.Error("TestServerReferencesAndDeletes: permanent delete failed", err)
+	}
+	if _, err := c.DeleteSchemaVersion("com.test.Address", 1, false); err != nil {
+		t.Error("TestServerReferencesAndDeletes: deleting unreferenced version failed", err)
+	}
+	if _, err := c.GetSchemaByID(addressID); err != nil {
+		t.Error("TestServerReferencesAndDeletes: soft deleted schema should still resolve by id", err)
+	}
+}
+
+func TestServerMode(t *testing.T) {
+	_, c := newClient(t)
+
+	if _, err := c.SetMode("", schemaregistry.ModeImport); err != nil {
+		t.Fatal("TestServerMode: ", err)
+	}
+	payload := map[string]interface{}{"schema": userV1, "id": 100, "version": 3}
+	var out struct {
+		ID int `json:"id"`
+	}
+	if err := c.Request("POST", "/subjects/com.test-value/versions", payload, &out); err != nil || out.ID != 100 {
+		t.Error("TestServerMode: import returned", out.ID, err)
+	}
+	res, err := c.GetSchemaByVersion("com.test-value", 3)
+	if err != nil || res.ID != 100 {
+		t.Error("TestServerMode: imported version returned", res, err)
+	}
+
+	if _, err := c.SetMode("com.test-value", schemaregistry.ModeReadOnly); err != nil {
+		t.Fatal("TestServerMode: ", err)
+	}
+	if _, err := c.CreateSchema("com.test-value", userV2, schemaregistry.AVRO); !errors.Is(err, schemaregistry.ErrOperationNotPermitted) {
+		t.Error("TestServerMode: expected ErrOperationNotPermitted, got", err)
+	}
+	if _, err := c.SetMode("", "SOMETIMES"); !errors.Is(err, schemaregistry.ErrInvalidMode) {
+		t.Error("TestServerMode: expected ErrInvalidMode, got", err)
+	}
+}
+
+func TestServerWithSchemaRegistry(t *testing.T) {
+	srv, c := newClient(t)
+
+	id, err := c.CreateSchema("com.test-value", userV1, schemaregistry.AVRO)
+	if err != nil {
+		t.Fatal("TestServerWithSchemaRegistry: ", err)
+	}
+
+	reg, err := schemaregistry.NewRegistry(srv.URL)
+	if err != nil {
+		t.Fatal("TestServerWithSchemaRegistry: ", err)
+	}
+	schema, err := reg.GetSchemaByID(id)
+	if err != nil {
+		t.Fatal("TestServerWithSchemaRegistry: ", err)
+	}
+	if schema.Schema != userV1 || schema.Type() != schemaregistry.AVRO || schema.Subject != "" {
+		t.Error("TestServerWithSchemaRegistry: unexpected schema", schema)
+	}
+
+	cached, err := reg.GetSchemaByID(id)
+	if err != nil || cached != schema {
+		t.Error("TestServerWithSchemaRegistry: expected cached schema", cached, err)
+	}
+
+	resolved, err := reg.ResolveSubject(schema)
+	if err != nil || resolved.Subject != "com.test-value" || resolved.Version != 1 || resolved.ID != id {
+		t.Error("TestServerWithSchemaRegistry: unexpected resolved schema", resolved, err)
+	}
+	if cached, err := reg.GetSchemaByID(id); err != nil || cached != resolved {
+		t.Error("TestServerWithSchemaRegistry: resolved schema not cached", cached, err)
+	}
+}
+
+func TestSchemaRegistryDeletedSubject(t *testing.T) {
+	srv, c := newClient(t)
+
+	id, err := c.CreateSchema("com.test-value", userV1, schemaregistry.AVRO)
+	if err != nil {
+		t.Fatal("TestSchemaRegistryDeletedSubject: ", err)
+	}
+	if _, err := c.DeleteSubject("com.test-value", false); err != nil {
+		t.Fatal("TestSchemaRegistryDeletedSubject: ", err)
+	}
+
+	reg, err := schemaregistry.NewRegistry(srv.URL)
+	if err != nil {
+		t.Fatal("TestSchemaRegistryDeletedSubject: ", err)
+	}
+	schema, err := reg.GetSchemaByID(id)
+	if err != nil || schema.Schema != userV1 {
+		t.Error("TestSchemaRegistryDeletedSubject: unexpected schema", schema, err)
+	}
+}
+
+func TestServerRegistryRegisterSchema(t *testing.T) {
+	srv, _ := newClient(t)
+
+	reg, err := schemaregistry.NewRegistry(srv.URL)
+	if err != nil {
+		t.Fatal("TestServerRegistryRegisterSchema: ", err)
+	}
+	schema, err := reg.RegisterSchema("com.test-value", userV1, schemaregistry.AVRO)
+	if err != nil {
+		t.Fatal("TestServerRegistryRegisterSchema: ", err)
+	}
+	if schema.ID != 1 || schema.Version != 1 {
+		t.Error("TestServerRegistryRegisterSchema: unexpected schema", schema)
+	}
+
+	latest, err := reg.GetLatestSchema("com.test-value")
+	if err != nil || latest != schema {
+		t.Error("TestServerRegistryRegisterSchema: expected cached latest schema", latest, err)
+	}
+}
+
+func TestServerProtobufCompatibility(t *testing.T) {
+	_, c := newClient(t)
+
+	v1 := "syntax = \"proto3\";\npackage com.test;\nmessage Order {\n  string id = 1;\n  int32 quantity = 2;\n}\n"
+	v2 := "syntax = \"proto3\";\npackage com.test;\nmessage Order {\n  string id = 1;\n  string quantity = 2;\n}\n"
+	if _, err := c.CreateSchema("orders-value", v1, schemaregistry.PROTOBUF); err != nil {
+		t.Fatal("TestServerProtobufCompatibility: ", err)
+	}
+	if _, err := c.CreateSchema("orders-value", v2, schemaregistry.PROTOBUF); !errors.Is(err, schemaregistry.ErrIncompatibleSchema) {
+		t.Error("TestServerProtobufCompatibility: expected ErrIncompatibleSchema, got", err)
+	}
+}
+
+func TestServerNormalize(t *testing.T) {
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+	c, err := schemaregistry.NewClient(srv.URL, schemaregistry.WithNormalizedSchemas())
+	if err != nil {
+		t.Fatal("TestServerNormalize: ", err)
+	}
+
+	id, err := c.CreateSchema("com.test-value", userV1, schemaregistry.AVRO)
+	if err != nil {
+		t.Fatal("TestServerNormalize: ", err)
+	}
+	reordered := `{"fields":[{"type":{"type":"string"},"name":"name"}],"name":"User","namespace":"com.test","type":"record"}`
+	again, err := c.CreateSchema("com.test-value", reordered, schemaregistry.AVRO)
+	if err != nil || again != id {
+		t.Error("TestServerNormalize: registering an equivalent schema returned", again, err)
+	}
+
+	found, err := c.LookupSchema("com.test-value", reordered, schemaregistry.AVRO)
+	if err != nil || found.ID != id || found.Version != 1 {
+		t.Error("TestServerNormalize: LookupSchema returned", found, err)
+	}
+}
+
+func TestServerContexts(t *testing.T) {
+	_, c := newClient(t)
+	tenant := c.InContext("tenant")
+
+	if _, err := tenant.CreateSchema("users", userV1, schemaregistry.AVRO); err != nil {
+		t.Fatal("TestServerContexts: ", err)
+	}
+	if _, err := c.CreateSchema("orders", userV1, schemaregistry.AVRO); err != nil {
+		t.Fatal("TestServerContexts: ", err)
+	}
+
+	contexts, err := c.ListContexts()
+	if err != nil || strings.Join(contexts, ",") != ".,.tenant" {
+		t.Error("TestServerContexts: unexpected contexts", contexts, err)
+	}
+	subjects, err := tenant.GetSubjects()
+	if err != nil || strings.Join(subjects, ",") != ":.tenant:users" {
+		t.Error("TestServerContexts: unexpected context subjects", subjects, err)
+	}
+	latest, err := tenant.GetLatestSchema("users")
+	if err != nil || latest.Subject != ":.tenant:users" {
+		t.Error("TestServerContexts: unexpected latest schema", latest, err)
+	}
+
+	// Every context has ids of its own.
+	id, err := tenant.CreateSchema("users", userV2, schemaregistry.AVRO)
+	if err != nil || id != 2 || latest.ID != 1 {
+		t.Fatal("TestServerContexts: unexpected context ids", latest.ID, id, err)
+	}
+	if id, err := c.CreateSchema("orders", userV2, schemaregistry.AVRO); err != nil || id != 2 {
+		t.Error("TestServerContexts: unexpected default context id", id, err)
+	}
+	if schema, err := tenant.GetSchemaByID(1); err != nil || schema != userV1 {
+		t.Error("TestServerContexts: unexpected context schema", schema, err)
+	}
+	if _, err := c.InContext("other").GetSchemaByID(1); !errors.Is(err, schemaregistry.ErrSchemaNotFound) {
+		t.Error("TestServerContexts: id found in another context", err)
+	}
+}
+
+func TestServerFindSchemaLowestID(t *testing.T) {
+	_, c := newClient(t)
+	if _, err := c.SetMode("", schemaregistry.ModeImport); err != nil {
+		t.Fatal("TestServerFindSchemaLowestID: ", err)
+	}
+	for _, imp := range []struct {
+		subject string
+		id      int
+	}{{"a", 5}, {"b", 3}, {"c", 4}} {
+		if _, err := c.ImportSchema(imp.subject, imp.id, 1, userV1, schemaregistry.AVRO); err != nil {
+			t.Fatal("TestServerFindSchemaLowestID: ", err)
+		}
+	}
+	if _, err := c.SetMode("", schemaregistry.ModeReadWrite); err != nil {
+		t.Fatal("TestServerFindSchemaLowestID: ", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		subject := "d" + strings.Repeat("d", i)
+		if id, err := c.CreateSchema(subject, userV1, schemaregistry.AVRO); err != nil || id != 3 {
+			t.Fatal("TestServerFindSchemaLowestID: unexpected id", id, err)
+		}
+	}
+}
+
+func TestServerMetadata(t *testing.T) {
+	_, c := newClient(t)
+
+	checkName := schemaregistry.Rule{
+		Name: "checkName",
+		Kind: schemaregistry.RuleKindCondition,
+		Mode: schemaregistry.RuleModeWrite,
+		Type: "CEL",
+		Expr: "size(message.name) > 0",
+	}
+	config, err := c.SetFullConfig("users", schemaregistry.Config{
+		DefaultMetadata: &schemaregistry.Metadata{Properties: map[string]string{"owner": "team-a"}},
+		OverrideRuleSet: &schemaregistry.RuleSet{DomainRules: []schemaregistry.Rule{checkName}},
+	})
+	if err != nil || config.DefaultMetadata == nil || config.OverrideRuleSet == nil {
+		t.Fatal("TestServerMetadata: unexpected config", config, err)
+	}
+
+	id, err := c.CreateSchema("users", userV1, schemaregistry.AVRO)
+	if err != nil {
+		t.Fatal("TestServerMetadata: ", err)
+	}
+	latest, err := c.GetLatestSchema("users")
+	if err != nil || latest.Metadata == nil || latest.Metadata.Properties["owner"] != "team-a" ||
+		latest.RuleSet == nil || len(latest.RuleSet.DomainRules) != 1 || latest.RuleSet.DomainRules[0].Name != "checkName" {
+		t.Error("TestServerMetadata: config defaults and overrides not applied", latest, err)
+	}
+
+	metadata := &schemaregistry.Metadata{
+		Tags:       map[string][]string{"User.name": {"PII"}},
+		Properties: map[string]string{"owner": "team-b"},
+	}
+	other, err := c.CreateSchemaWithMetadata("users", userV1, schemaregistry.AVRO, metadata, nil)
+	if err != nil || other == id {
+		t.Fatal("TestServerMetadata: schema with other metadata not registered", other, err)
+	}
+	found, err := c.LookupSchemaWithMetadata("users", userV1, schemaregistry.AVRO, metadata, nil)
+	if err != nil || found.ID != other || found.Version != 2 || found.Metadata.Tags["User.name"][0] != "PII" {
+		t.Error("TestServerMetadata: LookupSchemaWithMetadata returned", found, err)
+	}
+	if found, err := c.LookupSchema("users", userV1, schemaregistry.AVRO); err != nil || found.Version != 2 {
+		t.Error("TestServerMetadata: LookupSchema returned", found, err)
+	}
+
+	byID, err := c.GetSchemaResponseByID(context.Background(), id)
+	if err != nil || byID.Metadata == nil || byID.Metadata.Properties["owner"] != "team-a" {
+		t.Error("TestServerMetadata: metadata not returned by id", byID, err)
+	}
+
+	config, err = c.GetFullConfig("")
+	if err != nil || config.CompatibilityLevel != schemaregistry.CompatibilityBackward || config.DefaultMetadata != nil {
+		t.Error("TestServerMetadata: unexpected global config", config, err)
+	}
+}
+
+func TestServerRegistryRegisterSchemaWithMetadata(t *testing.T) {
+	srv, c := newClient(t)
+	if _, err := c.SetFullConfig("users", schemaregistry.Config{
+		OverrideMetadata: &schemaregistry.Metadata{Properties: map[string]string{"owner": "team-a"}},
+	}); err != nil {
+		t.Fatal("TestServerRegistryRegisterSchemaWithMetadata: ", err)
+	}
+
+	reg, err := schemaregistry.NewRegistry(srv.URL)
+	if err != nil {
+		t.Fatal("TestServerRegistryRegisterSchemaWithMetadata: ", err)
+	}
+	metadata := &schemaregistry.Metadata{Properties: map[string]string{"version": "1"}}
+	schema, err := reg.RegisterSchemaWithMetadata("users", userV1, schemaregistry.AVRO, metadata, nil)
+	if err != nil {
+		t.Fatal("TestServerRegistryRegisterSchemaWithMetadata: ", err)
+	}
+	if schema.Metadata == nil || schema.Metadata.Properties["owner"] != "team-a" || schema.Metadata.Properties["version"] != "1" {
+		t.Error("TestServerRegistryRegisterSchemaWithMetadata: unexpected metadata", schema.Metadata)
+	}
+	again, err := reg.RegisterSchemaWithMetadata("users", userV1, schemaregistry.AVRO, metadata, nil)
+	if err != nil || again != schema {
+		t.Error("TestServerRegistryRegisterSchemaWithMetadata: expected cached schema", again, err)
+	}
+
+	plain, err := reg.RegisterSchema("users", userV1, schemaregistry.AVRO)
+	if err != nil || plain == schema || plain.Version != 2 || plain.Metadata.Properties["version"] != "" {
+		t.Error("TestServerRegistryRegisterSchemaWithMetadata: unexpected schema without metadata", plain, err)
+	}
+	byID, err := reg.GetSchemaByID(schema.ID)
+	if err != nil || byID != schema {
+		t.Error("TestServerRegistryRegisterSchemaWithMetadata: expected cached schema by id", byID, err)
+	}
+}
+
+func TestServerRegistryReferenceVersions(t *testing.T) {
+	srv, c := newClient(t)
+	commonV1 := `{"type":"record","name":"Common","namespace":"com.test","fields":[{"name":"a","type":"string"}]}`
+	commonV2 := `{"type":"record","name":"Common","namespace":"com.test","fields":[{"name":"a","type":"string"},{"name":"b","type":"int","default":0}]}`
+	for _, schema := range []string{commonV1, commonV2} {
+		if _, err := c.CreateSchema("common", schema, schemaregistry.AVRO); err != nil {
+			t.Fatal("TestServerRegistryReferenceVersions: ", err)
+		}
+	}
+
+	reg, err := schemaregistry.NewRegistry(srv.URL)
+	if err != nil {
+		t.Fatal("TestServerRegistryReferenceVersions: ", err)
+	}
+	order := `{"type":"record","name":"Order","namespace":"com.test","fields":[{"name":"common","type":"com.test.Common"}]}`
+	var schemas []*schemaregistry.Schema
+	for _, version := range []int{1, 2} {
+		ref := schemaregistry.Reference{Name: "com.test.Common", Subject: "common", Version: version}
+		schema, err := reg.RegisterSchema("orders", order, schemaregistry.AVRO, ref)
+		if err != nil {
+			t.Fatal("TestServerRegistryReferenceVersions: ", err)
+		}
+		schemas = append(schemas, schema)
+	}
+	if schemas[0] == schemas[1] || schemas[0].ID == schemas[1].ID || schemas[1].References[0].Version != 2 {
+		t.Fatal("TestServerRegistryReferenceVersions: registration with other reference version served from cache", schemas[1])
+	}
+
+	for i, schema := range schemas {
+		parsed, err := reg.ParseAvro(schema)
+		if err != nil {
+			t.Fatal("TestServerRegistryReferenceVersions: ", err)
+		}
+		common := parsed.(*avro.RecordSchema).Fields()[0].Type().(*avro.RefSchema).Schema().(*avro.RecordSchema)
+		if len(common.Fields()) != i+1 {
+			t.Error("TestServerRegistryReferenceVersions: parsed with the wrong reference version", i+1, len(common.Fields()))
+		}
+	}
+}
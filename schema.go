@@ -7,6 +7,32 @@ type Schema struct {
 	Subject    string
 	Version    int
 	References []Reference
+	Metadata   *Metadata
+	RuleSet    *RuleSet
+
+	// context is the schema context the id belongs to, empty for the default
+	// context.
+	context string
+}
+
+// Type returns the schema type, which is AVRO when the registry omits it.
+func (s *Schema) Type() SchemaType {
+	if s.SchemaType == nil || *s.SchemaType == "" {
+		return AVRO
+	}
+
+	return *s.SchemaType
+}
+
+// Context returns the schema context the schema id belongs to, empty for the
+// default context.
+func (s *Schema) Context() string {
+	if s.context != "" {
+		return s.context
+	}
+	context, _ := SplitSubject(s.Subject)
+
+	return context
 }
 
 type Reference struct {
@@ -14,3 +40,37 @@ type Reference struct {
 	Subject string `json:"subject"`
 	Version int    `json:"version"`
 }
+
+// Metadata describes a schema with tags on its fields and free form properties.
+type Metadata struct {
+	// Tags maps schema paths, such as a record field name, to their tags.
+	Tags map[string][]string `json:"tags,omitempty"`
+	// Properties are key value pairs describing the schema, such as its owner.
+	Properties map[string]string `json:"properties,omitempty"`
+	// Sensitive lists the properties that must not be shown.
+	Sensitive []string `json:"sensitive,omitempty"`
+}
+
+// RuleSet holds the rules of a data contract. Migration rules transform data
+// between schema versions and domain rules validate or transform data when it
+// is written or read.
+type RuleSet struct {
+	MigrationRules []Rule `json:"migrationRules,omitempty"`
+	DomainRules    []Rule `json:"domainRules,omitempty"`
+}
+
+// Rule is a data contract rule evaluated by clients on serialization and
+// deserialization.
+type Rule struct {
+	Name      string            `json:"name"`
+	Doc       string            `json:"doc,omitempty"`
+	Kind      RuleKind          `json:"kind,omitempty"`
+	Mode      RuleMode          `json:"mode,omitempty"`
+	Type      string            `json:"type,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	Params    map[string]string `json:"params,omitempty"`
+	Expr      string            `json:"expr,omitempty"`
+	OnSuccess string            `json:"onSuccess,omitempty"`
+	OnFailure string            `json:"onFailure,omitempty"`
+	Disabled  bool              `json:"disabled,omitempty"`
+}
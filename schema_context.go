@@ -0,0 +1,48 @@
+package schemaregistry
+
+import "strings"
+
+// DefaultContext is the context of subjects that are not qualified with one.
+const DefaultContext = "."
+
+// contextName returns the context name starting with a dot, or an empty name
+// for the default context.
+func contextName(name string) string {
+	name = strings.Trim(name, ":")
+	if name == "" || name == DefaultContext {
+		return ""
+	}
+	if !strings.HasPrefix(name, ".") {
+		name = "." + name
+	}
+
+	return name
+}
+
+// QualifiedSubject returns the subject qualified with the context, as in
+// :.context:subject. Subjects of the default context and subjects qualified
+// already are returned unchanged. An empty subject returns the context prefix
+// itself, which stands for the context in subject prefixes and configs.
+func QualifiedSubject(context, subject string) string {
+	context = contextName(context)
+	if context == "" || strings.HasPrefix(subject, ":.") {
+		return subject
+	}
+
+	return ":" + context + ":" + subject
+}
+
+// SplitSubject splits a qualified subject into its context and its name. The
+// context of a subject that is not qualified is the default context, returned
+// as an empty name.
+func SplitSubject(subject string) (string, string) {
+	if !strings.HasPrefix(subject, ":.") {
+		return "", subject
+	}
+	end := strings.IndexByte(subject[1:], ':')
+	if end < 0 {
+		return "", subject
+	}
+
+	return contextName(subject[1 : end+1]), subject[end+2:]
+}
@@ -0,0 +1,125 @@
+package schemaregistry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestQualifiedSubject(t *testing.T) {
+	cases := []struct {
+		context, subject, want string
+	}{
+		{"", "users", "users"},
+		{".", "users", "users"},
+		{"tenant", "users", ":.tenant:users"},
+		{".tenant", "users", ":.tenant:users"},
+		{".tenant", ":.other:users", ":.other:users"},
+		{".tenant", "", ":.tenant:"},
+	}
+	for _, c := range cases {
+		if got := QualifiedSubject(c.context, c.subject); got != c.want {
+			t.Errorf("TestQualifiedSubject: %q %q got %q want %q", c.context, c.subject, got, c.want)
+		}
+	}
+}
+
+func TestSplitSubject(t *testing.T) {
+	cases := map[string][2]string{
+		"users":          {"", "users"},
+		":.tenant:users": {".tenant", "users"},
+		":.:users":       {"", "users"},
+		":.tenant:":      {".tenant", ""},
+		":.broken":       {"", ":.broken"},
+	}
+	for subject, want := range cases {
+		if context, name := SplitSubject(subject); context != want[0] || name != want[1] {
+			t.Errorf("TestSplitSubject: %q got %q %q want %q", subject, context, name, want)
+		}
+	}
+}
+
+func TestClientContext(t *testing.T) {
+	var uris []string
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		uris = append(uris, strings.TrimPrefix(r.URL.String(), "localhost:8080"))
+		body := `{}`
+		if uri := r.URL.String(); strings.HasSuffix(uri, "/contexts") || strings.Contains(uri, "/subjects?") {
+			body = `[".", ".tenant"]`
+		}
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(body)), StatusCode: 200}, nil
+	}
+	c, err := NewClient("localhost:8080", WithCustomHTTPClient(client), WithContext("tenant"))
+	if err != nil {
+		t.Fatal("TestClientContext: ", err)
+	}
+
+	c.GetSubjects()
+	c.GetLatestSchema("users")
+	c.GetLatestSchema(":.other:users")
+	c.GetSchemaByID(1)
+	c.GetConfig("")
+	c.GetMode("users")
+	c.CheckCompatibility("users", `"string"`, AVRO, int(LatestVersion))
+	c.InContext("").GetSchemaByID(1)
+	contexts, err := c.ListContexts()
+	if err != nil || len(contexts) != 2 || contexts[1] != ".tenant" {
+		t.Error("TestClientContext: unexpected contexts", contexts, err)
+	}
+
+	want := []string{
+		"/subjects?subjectPrefix=%3A.tenant%3A",
+		"/subjects/:.tenant:users/versions/latest",
+		"/subjects/:.other:users/versions/latest",
+		"/schemas/ids/1?subject=%3A.tenant%3A",
+		"/config/:.tenant:",
+		"/mode/:.tenant:users",
+		"/compatibility/subjects/:.tenant:users/versions/latest?verbose=true",
+		"/schemas/ids/1",
+		"/contexts",
+	}
+	if strings.Join(uris, "\n") != strings.Join(want, "\n") {
+		t.Errorf("TestClientContext: got\n%s\nwant\n%s", strings.Join(uris, "\n"), strings.Join(want, "\n"))
+	}
+	if c.Context() != ".tenant" || c.InContext(".").Context() != "" {
+		t.Error("TestClientContext: unexpected context", c.Context())
+	}
+}
+
+func TestRegistryContextIDs(t *testing.T) {
+	client := &HTTPClientMock{}
+	client.DoFunc = func(r *http.Request) (*http.Response, error) {
+		body := `{"schema":"\"string\""}`
+		switch uri := r.URL.String(); {
+		case strings.Contains(uri, "?subject=%3A.tenant%3A"):
+			body = `{"schema":"\"long\""}`
+		case strings.HasSuffix(uri, "/subjects/:.tenant:users/versions/1"):
+			body = `{"subject":":.tenant:users","version":1,"id":1,"schema":"\"long\""}`
+		}
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(body)), StatusCode: 200}, nil
+	}
+	reg, err := NewRegistry("localhost:8080", WithHTTPClient(client), WithSchemaContext("tenant"))
+	if err != nil {
+		t.Fatal("TestRegistryContextIDs: ", err)
+	}
+
+	if err := reg.Register("users", 1); err != nil {
+		t.Fatal("TestRegistryContextIDs: ", err)
+	}
+	tenant, err := reg.GetSchemaByID(1)
+	if err != nil || tenant.Schema != `"long"` || tenant.Subject != ":.tenant:users" {
+		t.Error("TestRegistryContextIDs: unexpected tenant schema", tenant, err)
+	}
+	def, err := reg.GetSchemaByContextID(DefaultContext, 1)
+	if err != nil || def.Schema != `"string"` {
+		t.Error("TestRegistryContextIDs: unexpected default context schema", def, err)
+	}
+	if again, _ := reg.GetSchemaByID(1); again != tenant {
+		t.Error("TestRegistryContextIDs: schema ids of the contexts collide", again)
+	}
+	if len(reg.idSchema) != 2 {
+		t.Error("TestRegistryContextIDs: unexpected id cache", reg.idSchema)
+	}
+}
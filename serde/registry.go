@@ -0,0 +1,306 @@
+package serde
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/hamba/avro"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/avroschema"
+)
+
+// magicByte prefixes every message in the registry wire format. It is followed
+// by the big endian schema id and the encoded payload.
+const magicByte byte = 0
+
+const wireHeaderLen = 5
+
+type serdeOptions struct {
+	metrics schemaregistry.Metrics
+	tracer  schemaregistry.Tracer
+}
+
+type serdeOps func(*serdeOptions)
+
+// WithMetrics reports every serialization or deserialization to the metrics.
+func WithMetrics(metrics schemaregistry.Metrics) serdeOps {
+	return func(opts *serdeOptions) {
+		if metrics != nil {
+			opts.metrics = metrics
+		}
+	}
+}
+
+// WithTracer traces every serialization or deserialization.
+func WithTracer(tracer schemaregistry.Tracer) serdeOps {
+	return func(opts *serdeOptions) {
+		if tracer != nil {
+			opts.tracer = tracer
+		}
+	}
+}
+
+func applySerdeOptions(ops []serdeOps) *serdeOptions {
+	opts := &serdeOptions{metrics: schemaregistry.NopMetrics{}, tracer: schemaregistry.NopTracer{}}
+	for _, opt := range ops {
+		opt(opts)
+	}
+
+	return opts
+}
+
+// AvroSerializer encodes values as avro in the registry wire format.
+type AvroSerializer struct {
+	registry schemaregistry.Registry
+	avro     *AvroSerde
+	metrics  schemaregistry.Metrics
+	tracer   schemaregistry.Tracer
+	parsed   sync.Map // map[parsedKey]Schema
+	derived  sync.Map // map[reflect.Type]string
+}
+
+// NewAvroSerializer creates an AvroSerializer looking schemas up in the registry.
+func NewAvroSerializer(registry schemaregistry.Registry, ops ...serdeOps) *AvroSerializer {
+	opts := applySerdeOptions(ops)
+
+	return &AvroSerializer{
+		registry: registry,
+		avro:     NewAvroSerde(),
+		metrics:  opts.metrics,
+		tracer:   opts.tracer,
+	}
+}
+
+// Serialize encodes the value with the latest schema of the subject.
+func (s *AvroSerializer) Serialize(subject string, value interface{}) ([]byte, error) {
+	return s.SerializeContext(context.Background(), subject, value)
+}
+
+// SerializeContext is Serialize with a context carrying the parent span.
+func (s *AvroSerializer) SerializeContext(ctx context.Context, subject string, value interface{}) ([]byte, error) {
+	return s.serialize(ctx, subject, value, func() (*schemaregistry.Schema, error) {
+		return s.registry.GetLatestSchema(subject)
+	})
+}
+
+// SerializeWithSchema registers the schema under the subject and encodes the value with it.
+func (s *AvroSerializer) SerializeWithSchema(subject, schema string, value interface{}) ([]byte, error) {
+	return s.SerializeWithSchemaContext(context.Background(), subject, schema, value)
+}
+
+// SerializeWithSchemaContext is SerializeWithSchema with a context carrying
+// the parent span.
+func (s *AvroSerializer) SerializeWithSchemaContext(ctx context.Context, subject, schema string, value interface{}) ([]byte, error) {
+	return s.serialize(ctx, subject, value, func() (*schemaregistry.Schema, error) {
+		return s.registry.RegisterSchema(subject, schema, schemaregistry.AVRO)
+	})
+}
+
+// SerializeDerived derives the schema from the type of the value, registers it
+// under the subject and encodes the value with it.
+func (s *AvroSerializer) SerializeDerived(subject string, value interface{}) ([]byte, error) {
+	return s.SerializeDerivedContext(context.Background(), subject, value)
+}
+
+// SerializeDerivedContext is SerializeDerived with a context carrying the
+// parent span.
+func (s *AvroSerializer) SerializeDerivedContext(ctx context.Context, subject string, value interface{}) ([]byte, error) {
+	return s.serialize(ctx, subject, value, func() (*schemaregistry.Schema, error) {
+		typ := reflect.TypeOf(value)
+		schema, ok := s.derived.Load(typ)
+		if !ok {
+			derived, err := avroschema.Schema(value, avroschema.Options{})
+			if err != nil {
+				return nil, err
+			}
+			schema, _ = s.derived.LoadOrStore(typ, derived)
+		}
+
+		return s.registry.RegisterSchema(subject, schema.(string), schemaregistry.AVRO)
+	})
+}
+
+// serialize encodes the value with the schema returned by lookup, reporting
+// the serialization to the metrics and the tracer.
+func (s *AvroSerializer) serialize(ctx context.Context, subject string, value interface{}, lookup func() (*schemaregistry.Schema, error)) (data []byte, err error) {
+	_, span := s.tracer.Start(ctx, "schema_registry.serialize")
+	span.SetAttribute(schemaregistry.AttrSubject, subject)
+	defer func() {
+		s.metrics.ObserveSerde(schemaregistry.SerdeSerialize, subject, err)
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+
+	schema, err := lookup()
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttribute(schemaregistry.AttrSchemaID, schema.ID)
+
+	parsed, hit, err := parseCached(&s.parsed, s.registry, s.avro, schema)
+	span.SetAttribute(schemaregistry.AttrCacheHit, hit)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := s.avro.Marshal(parsed, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(wireHeader(schema.ID), payload...), nil
+}
+
+// AvroDeserializer decodes avro values in the registry wire format.
+type AvroDeserializer struct {
+	registry schemaregistry.Registry
+	avro     *AvroSerde
+	metrics  schemaregistry.Metrics
+	tracer   schemaregistry.Tracer
+	parsed   sync.Map // map[parsedKey]Schema
+}
+
+// NewAvroDeserializer creates an AvroDeserializer looking schemas up in the registry.
+func NewAvroDeserializer(registry schemaregistry.Registry, ops ...serdeOps) *AvroDeserializer {
+	opts := applySerdeOptions(ops)
+
+	return &AvroDeserializer{
+		registry: registry,
+		avro:     NewAvroSerde(),
+		metrics:  opts.metrics,
+		tracer:   opts.tracer,
+	}
+}
+
+// Deserialize decodes the data into value using the writer schema identified in the data.
+// The deserialization is reported to the metrics under the subject of the
+// writer schema. Registries returning schemas by id without their subject,
+// such as SchemaRegistry, are asked to resolve it once per schema; the subject
+// is empty if the schema cannot be found or its subject resolved.
+func (d *AvroDeserializer) Deserialize(data []byte, value interface{}) error {
+	return d.DeserializeContext(context.Background(), data, value)
+}
+
+// DeserializeContext is Deserialize with a context carrying the parent span.
+func (d *AvroDeserializer) DeserializeContext(ctx context.Context, data []byte, value interface{}) (err error) {
+	_, span := d.tracer.Start(ctx, "schema_registry.deserialize")
+	var subject string
+	defer func() {
+		d.metrics.ObserveSerde(schemaregistry.SerdeDeserialize, subject, err)
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+
+	id, payload, err := SplitWireFormat(data)
+	if err != nil {
+		return err
+	}
+	span.SetAttribute(schemaregistry.AttrSchemaID, id)
+
+	schema, err := d.registry.GetSchemaByID(id)
+	if err != nil {
+		return err
+	}
+	if schema == nil {
+		return fmt.Errorf("schema id:%d not found", id)
+	}
+	subject = resolveSubject(d.registry, schema)
+	span.SetAttribute(schemaregistry.AttrSubject, subject)
+
+	parsed, hit, err := parseCached(&d.parsed, d.registry, d.avro, schema)
+	span.SetAttribute(schemaregistry.AttrCacheHit, hit)
+	if err != nil {
+		return err
+	}
+
+	return d.avro.Unmarshal(parsed, payload, value)
+}
+
+// SplitWireFormat splits registry wire format data into the schema id and the payload.
+func SplitWireFormat(data []byte) (int, []byte, error) {
+	if len(data) < wireHeaderLen {
+		return 0, nil, errors.New("data too short for registry wire format")
+	}
+	if data[0] != magicByte {
+		return 0, nil, fmt.Errorf("unknown magic byte [%d]", data[0])
+	}
+
+	return int(binary.BigEndian.Uint32(data[1:wireHeaderLen])), data[wireHeaderLen:], nil
+}
+
+func wireHeader(id int) []byte {
+	header := make([]byte, wireHeaderLen, wireHeaderLen+64)
+	header[0] = magicByte
+	binary.BigEndian.PutUint32(header[1:], uint32(id))
+
+	return header
+}
+
+// avroParser is implemented by registries that share parsed schemas, such as
+// SchemaRegistry.
+type avroParser interface {
+	ParseAvro(schema *schemaregistry.Schema) (avro.Schema, error)
+}
+
+// subjectResolver is implemented by registries that return schemas by id
+// without their subject and can look it up, such as SchemaRegistry.
+type subjectResolver interface {
+	ResolveSubject(schema *schemaregistry.Schema) (*schemaregistry.Schema, error)
+}
+
+// resolveSubject returns the subject of the schema, resolving it through the
+// registry if the schema was fetched by id without it. The subject is only
+// used to report the schema, so a failed resolution leaves it empty.
+func resolveSubject(registry schemaregistry.Registry, schema *schemaregistry.Schema) string {
+	if schema.Subject != "" {
+		return schema.Subject
+	}
+	r, ok := registry.(subjectResolver)
+	if !ok {
+		return ""
+	}
+	resolved, err := r.ResolveSubject(schema)
+	if err != nil {
+		return ""
+	}
+
+	return resolved.Subject
+}
+
+// parsedKey identifies a parsed schema. Schema ids are only unique within a
+// schema context.
+type parsedKey struct {
+	context string
+	id      int
+}
+
+// parseCached returns the parsed schema and whether it was cached.
+func parseCached(cache *sync.Map, registry schemaregistry.Registry, a *AvroSerde, schema *schemaregistry.Schema) (Schema, bool, error) {
+	key := parsedKey{schema.Context(), schema.ID}
+	if parsed, ok := cache.Load(key); ok {
+		return parsed.(Schema), true, nil
+	}
+
+	var parsed Schema
+	var err error
+	if p, ok := registry.(avroParser); ok {
+		parsed, err = p.ParseAvro(schema)
+	} else {
+		parsed, err = a.Parse(schema.Schema)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot parse schema id:%d err: %w", schema.ID, err)
+	}
+	cache.Store(key, parsed)
+
+	return parsed, false, nil
+}
@@ -0,0 +1,190 @@
+package serde
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	schemaregistry "github.com/anjulapaulus/schema_registry"
+	"github.com/anjulapaulus/schema_registry/registrytest"
+)
+
+type user struct {
+	Name string `avro:"name"`
+	Age  int64  `avro:"age"`
+}
+
+const userSchema = `{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"long"}]}`
+
+func TestAvroSerializerRoundTrip(t *testing.T) {
+	reg := schemaregistry.NewMockRegistry()
+	ser := NewAvroSerializer(reg)
+	des := NewAvroDeserializer(reg)
+
+	data, err := ser.SerializeWithSchema("users-value", userSchema, user{Name: "jane", Age: 30})
+	if err != nil {
+		t.Fatal("TestAvroSerializerRoundTrip: ", err)
+	}
+	id, _, err := SplitWireFormat(data)
+	if err != nil || id != 1 {
+		t.Error("TestAvroSerializerRoundTrip: unexpected wire header", id, err)
+	}
+
+	var out user
+	if err := des.Deserialize(data, &out); err != nil {
+		t.Fatal("TestAvroSerializerRoundTrip: ", err)
+	}
+	if out.Name != "jane" || out.Age != 30 {
+		t.Error("TestAvroSerializerRoundTrip: unexpected value", out)
+	}
+
+	latest, err := ser.Serialize("users-value", user{Name: "joe"})
+	if err != nil || latest[4] != 1 {
+		t.Error("TestAvroSerializerRoundTrip: Serialize with latest schema failed", err)
+	}
+}
+
+func TestAvroSerializerDerived(t *testing.T) {
+	reg := schemaregistry.NewMockRegistry()
+	ser := NewAvroSerializer(reg)
+	des := NewAvroDeserializer(reg)
+
+	data, err := ser.SerializeDerived("users-value", user{Name: "jane", Age: 30})
+	if err != nil {
+		t.Fatal("TestAvroSerializerDerived: ", err)
+	}
+	again, err := ser.SerializeDerived("users-value", user{Name: "joe"})
+	if err != nil {
+		t.Fatal("TestAvroSerializerDerived: ", err)
+	}
+	if data[4] != 1 || again[4] != 1 {
+		t.Error("TestAvroSerializerDerived: derived schema registered twice")
+	}
+
+	var out user
+	if err := des.Deserialize(data, &out); err != nil {
+		t.Fatal("TestAvroSerializerDerived: ", err)
+	}
+	if out.Name != "jane" || out.Age != 30 {
+		t.Error("TestAvroSerializerDerived: unexpected value", out)
+	}
+
+	if _, err := ser.SerializeDerived("users-value", nil); err == nil {
+		t.Error("TestAvroSerializerDerived: nil value serialized")
+	}
+}
+
+func TestSplitWireFormat(t *testing.T) {
+	if _, _, err := SplitWireFormat([]byte{0, 0}); err == nil {
+		t.Error("TestSplitWireFormat: short data not handled")
+	}
+	if _, _, err := SplitWireFormat([]byte{1, 0, 0, 0, 1}); err == nil {
+		t.Error("TestSplitWireFormat: bad magic byte not handled")
+	}
+}
+
+// contextRegistry serves the latest schemas of subjects in several contexts,
+// whose ids may collide.
+type contextRegistry struct {
+	latest map[string]*schemaregistry.Schema
+}
+
+func (r *contextRegistry) Register(subject string, version int) error {
+	return nil
+}
+
+func (r *contextRegistry) GetSchemaByID(schemaId int) (*schemaregistry.Schema, error) {
+	return nil, schemaregistry.ErrSchemaNotFound
+}
+
+func (r *contextRegistry) GetLatestSchema(subject string) (*schemaregistry.Schema, error) {
+	return r.latest[subject], nil
+}
+
+func (r *contextRegistry) RegisterSchema(subject, schema string, schemaType schemaregistry.SchemaType, references ...schemaregistry.Reference) (*schemaregistry.Schema, error) {
+	return r.latest[subject], nil
+}
+
+func TestAvroSerializerContexts(t *testing.T) {
+	reg := &contextRegistry{latest: map[string]*schemaregistry.Schema{
+		":.a:x": {ID: 1, Subject: ":.a:x", Version: 1, Schema: `{"type":"record","name":"X","fields":[{"name":"a","type":"string"}]}`},
+		":.b:y": {ID: 1, Subject: ":.b:y", Version: 1, Schema: `{"type":"record","name":"Y","fields":[{"name":"b","type":"int"}]}`},
+	}}
+	ser := NewAvroSerializer(reg)
+
+	type x struct {
+		A string `avro:"a"`
+	}
+	type y struct {
+		B int `avro:"b"`
+	}
+	if _, err := ser.Serialize(":.a:x", x{A: "jane"}); err != nil {
+		t.Fatal("TestAvroSerializerContexts: ", err)
+	}
+	if _, err := ser.Serialize(":.b:y", y{B: 30}); err != nil {
+		t.Error("TestAvroSerializerContexts: schema parsed in another context reused", err)
+	}
+}
+
+// serdeRecorder records the subjects of the deserializations and the
+// endpoints of the registry requests.
+type serdeRecorder struct {
+	schemaregistry.NopMetrics
+	mu        sync.Mutex
+	subjects  []string
+	endpoints []string
+}
+
+func (r *serdeRecorder) ObserveRequest(method, endpoint string, status int, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endpoints = append(r.endpoints, endpoint)
+}
+
+func (r *serdeRecorder) ObserveSerde(op schemaregistry.SerdeOperation, subject string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if op == schemaregistry.SerdeDeserialize {
+		r.subjects = append(r.subjects, subject)
+	}
+}
+
+func TestAvroDeserializerSubject(t *testing.T) {
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+	producer, err := schemaregistry.NewRegistry(srv.URL)
+	if err != nil {
+		t.Fatal("TestAvroDeserializerSubject: ", err)
+	}
+	data, err := NewAvroSerializer(producer).SerializeWithSchema("users", userSchema, user{Name: "jane", Age: 30})
+	if err != nil {
+		t.Fatal("TestAvroDeserializerSubject: ", err)
+	}
+
+	metrics := &serdeRecorder{}
+	consumer, err := schemaregistry.NewRegistry(srv.URL, schemaregistry.WithMetrics(metrics))
+	if err != nil {
+		t.Fatal("TestAvroDeserializerSubject: ", err)
+	}
+	de := NewAvroDeserializer(consumer, WithMetrics(metrics))
+	for i := 0; i < 3; i++ {
+		var out user
+		if err := de.Deserialize(data, &out); err != nil || out.Name != "jane" {
+			t.Fatal("TestAvroDeserializerSubject: ", out, err)
+		}
+	}
+
+	if strings.Join(metrics.subjects, ",") != "users,users,users" {
+		t.Error("TestAvroDeserializerSubject: unexpected subjects", metrics.subjects)
+	}
+	resolved := 0
+	for _, endpoint := range metrics.endpoints {
+		if strings.HasSuffix(endpoint, "/versions") {
+			resolved++
+		}
+	}
+	if resolved != 1 {
+		t.Error("TestAvroDeserializerSubject: subject not resolved once", metrics.endpoints)
+	}
+}
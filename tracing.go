@@ -0,0 +1,56 @@
+package schemaregistry
+
+import (
+	"context"
+	"net/http"
+)
+
+// Span attributes set by the client and the serializers.
+const (
+	AttrEndpoint   = "schema_registry.endpoint"
+	AttrSubject    = "schema_registry.subject"
+	AttrVersion    = "schema_registry.version"
+	AttrSchemaID   = "schema_registry.schema_id"
+	AttrCacheHit   = "schema_registry.cache_hit"
+	AttrMethod     = "http.method"
+	AttrStatusCode = "http.status_code"
+)
+
+// Tracer traces registry requests and serialization. Implementations must be
+// safe for concurrent use. The oteltrace package implements it with
+// OpenTelemetry.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx, if any, and returns
+	// a context holding the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+	// Inject writes the trace context of ctx to the request headers.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a traced operation.
+type Span interface {
+	// SetAttribute sets an attribute of the span. The value is a string, an
+	// int or a bool.
+	SetAttribute(key string, value interface{})
+	// RecordError marks the span as failed with the error.
+	RecordError(err error)
+	// End ends the span.
+	End()
+}
+
+// NopTracer starts spans that record nothing. It is used when no Tracer is set.
+type NopTracer struct{}
+
+var _ Tracer = NopTracer{}
+
+func (NopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+func (NopTracer) Inject(ctx context.Context, header http.Header) {}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key string, value interface{}) {}
+func (nopSpan) RecordError(err error)                      {}
+func (nopSpan) End()                                       {}